@@ -1,7 +1,10 @@
 @echo off
-set VERSION=1.2
+set VERSION=v1.3
+for /f %%i in ('git rev-parse --short HEAD 2^>nul') do set COMMIT=%%i
+if "%COMMIT%"=="" set COMMIT=dev
+for /f %%i in ('powershell -NoProfile -Command "(Get-Date).ToUniversalTime().ToString(\"yyyy-MM-ddTHH:mm:ssZ\")"') do set BUILD_DATE=%%i
 
 echo Building Windows version...
 set GOOS=windows
 set GOARCH=amd64
-go build -o squirrel.exe
\ No newline at end of file
+go build -ldflags "-X main.version=%VERSION% -X main.commit=%COMMIT% -X main.buildDate=%BUILD_DATE%" -o squirrel.exe
@@ -1,8 +1,11 @@
 #!/bin/bash
 
 # 设置版本信息
-VERSION="1.2"
+VERSION="v1.3"
+COMMIT=$(git rev-parse --short HEAD 2>/dev/null || echo dev)
+BUILD_DATE=$(date -u +%Y-%m-%dT%H:%M:%SZ)
+LDFLAGS="-X main.version=${VERSION} -X main.commit=${COMMIT} -X main.buildDate=${BUILD_DATE}"
 
 # 构建Linux版本
 echo "Building Linux-amd64 version..."
-GOOS=linux GOARCH=amd64 go build -o squirrel
\ No newline at end of file
+GOOS=linux GOARCH=amd64 go build -ldflags "${LDFLAGS}" -o squirrel
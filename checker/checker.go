@@ -2,24 +2,27 @@ package checker
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"image"
-	"image/color"
-	"image/png"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"subdomain-checker/config"
 	"subdomain-checker/screenshot"
-
-	"github.com/chromedp/chromedp"
-	"github.com/fogleman/gg"
+	"subdomain-checker/utils"
 )
 
 // 子域名检测结果
@@ -30,25 +33,40 @@ type Result struct {
 	StatusText   string // 状态文本，如"存活"、"404"、"403"等
 	Message      string
 	ResponseTime time.Duration
-	PageInfo     *PageType // 页面信息
-	Title        string    // 页面标题
-	Screenshot   string    // 保存的截图文件名
-}
-
-// 配置项
-type Config struct {
-	Timeout          int
-	Concurrency      int
-	Verbose          bool
-	FollowRedirects  bool
-	ShowResponseTime bool
-	OutputFile       string
-	ExcelFile        string // 添加 Excel 输出文件配置
-	ExtractInfo      bool   // 是否提取页面重要信息
-	OnlyAlive        bool   // 是否只导出存活的域名
-	Screenshot       bool   // 是否对所有网页进行截图
-	ScreenshotAlive  bool   // 是否只截图存活的网页
-	ScreenshotDir    string // 截图保存目录
+	PageInfo     *PageType   // 页面信息
+	Title        string      // 页面标题
+	Screenshot   string      // 保存的截图文件名
+	ErrorClass   string      // 请求失败时的错误类别，如"超时"、"DNS解析失败"
+	FinalURL     string      // 实际得到响应的URL（包含协议，跟随重定向时为最终地址）
+	Headers      http.Header // 响应头，请求失败时为nil
+	Input        string      // 输入中的原始目标（归一化后）
+	BodyHash     string      // 响应内容的哈希，用于识别内容相同的页面（未读取内容时为空）
+	BodySize     int64       // 响应内容的字节数，未读取内容时取 Content-Length，都没有时为-1
+	IDN          string      // 国际化域名的Unicode形式（Domain 不含punycode时为空）
+	Original     string      // 归一化前的原始写法（与 Input 相同时为空）
+	Note         string      // 输入文件中该目标的行尾备注
+	Override     string      // 应用的 -overrides 规则（匹配的模式），未应用时为空
+	Response     string      // -store-response 保存的原始响应文件路径，未保存时为空
+	IPs          []string    // 请求实际连接的IP地址（跟随重定向时可能有多个），未建立连接时为空
+	UserAgent    string      // 请求和截图使用的User-Agent，使用默认值时为空
+	Proxy        string      // 请求使用的代理（隐藏密码），未使用代理时为空
+	Method       string      // -head 时得到状态码的请求方法（HEAD，或回退后的GET），未使用 -head 时为空
+	Fingerprints []string    // -extract 时根据响应头和页面内容识别的技术（如 Nginx、WordPress）
+	FaviconHash  string      // -favicon 时存活主机 /favicon.ico 的图标哈希（与 Shodan 相同的mmh3），没有图标时为空
+
+	// 记录的响应头（Server 等默认的几个和 -headers-capture 指定的），只包含响应中存在的，同名多个值以", "连接
+	CapturedHeaders map[string]string
+
+	// BodySize 取自 Content-Length 响应头（HEAD请求或未读取内容的错误页面），而不是实际读取的字节数
+	BodySizeApprox bool
+
+	// -follow 时经过的重定向，每一项为地址和状态码（如 "https://dev.example.com/ (302)"），最后一项为最终地址；没有重定向时为nil
+	RedirectChain []string
+	// 重定向链离开了起始主机的主域名（eTLD+1），可能指向第三方服务
+	RedirectCrossSite bool
+
+	// 需要截图但还没有截图：检测不等待截图完成，由调用方用 SubmitScreenshot 提交，完成后再补上 Screenshot
+	ScreenshotPending bool `json:"-"`
 }
 
 // 页面类型
@@ -57,291 +75,479 @@ type PageType struct {
 	Description string // 更详细的描述
 }
 
-// 截图任务
-type ScreenshotTask struct {
-	URL      string
-	Filename string
-	Dir      string
-	Result   chan<- string // 返回截图路径或空字符串（失败时）
-}
-
-// 截图工作池
-type ScreenshotPool struct {
-	tasks   chan ScreenshotTask
-	workers int
-	wg      sync.WaitGroup
-}
-
-// 创建新的截图工作池
-func NewScreenshotPool(workers int) *ScreenshotPool {
-	return &ScreenshotPool{
-		tasks:   make(chan ScreenshotTask, workers*2), // 缓冲大小为工作者数量的2倍
-		workers: workers,
-	}
-}
-
-// 启动截图工作池
-func (p *ScreenshotPool) Start() {
-	for i := 0; i < p.workers; i++ {
-		p.wg.Add(1)
-		go func(workerId int) {
-			defer p.wg.Done()
-
-			// 为每个工作者创建一个chromedp实例
-			opts := append(chromedp.DefaultExecAllocatorOptions[:],
-				chromedp.Flag("headless", true),
-				chromedp.Flag("disable-gpu", true),
-				chromedp.Flag("no-sandbox", true),
-				chromedp.Flag("disable-dev-shm-usage", true),
-				chromedp.WindowSize(1920, 1080),
-			)
-			allocCtx, cancel := chromedp.NewExecAllocator(context.Background(), opts...)
-			defer cancel()
-
-			ctx, cancel := chromedp.NewContext(allocCtx)
-			defer cancel()
-
-			for task := range p.tasks {
-				screenshotPath := filepath.Join(task.Dir, task.Filename)
-
-				// 执行截图
-				if err := takeScreenshotWithContext(ctx, task.URL, screenshotPath); err == nil {
-					task.Result <- screenshotPath
-				} else {
-					// 截图失败，生成错误图片
-					errorFilename := "error_" + task.Filename
-					errorPath := filepath.Join(task.Dir, errorFilename)
-					generateErrorImage(errorFilename, task.Dir)
-					task.Result <- errorPath
-				}
-			}
-		}(i)
+// 检查域名是否存活
+func (s *Session) CheckDomain(domain string, cfg config.Config, resultChan chan<- Result) {
+	// 无法转换为punycode的国际化域名直接报告，而不是作为连接失败
+	host := strings.TrimPrefix(strings.TrimPrefix(domain, "http://"), "https://")
+	host = strings.SplitN(host, "/", 2)[0]
+	if _, err := utils.ToASCIITarget(host); err != nil {
+		resultChan <- Result{
+			Domain:     domain,
+			Input:      domain,
+			StatusText: ErrorInvalid,
+			ErrorClass: ErrorInvalid,
+			Message:    err.Error(),
+		}
+		return
 	}
-}
 
-// 提交截图任务
-func (p *ScreenshotPool) Submit(url, filename, dir string) <-chan string {
-	result := make(chan string, 1)
-	p.tasks <- ScreenshotTask{
-		URL:      url,
-		Filename: filename,
-		Dir:      dir,
-		Result:   result,
+	// 应用逐目标的参数覆盖，并在结果中记录使用的规则
+	if cfg.Overrides != nil {
+		if overridden, pattern := cfg.Overrides.Apply(cfg, host); pattern != "" {
+			out := make(chan Result, 1)
+			s.CheckDomain(domain, overridden, out)
+			result := <-out
+			result.Override = pattern
+			resultChan <- result
+			return
+		}
 	}
-	return result
-}
-
-// 关闭截图工作池
-func (p *ScreenshotPool) Stop() {
-	close(p.tasks)
-	p.wg.Wait()
-}
 
-// 使用已有的context进行截图
-func takeScreenshotWithContext(ctx context.Context, url string, screenshotPath string) error {
-	// 准备截图缓冲区
-	var buf []byte
-
-	// 检查URL是否包含协议前缀
-	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
-		url = "http://" + url
+	// 选定该目标的User-Agent，HTTPS请求、回退的HTTP请求和截图都使用同一个
+	cfg.UserAgent, cfg.RandomUA = chooseUserAgent(cfg), false
+
+	// 使用 -proxy-file 时为该目标选择一个代理，HTTPS请求和回退的HTTP请求都通过它发送，并记录代理是否失败
+	if pool := s.proxies; pool != nil && cfg.Proxy == "" {
+		proxy := pool.pick()
+		cfg.Proxy = proxy.url.String()
+		out := make(chan Result, 1)
+		s.CheckDomain(domain, cfg, out)
+		result := <-out
+		pool.report(proxy, result.ErrorClass == ErrorProxy)
+		resultChan <- result
+		return
 	}
 
-	// 设置超时
-	taskCtx, cancel := context.WithTimeout(ctx, 20*time.Second)
-	defer cancel()
-
-	// 执行截图
-	tasks := chromedp.Tasks{
-		chromedp.Navigate(url),
-		// 等待页面加载
-		chromedp.Sleep(2 * time.Second),
-
-		// 获取页面滚动高度
-		chromedp.Evaluate(`Math.max(document.body.scrollHeight, document.documentElement.scrollHeight)`, nil),
-
-		// 使用FullScreenshot
-		chromedp.FullScreenshot(&buf, 90), // 稍微降低质量以提高性能
+	// 限定了协议时只用该协议检测和截图，目标中的协议一并替换，失败时不回退
+	if scheme := cfg.RequiredScheme(); scheme != "" {
+		target := strings.TrimPrefix(strings.TrimPrefix(domain, "http://"), "https://")
+		s.checkSingleDomain(scheme+"://"+target, domain, cfg, resultChan)
+		return
 	}
 
-	if err := chromedp.Run(taskCtx, tasks); err != nil {
-		return err
+	// 如果已经指定了协议，直接使用
+	if strings.HasPrefix(domain, "http://") || strings.HasPrefix(domain, "https://") {
+		s.checkSingleDomain(domain, domain, cfg, resultChan)
+		return
 	}
 
-	// 保存截图到文件
-	return os.WriteFile(screenshotPath, buf, 0644)
-}
+	// -paths 时同一主机的各路径使用第一个路径选定的协议
+	if cfg.Paths != "" && s.checkWithChosenScheme(domain, host, cfg, resultChan) {
+		return
+	}
 
-// 检查域名是否存活
-func CheckDomain(domain string, cfg config.Config, resultChan chan<- Result, screenshotPool *screenshot.ScreenshotPool) {
-	// 如果已经指定了协议，直接使用
-	if strings.HasPrefix(domain, "http://") || strings.HasPrefix(domain, "https://") {
-		checkSingleDomain(domain, cfg, resultChan, screenshotPool)
+	// 带有非HTTPS常用端口（如 8080）的目标先尝试HTTP。HTTPS端口收到明文HTTP请求时通常返回400而不是连接失败，
+	// 所以HTTP没有得到响应或返回400时再尝试HTTPS，HTTPS也没有得到响应时保留HTTP的结果
+	if httpFirst(host) {
+		out := make(chan Result, 1)
+		s.checkSingleDomain("http://"+domain, domain, cfg, out)
+		result := <-out
+		if result.Status == 0 || result.Status == http.StatusBadRequest {
+			s.checkSingleDomain("https://"+domain, domain, cfg, out)
+			if httpsResult := <-out; httpsResult.Status != 0 {
+				result = httpsResult
+			}
+		}
+		resultChan <- result
 		return
 	}
 
 	// 未指定协议，先尝试HTTPS
 	httpsDomain := "https://" + domain
 	httpsResult := Result{
-		Domain: httpsDomain,
-		Alive:  false,
+		Domain:    httpsDomain,
+		Alive:     false,
+		Input:     domain,
+		IDN:       utils.ToUnicodeTarget(httpsDomain),
+		UserAgent: cfg.UserAgent,
+		Proxy:     redactedProxy(cfg.Proxy),
 	}
 
 	// 创建一个带有连接池的客户端
+	transport := newTransport(cfg)
+	client := newClient(cfg, transport)
+
+	connectedIPs := recordIPs(transport)
+	resp, method, responseTime, err := s.probe(client, transport, httpsDomain, cfg)
+	httpsResult.ResponseTime = responseTime
+
+	if err == nil {
+		defer resp.Body.Close()
+		httpsResult.IPs = connectedIPs()
+		s.fillResult(&httpsResult, resp, method, client, transport, cfg)
+		resultChan <- httpsResult
+		return
+	}
+
+	// HTTPS请求失败，尝试HTTP
+	utils.Log().Record(utils.LevelDebug, "HTTPS请求失败，尝试HTTP", "domain", domain, "error", err)
+	httpDomain := "http://" + domain
+	s.checkSingleDomain(httpDomain, domain, cfg, resultChan)
+}
+
+// 通常使用HTTPS的端口，带有这些端口或不带端口的目标先尝试HTTPS
+var httpsPorts = []string{"443", "8443"}
+
+// 目标是否先尝试HTTP：带有端口，且不是通常使用HTTPS的端口
+func httpFirst(hostPort string) bool {
+	_, port, err := net.SplitHostPort(hostPort)
+	return err == nil && !slices.Contains(httpsPorts, port)
+}
+
+// 创建检测使用的 Transport，cfg.Proxy 不为空时请求通过该代理发送（代理地址已在参数检查时验证）
+func newTransport(cfg config.Config) *http.Transport {
 	transport := &http.Transport{
 		MaxIdleConns:        100,
 		MaxIdleConnsPerHost: 10,
 		IdleConnTimeout:     30 * time.Second,
 		DisableKeepAlives:   false, // 启用keep-alive
 	}
-
-	client := &http.Client{
-		Timeout:   time.Duration(cfg.Timeout) * time.Second,
-		Transport: transport,
+	if cfg.Proxy == "" {
+		return transport
 	}
-
-	// 处理重定向
-	if !cfg.FollowRedirects {
-		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
+	if proxyURL, err := config.ParseProxy(cfg.Proxy); err == nil {
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	// HTTP代理拒绝CONNECT（如认证失败）时 net/http 只返回状态文本，这里改为代理错误
+	transport.OnProxyConnectResponse = func(ctx context.Context, proxyURL *url.URL, req *http.Request, resp *http.Response) error {
+		if resp.StatusCode != http.StatusOK {
+			return proxyError(fmt.Errorf("代理拒绝连接 %s: %s", req.Host, resp.Status))
 		}
+		return nil
 	}
+	return transport
+}
 
-	startTime := time.Now()
-	resp, err := client.Get(httpsDomain)
-	responseTime := time.Since(startTime)
-	httpsResult.ResponseTime = responseTime
+// 检查代理能否连接，在开始检测前调用，避免代理不可用时每个目标都被记为无法访问
+func CheckProxy(proxyURL *url.URL, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", proxyURL.Host, timeout)
+	if err != nil {
+		return fmt.Errorf("无法连接代理 %s: %v", proxyURL.Redacted(), err)
+	}
+	return conn.Close()
+}
 
-	if err == nil {
-		defer resp.Body.Close()
-		httpsResult.Status = resp.StatusCode
-
-		// 根据状态码设置状态文本和存活标志
-		httpsResult.StatusText, httpsResult.Alive = getStatusTextAndAlive(resp.StatusCode)
-		httpsResult.Message = http.StatusText(resp.StatusCode)
-
-		// 提取页面信息
-		if resp.StatusCode < 400 {
-			body, err := io.ReadAll(resp.Body)
-			if err == nil {
-				pageContent := string(body)
-				if cfg.ExtractInfo {
-					httpsResult.PageInfo = detectPageType(pageContent)
-				}
-				httpsResult.Title = extractTitle(pageContent)
+// 记录 transport 建立的连接的对端IP地址，返回的函数按连接顺序给出去重后的地址。
+// 与 net/http 的默认拨号一样不单独设置超时，由 client.Timeout 限制。
+// 使用代理时连接的是代理服务器，不记录地址
+func recordIPs(transport *http.Transport) func() []string {
+	if transport.Proxy != nil {
+		return func() []string { return nil }
+	}
+	var mu sync.Mutex
+	var ips []string
+	dialer := &net.Dialer{}
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		if tcpAddr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+			ip := tcpAddr.IP.String()
+			mu.Lock()
+			if !slices.Contains(ips, ip) {
+				ips = append(ips, ip)
 			}
+			mu.Unlock()
 		}
+		return conn, nil
+	}
+	return func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(ips)
+	}
+}
 
-		// 如果需要截图，使用截图工作池
-		if screenshotPool != nil && (cfg.Screenshot || cfg.ScreenshotAlive) {
-			// 为网站生成唯一的截图文件名
-			screenFilename := generateScreenshotFilename(httpsDomain)
-
-			// 确保截图目录存在
-			if err := os.MkdirAll(cfg.ScreenshotDir, 0755); err == nil {
-				// 提交截图任务到工作池
-				resultCh := screenshotPool.Submit(httpsDomain, screenFilename, cfg.ScreenshotDir)
-
-				// 等待截图结果
-				if screenshotPath := <-resultCh; screenshotPath != "" {
-					// 将完整路径转换为相对路径
-					relPath := filepath.Join("screenshots", filepath.Base(screenshotPath))
-					// 确保使用正斜杠
-					relPath = strings.ReplaceAll(relPath, "\\", "/")
-					httpsResult.Screenshot = relPath
-				}
+// 从响应头中取出需要记录的响应头，一个都没有时返回nil
+func captureHeaders(header http.Header, cfg config.Config) map[string]string {
+	var captured map[string]string
+	for _, name := range cfg.CapturedHeaders() {
+		if values := header.Values(name); len(values) > 0 {
+			if captured == nil {
+				captured = make(map[string]string)
 			}
+			captured[name] = strings.Join(values, ", ")
 		}
-
-		resultChan <- httpsResult
-		return
 	}
+	return captured
+}
 
-	// HTTPS请求失败，尝试HTTP
-	httpDomain := "http://" + domain
-	checkSingleDomain(httpDomain, cfg, resultChan, screenshotPool)
+// 代理地址的显示形式，隐藏其中的密码
+func redactedProxy(proxy string) string {
+	if proxyURL, err := url.Parse(proxy); err == nil {
+		return proxyURL.Redacted()
+	}
+	return proxy
 }
 
-// 使用指定协议检查单个域名
-func checkSingleDomain(domain string, cfg config.Config, resultChan chan<- Result, screenshotPool *screenshot.ScreenshotPool) {
-	result := Result{
-		Domain: domain,
-		Alive:  false,
+// 创建检测使用的客户端：未使用 -follow 时不跟随重定向，跟随时最多 -max-redirects 次，超过时请求失败；
+// 限定了协议时停在指向其他协议的重定向上，其余重定向照常跟随
+func newClient(cfg config.Config, transport *http.Transport) *http.Client {
+	scheme := cfg.RequiredScheme()
+	return &http.Client{
+		Timeout:   time.Duration(cfg.Timeout) * time.Second,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if !cfg.FollowRedirects || (scheme != "" && req.URL.Scheme != scheme) {
+				return http.ErrUseLastResponse
+			}
+			if len(via) >= cfg.MaxRedirects {
+				return fmt.Errorf("重定向超过 %d 次", cfg.MaxRedirects)
+			}
+			return nil
+		},
 	}
+}
 
-	// 创建一个带有连接池的客户端
-	transport := &http.Transport{
-		MaxIdleConns:        100,
-		MaxIdleConnsPerHost: 10,
-		IdleConnTimeout:     30 * time.Second,
-		DisableKeepAlives:   false, // 启用keep-alive
+// 跟随重定向经过的地址和状态码，从最终响应沿 Request.Response 向前追溯，只包含得到该响应的请求（不含回退前的HEAD请求）。
+// 没有重定向时返回nil
+func redirectChain(resp *http.Response) []string {
+	var chain []string
+	for r := resp; r != nil && r.Request != nil; r = r.Request.Response {
+		chain = append(chain, fmt.Sprintf("%s (%d)", r.Request.URL, r.StatusCode))
+	}
+	if len(chain) < 2 {
+		return nil
 	}
+	slices.Reverse(chain)
+	return chain
+}
 
-	client := &http.Client{
-		Timeout:   time.Duration(cfg.Timeout) * time.Second,
-		Transport: transport,
+// 重定向链中是否有地址不属于第一个地址的主域名（eTLD+1），链中每一项为 redirectChain 给出的 "地址 (状态码)"
+func CrossSiteChain(chain []string) bool {
+	if len(chain) < 2 {
+		return false
 	}
+	apex := func(hop string) string {
+		target, _, _ := strings.Cut(hop, " ")
+		u, err := url.Parse(target)
+		if err != nil {
+			return ""
+		}
+		return apexOfHost(u.Hostname())
+	}
+	first := apex(chain[0])
+	for _, hop := range chain[1:] {
+		if apex(hop) != first {
+			return true
+		}
+	}
+	return false
+}
 
-	// 处理重定向
-	if !cfg.FollowRedirects {
-		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
+// 发送检测请求，返回响应、得到响应的请求方法（未使用 -head 时为空）和该请求的响应时间。
+// 使用 -head 时先发送HEAD请求，服务器不支持HEAD（405/501）或连接后没有给出有效响应（连接被重置、响应格式错误等）时改用GET；
+// 同时使用 -head-title 时，HEAD得到小于400的状态码后再发送GET读取标题；两个请求都发送时以GET的结果为准。
+// -extract、-store-response 和 -dedupe 需要响应内容，此时直接发送GET
+func (s *Session) probe(client *http.Client, transport *http.Transport, url string, cfg config.Config) (*http.Response, string, time.Duration, error) {
+	if !cfg.Head || cfg.ExtractInfo || cfg.StoreResponse != "" || cfg.Dedupe {
+		resp, elapsed, err := s.timedRequest(client, transport, http.MethodGet, url, cfg)
+		return resp, methodOf(cfg, http.MethodGet), elapsed, err
+	}
+	resp, elapsed, err := s.timedRequest(client, transport, http.MethodHead, url, cfg)
+	if err == nil {
+		notAllowed := resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented
+		if !notAllowed && (!cfg.HeadTitle || resp.StatusCode >= 400) {
+			return resp, http.MethodHead, elapsed, nil
 		}
+		resp.Body.Close()
+	} else if class := ErrorClass(err); class != ErrorReset && class != ErrorOther {
+		return nil, http.MethodHead, elapsed, err
 	}
+	if err == nil && resp.StatusCode < 400 {
+		utils.Log().Record(utils.LevelDebug, "HEAD请求存活，发送GET读取标题", "url", url, "status", resp.StatusCode)
+	} else {
+		utils.Log().Record(utils.LevelDebug, "HEAD请求不可用，改用GET", "url", url, "status", statusOf(resp), "error", err)
+	}
+	resp, elapsed, err = s.timedRequest(client, transport, http.MethodGet, url, cfg)
+	return resp, http.MethodGet, elapsed, err
+}
 
+// 按 -rate-per-host 等待后发送请求，返回响应和请求耗时（不含等待时间）
+func (s *Session) timedRequest(client *http.Client, transport *http.Transport, method, url string, cfg config.Config) (*http.Response, time.Duration, error) {
+	s.limiter.waitRateLimit(url, cfg)
 	startTime := time.Now()
-	resp, err := client.Get(domain)
-	responseTime := time.Since(startTime)
+	resp, err := doRequest(client, transport, method, url, cfg)
+	return resp, time.Since(startTime), err
+}
+
+// 结果中记录的请求方法，只在使用 -head 时记录
+func methodOf(cfg config.Config, method string) string {
+	if !cfg.Head {
+		return ""
+	}
+	return method
+}
+
+// 响应的状态码，没有响应时为0
+func statusOf(resp *http.Response) int {
+	if resp == nil {
+		return 0
+	}
+	return resp.StatusCode
+}
+
+// 发送请求，指定了 -host-header 时覆盖Host请求头，HTTPS请求同时使用该名称作为SNI；
+// 设置了 UserAgent 时替换Go的默认User-Agent，并附加 RequestHeaders 中的请求头
+func doRequest(client *http.Client, transport *http.Transport, method, url string, cfg config.Config) (*http.Response, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.HostHeader != "" {
+		req.Host = cfg.HostHeader
+		serverName := cfg.HostHeader
+		if host, _, err := net.SplitHostPort(serverName); err == nil {
+			serverName = host
+		}
+		transport.TLSClientConfig = &tls.Config{ServerName: serverName}
+	}
+	if cfg.UserAgent != "" {
+		req.Header.Set("User-Agent", cfg.UserAgent)
+	}
+	for _, header := range cfg.RequestHeaders {
+		name, value, _ := strings.Cut(header, ":")
+		req.Header.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	resp, err := client.Do(req)
+	// 经HTTP代理访问 http:// 目标时，代理认证失败的407响应来自代理而不是目标
+	if err == nil && transport.Proxy != nil && resp.StatusCode == http.StatusProxyAuthRequired && resp.Header.Get("Proxy-Authenticate") != "" {
+		resp.Body.Close()
+		return nil, proxyError(fmt.Errorf("代理要求认证: %s", resp.Status))
+	}
+	return resp, err
+}
+
+// 使用指定协议检查单个域名，input 为输入中的原始目标
+func (s *Session) checkSingleDomain(domain, input string, cfg config.Config, resultChan chan<- Result) {
+	result := Result{
+		Domain:    domain,
+		Alive:     false,
+		Input:     input,
+		IDN:       utils.ToUnicodeTarget(domain),
+		UserAgent: cfg.UserAgent,
+		Proxy:     redactedProxy(cfg.Proxy),
+	}
+
+	// 创建一个带有连接池的客户端
+	transport := newTransport(cfg)
+	client := newClient(cfg, transport)
+
+	connectedIPs := recordIPs(transport)
+	resp, method, responseTime, err := s.probe(client, transport, domain, cfg)
 	result.ResponseTime = responseTime
 
 	if err != nil {
-		result.Message = err.Error()
+		utils.Log().Record(utils.LevelDebug, "无法访问", "domain", domain, "error", err)
+		result.Message = errorMessage(err)
 		result.StatusText = "无法访问"
+		result.ErrorClass = ErrorClass(err)
+		result.IPs = connectedIPs() // 已建立连接后失败（如TLS错误、超时）时保留连接的地址
 		resultChan <- result
 		return
 	}
 	defer resp.Body.Close()
 
+	result.IPs = connectedIPs()
+	s.fillResult(&result, resp, method, client, transport, cfg)
+	resultChan <- result
+}
+
+// 根据得到的响应填写结果，HTTPS检测和指定协议的检测共用。method 为得到响应的请求方法（未使用 -head 时为空），
+// client 和 transport 用于 -favicon 请求图标
+func (s *Session) fillResult(result *Result, resp *http.Response, method string, client *http.Client, transport *http.Transport, cfg config.Config) {
 	result.Status = resp.StatusCode
+	result.Method = method
+	result.FinalURL = resp.Request.URL.String()
+	result.RedirectChain = redirectChain(resp)
+	result.RedirectCrossSite = CrossSiteChain(result.RedirectChain)
+	result.Headers = resp.Header
+	result.CapturedHeaders = captureHeaders(resp.Header, cfg)
 
 	// 根据状态码设置状态文本和存活标志
 	result.StatusText, result.Alive = getStatusTextAndAlive(resp.StatusCode)
-	result.Message = http.StatusText(resp.StatusCode)
-
-	// 提取页面信息
-	if resp.StatusCode < 400 {
-		body, err := io.ReadAll(resp.Body)
-		if err == nil {
+	result.Message = statusMessage(resp)
+
+	// 提取页面信息（HEAD响应没有内容），未读取内容时以 Content-Length 作为近似的内容大小
+	var body []byte
+	var err error
+	result.BodySize, result.BodySizeApprox = resp.ContentLength, true
+	if resp.StatusCode < 400 && method != http.MethodHead {
+		if body, err = io.ReadAll(resp.Body); err == nil {
 			pageContent := string(body)
+			result.BodyHash = hashBody(body)
+			result.BodySize, result.BodySizeApprox = int64(len(body)), false
 			if cfg.ExtractInfo {
 				result.PageInfo = detectPageType(pageContent)
 			}
 			result.Title = extractTitle(pageContent)
 		}
+	} else if cfg.Dedupe {
+		// 错误页面只计算哈希，使内容相同的默认页面（如默认的404页面）归为一组
+		if body, err = io.ReadAll(resp.Body); err == nil {
+			result.BodyHash = hashBody(body)
+			result.BodySize, result.BodySizeApprox = int64(len(body)), false
+		}
+	}
+	if cfg.ExtractInfo {
+		result.Fingerprints = detectFingerprints(resp.Header, body)
+	}
+	if cfg.Favicon && result.Alive {
+		result.FaviconHash = s.fetchFaviconHash(client, transport, resp.Request.URL, cfg)
+	}
+	if shouldStoreResponse(cfg, *result) {
+		result.Response = storeResponse(cfg.StoreResponse, result.Domain, resp, body)
 	}
 
-	// 如果需要截图，使用截图工作池
-	if screenshotPool != nil && (cfg.Screenshot || cfg.ScreenshotAlive) {
-		// 为网站生成唯一的截图文件名
-		screenFilename := generateScreenshotFilename(domain)
+	// 需要截图时只做标记，截图由调用方提交到截图工作池。-screenshot-alive 只截图存活的网页，
+	// 同时指定 -screenshot-protected 时一并截图受保护的网页
+	result.ScreenshotPending = cfg.Screenshot ||
+		cfg.ScreenshotAlive && (result.Alive || cfg.ScreenshotProtect && IsProtected(*result))
+}
 
-		// 确保截图目录存在
-		if err := os.MkdirAll(cfg.ScreenshotDir, 0755); err == nil {
-			// 提交截图任务到工作池
-			resultCh := screenshotPool.Submit(domain, screenFilename, cfg.ScreenshotDir)
+// 把等待截图的结果提交到截图工作池，队列已满时阻塞到任务进入队列为止。
+// 返回的通道在截图结束后给出截图文件的路径，失败时为空字符串，可用 ScreenshotRelPath 转换为结果中的相对路径
+func SubmitScreenshot(pool *screenshot.ScreenshotPool, result Result, dir string) <-chan string {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		utils.Log().Warnf("⚠️  创建截图目录失败，跳过截图: %s - %v\n", result.Domain, err)
+		failed := make(chan string, 1)
+		failed <- ""
+		return failed
+	}
+	return pool.SubmitWait(result.Domain, generateScreenshotFilename(result.Domain), dir, result.UserAgent)
+}
 
-			// 等待截图结果
-			if screenshotPath := <-resultCh; screenshotPath != "" {
-				// 将完整路径转换为相对路径
-				relPath := filepath.Join("screenshots", filepath.Base(screenshotPath))
-				// 确保使用正斜杠
-				relPath = strings.ReplaceAll(relPath, "\\", "/")
-				result.Screenshot = relPath
-			}
-		}
+// 截图文件路径转换为结果和报告中使用的相对路径（screenshots/文件名，使用正斜杠），空路径保持为空
+func ScreenshotRelPath(screenshotPath string) string {
+	if screenshotPath == "" {
+		return ""
 	}
+	relPath := filepath.Join("screenshots", filepath.Base(screenshotPath))
+	return strings.ReplaceAll(relPath, "\\", "/")
+}
 
-	resultChan <- result
+// 受保护：服务有响应但拒绝匿名访问（401、403、407），不计为存活，也不计为无法访问。
+// 401 和 407 的状态文本分别为"需要认证"和"需要代理认证"，仍属于受保护
+const StatusProtected = "受保护"
+
+// 服务要求认证（401）或代理要求认证（407）的状态文本
+const (
+	StatusAuthRequired      = "需要认证"
+	StatusProxyAuthRequired = "需要代理认证"
+)
+
+// 服务限流（429）的状态文本，-adaptive 和 -auto-concurrency 把它当作需要降低并发的信号
+const StatusTooManyRequests = "请求过多"
+
+// 状态码是否表示需要认证或禁止访问
+func IsProtectedStatus(statusCode int) bool {
+	return statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden || statusCode == http.StatusProxyAuthRequired
+}
+
+// 结果是否属于受保护的主机。按状态码判断，从以前的结果文件读取的结果同样适用
+func IsProtected(result Result) bool {
+	return !result.Alive && IsProtectedStatus(result.Status)
 }
 
 // 根据状态码返回对应的状态文本和是否存活
@@ -351,8 +557,14 @@ func getStatusTextAndAlive(statusCode int) (string, bool) {
 		return "存活", true
 	case statusCode == 301 || statusCode == 302:
 		return "重定向", true
-	case statusCode == 403:
-		return "禁止访问", false
+	case statusCode == http.StatusUnauthorized:
+		return StatusAuthRequired, false
+	case statusCode == http.StatusProxyAuthRequired:
+		return StatusProxyAuthRequired, false
+	case IsProtectedStatus(statusCode):
+		return StatusProtected, false
+	case statusCode == http.StatusTooManyRequests:
+		return StatusTooManyRequests, false
 	case statusCode == 404:
 		return "未找到", false
 	case statusCode == 500:
@@ -368,6 +580,137 @@ func getStatusTextAndAlive(statusCode int) (string, bool) {
 	}
 }
 
+// 结果中的消息：状态码的说明，401 和 407 时附上 WWW-Authenticate 或 Proxy-Authenticate 中的认证方式，
+// 如 "Unauthorized (认证方式: Basic, Negotiate)"
+func statusMessage(resp *http.Response) string {
+	message := http.StatusText(resp.StatusCode)
+	var challenges []string
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		challenges = resp.Header.Values("WWW-Authenticate")
+	case http.StatusProxyAuthRequired:
+		challenges = resp.Header.Values("Proxy-Authenticate")
+	}
+	if schemes := authSchemes(challenges); len(schemes) > 0 {
+		message += " (认证方式: " + strings.Join(schemes, ", ") + ")"
+	}
+	return message
+}
+
+// 从认证质询中取出认证方式（如 Basic、Bearer、Digest），重复的（不区分大小写）只保留一个。
+// 一个响应头中可以有以逗号分隔的多个质询，质询的参数（含 "="，引号内可以有逗号）不是认证方式
+func authSchemes(challenges []string) []string {
+	var schemes []string
+	for _, challenge := range challenges {
+		for _, part := range splitOutsideQuotes(challenge) {
+			scheme, _, _ := strings.Cut(strings.TrimSpace(part), " ")
+			duplicate := slices.ContainsFunc(schemes, func(s string) bool { return strings.EqualFold(s, scheme) })
+			if scheme == "" || strings.Contains(scheme, "=") || duplicate {
+				continue
+			}
+			schemes = append(schemes, scheme)
+		}
+	}
+	return schemes
+}
+
+// 按引号外的逗号拆分
+func splitOutsideQuotes(s string) []string {
+	var parts []string
+	quoted, start := false, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// 错误类别
+const (
+	ErrorTimeout = "超时"
+	ErrorDNS     = "DNS解析失败"
+	ErrorRefused = "连接被拒绝"
+	ErrorReset   = "连接被重置"
+	ErrorTLS     = "TLS错误"
+	ErrorOther   = "请求错误"
+	ErrorInvalid = "无效域名"
+	ErrorProxy   = "代理连接失败"
+)
+
+// 根据请求错误判断错误类别
+func ErrorClass(err error) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var recordErr tls.RecordHeaderError
+	var certErr *tls.CertificateVerificationError
+	switch {
+	case isProxyError(err):
+		return ErrorProxy
+	case errors.As(err, &dnsErr):
+		return ErrorDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorRefused
+	case errors.Is(err, syscall.ECONNRESET):
+		return ErrorReset
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorTimeout
+	case errors.As(err, &recordErr), errors.As(err, &certErr):
+		return ErrorTLS
+	default:
+		return ErrorOther
+	}
+}
+
+// 是否为连接代理服务器失败：net/http 把连接代理的错误包装为 Op 为 proxyconnect 的 net.OpError；
+// SOCKS5握手中的错误是 Op 为 "socks connect" 的错误，其中连接被关闭或认证失败属于代理的问题，
+// 代理报告的目标连接失败（如 connection refused）则不属于
+func isProxyError(err error) bool {
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		return false
+	}
+	if opErr.Op == "proxyconnect" {
+		return true
+	}
+	if !strings.HasPrefix(opErr.Op, "socks") {
+		return false
+	}
+	return errors.Is(opErr.Err, io.EOF) || errors.Is(opErr.Err, io.ErrUnexpectedEOF) || errors.Is(opErr.Err, syscall.ECONNRESET) ||
+		strings.Contains(opErr.Err.Error(), "authentication")
+}
+
+// 包装为与 net/http 连接代理失败相同形式的错误
+func proxyError(err error) error {
+	return &net.OpError{Op: "proxyconnect", Net: "tcp", Err: err}
+}
+
+// 请求失败时结果中的消息，代理或DNS解析失败时明确说明，而不是只给出 net/http 的连接错误
+func errorMessage(err error) string {
+	var dnsErr *net.DNSError
+	if isProxyError(err) {
+		return ErrorProxy + ": " + err.Error()
+	}
+	if errors.As(err, &dnsErr) {
+		return "域名解析失败: " + dnsErr.Error()
+	}
+	return err.Error()
+}
+
+// 计算响应内容的哈希（SHA-256前16个十六进制字符）
+func hashBody(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:8])
+}
+
 // 检测页面类型
 func detectPageType(content string) *PageType {
 	lowerContent := strings.ToLower(content)
@@ -441,59 +784,15 @@ func containsAny(content string, patterns []string) bool {
 
 // 生成截图文件名
 func generateScreenshotFilename(domain string) string {
-	// 将域名中的特殊字符替换为下划线
-	filename := strings.ReplaceAll(domain, "://", "_")
-	filename = strings.ReplaceAll(filename, ".", "_")
-	filename = strings.ReplaceAll(filename, ":", "_")
-	filename = strings.ReplaceAll(filename, "/", "_")
-	return filename + ".png"
+	return safeFilename(domain) + ".png"
 }
 
-// 生成错误图片（当无法截图时）
-func generateErrorImage(filename string, screenshotDir string) error {
-	// 创建截图目录（如果不存在）
-	if err := os.MkdirAll(screenshotDir, 0755); err != nil {
-		return fmt.Errorf("创建截图目录失败: %v", err)
-	}
-
-	// 生成一个简单的错误图片
-	width, height := 800, 600
-	upLeft := image.Point{0, 0}
-	lowRight := image.Point{width, height}
-
-	img := image.NewRGBA(image.Rectangle{upLeft, lowRight})
-
-	// 填充白色背景
-	for x := 0; x < width; x++ {
-		for y := 0; y < height; y++ {
-			img.Set(x, y, color.White)
-		}
-	}
-
-	// 添加红色错误文本
-	fontColor := color.RGBA{255, 0, 0, 255} // 红色
-	errorPath := filepath.Join(screenshotDir, filename)
-
-	// 创建图片对象
-	dc := gg.NewContextForRGBA(img)
-
-	// 设置文本颜色
-	dc.SetColor(fontColor)
-
-	// 写入错误消息
-	dc.DrawStringAnchored("无法截取网站截图", float64(width/2), float64(height/2), 0.5, 0.5)
-	dc.DrawStringAnchored("Screenshot Failed", float64(width/2), float64(height/2)+40, 0.5, 0.5)
-
-	// 保存图片
-	f, err := os.Create(errorPath)
-	if err != nil {
-		return fmt.Errorf("创建错误图片文件失败: %v", err)
-	}
-	defer f.Close()
-
-	if err := png.Encode(f, dc.Image()); err != nil {
-		return fmt.Errorf("编码错误图片失败: %v", err)
-	}
-
-	return nil
+// 目标对应的文件名（不含扩展名），截图和 -store-response 使用相同的规则。
+// 将域名中的特殊字符替换为下划线，端口前的冒号替换为两个下划线，
+// 避免 host:8080 与 host.8080 等目标生成相同的文件名
+func safeFilename(domain string) string {
+	filename := strings.ReplaceAll(domain, "://", "_")
+	filename = strings.ReplaceAll(filename, ".", "_")
+	filename = strings.ReplaceAll(filename, ":", "__")
+	return strings.ReplaceAll(filename, "/", "_")
 }
@@ -0,0 +1,81 @@
+package checker
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"subdomain-checker/config"
+)
+
+// 包级别的 CheckDomain：存活、连接被拒绝和域名不存在的目标分别得到对应的状态和错误类别
+func TestCheckDomain(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<title>ok</title>"))
+	}))
+	defer server.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refused := listener.Addr().String()
+	listener.Close()
+
+	tests := []struct {
+		name       string
+		domain     string
+		alive      bool
+		status     int
+		statusText string
+		errorClass string
+	}{
+		{"存活", strings.TrimPrefix(server.URL, "http://"), true, 200, "存活", ""},
+		{"连接被拒绝", refused, false, 0, "无法访问", ErrorRefused},
+		{"域名不存在", "squirrel-nxdomain.invalid", false, 0, "无法访问", ErrorDNS},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := make(chan Result, 1)
+			CheckDomain(tt.domain, config.Config{Timeout: 5}, out)
+			result := <-out
+			if result.Alive != tt.alive || result.Status != tt.status || result.StatusText != tt.statusText || result.ErrorClass != tt.errorClass {
+				t.Errorf("结果为 alive=%v status=%d statusText=%q errorClass=%q，应为 %v %d %q %q",
+					result.Alive, result.Status, result.StatusText, result.ErrorClass, tt.alive, tt.status, tt.statusText, tt.errorClass)
+			}
+		})
+	}
+}
+
+// 截图标记：-screenshot 截图所有有响应的网页，-screenshot-alive 只截图存活的网页，
+// 加上 -screenshot-protected 时一并截图受保护的网页
+func TestFillResultScreenshotPending(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.Config
+		want map[int]bool // 状态码 -> 是否等待截图
+	}{
+		{"不截图", config.Config{}, map[int]bool{200: false, 403: false, 404: false}},
+		{"-screenshot", config.Config{Screenshot: true}, map[int]bool{200: true, 403: true, 404: true}},
+		{"-screenshot-alive", config.Config{ScreenshotAlive: true}, map[int]bool{200: true, 401: false, 403: false, 404: false}},
+		{"-screenshot-protected", config.Config{ScreenshotAlive: true, ScreenshotProtect: true}, map[int]bool{200: true, 401: true, 403: true, 407: true, 404: false}},
+	}
+	session := NewSession(config.Config{}, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for status, want := range tt.want {
+				recorder := httptest.NewRecorder()
+				recorder.WriteHeader(status)
+				resp := recorder.Result()
+				resp.Request = httptest.NewRequest(http.MethodGet, "https://a.example.com/", nil)
+				result := Result{Domain: "https://a.example.com", Input: "a.example.com"}
+				session.fillResult(&result, resp, "", nil, nil, tt.cfg)
+				if result.ScreenshotPending != want {
+					t.Errorf("状态码 %d 的 ScreenshotPending 为 %v，应为 %v", status, result.ScreenshotPending, want)
+				}
+			}
+		})
+	}
+}
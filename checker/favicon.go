@@ -0,0 +1,102 @@
+package checker
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"math/bits"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"subdomain-checker/config"
+	"subdomain-checker/utils"
+)
+
+// 读取图标内容的上限，超过时视为不是图标
+const maxFaviconSize = 1 << 20
+
+// 获取 /favicon.ico（相对于得到响应的地址）并计算图标哈希，使用与检测请求相同的客户端（重定向规则相同）。
+// 图标不存在、不是图片或请求失败时返回空字符串，不影响检测结果
+func (s *Session) fetchFaviconHash(client *http.Client, transport *http.Transport, base *url.URL, cfg config.Config) string {
+	faviconURL := base.ResolveReference(&url.URL{Path: "/favicon.ico"}).String()
+	resp, _, err := s.timedRequest(client, transport, http.MethodGet, faviconURL, cfg)
+	if err != nil {
+		utils.Log().Record(utils.LevelDebug, "获取图标失败", "url", faviconURL, "error", err)
+		return ""
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFaviconSize+1))
+	if err != nil || len(data) == 0 || len(data) > maxFaviconSize || !isImage(resp.Header.Get("Content-Type"), data) {
+		return ""
+	}
+	return faviconHash(data)
+}
+
+// 响应内容是否为图片：内容本身能识别为图片，或声明为图片且内容不是HTML（不存在的路径返回首页等情况）
+func isImage(contentType string, data []byte) bool {
+	sniffed := http.DetectContentType(data)
+	if strings.HasPrefix(sniffed, "image/") {
+		return true
+	}
+	return strings.HasPrefix(strings.ToLower(contentType), "image/") && !strings.HasPrefix(sniffed, "text/html")
+}
+
+// 与 Shodan 相同的图标哈希：对图标内容做base64编码（每76个字符换行，末尾有换行），再计算32位有符号的mmh3哈希
+func faviconHash(data []byte) string {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var b strings.Builder
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76])
+		b.WriteByte('\n')
+		encoded = encoded[76:]
+	}
+	if encoded != "" {
+		b.WriteString(encoded)
+		b.WriteByte('\n')
+	}
+	return strconv.FormatInt(int64(int32(murmur3([]byte(b.String())))), 10)
+}
+
+// MurmurHash3 x86 32位版本，种子为0
+func murmur3(data []byte) uint32 {
+	const c1, c2 = 0xcc9e2d51, 0x1b873593
+	var h uint32
+	n := len(data)
+	for len(data) >= 4 {
+		k := binary.LittleEndian.Uint32(data)
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+		data = data[4:]
+	}
+	var k uint32
+	switch len(data) {
+	case 3:
+		k ^= uint32(data[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(data[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(data[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+	h ^= uint32(n)
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
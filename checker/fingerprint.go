@@ -0,0 +1,124 @@
+package checker
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// 技术指纹规则：任一条件匹配即认为使用了该技术
+type fingerprintRule struct {
+	Name    string
+	Headers map[string]*regexp.Regexp // 响应头名称 -> 匹配值的正则（不区分大小写），值为nil时只要求该响应头存在
+	Body    []string                  // 页面内容中的子串（小写，不区分大小写匹配）
+	Cookies []string                  // Set-Cookie 中的Cookie名称前缀
+	Favicon []string                  // 页面引用的图标地址中的子串（小写）
+}
+
+// 忽略大小写的正则
+func caseInsensitive(pattern string) *regexp.Regexp {
+	return regexp.MustCompile("(?i)" + pattern)
+}
+
+// 技术指纹规则表，顺序即结果中的顺序（Web服务器、语言和框架、应用）
+var fingerprintRules = []fingerprintRule{
+	// Web服务器和CDN
+	{Name: "Nginx", Headers: map[string]*regexp.Regexp{"Server": caseInsensitive(`^nginx`)}, Body: []string{"<center>nginx</center>", "welcome to nginx!"}},
+	{Name: "OpenResty", Headers: map[string]*regexp.Regexp{"Server": caseInsensitive(`^openresty`)}, Body: []string{"<center>openresty</center>"}},
+	{Name: "Tengine", Headers: map[string]*regexp.Regexp{"Server": caseInsensitive(`^tengine`)}},
+	{Name: "Apache", Headers: map[string]*regexp.Regexp{"Server": caseInsensitive(`^apache($|/| )`)}, Body: []string{"<address>apache/"}},
+	{Name: "IIS", Headers: map[string]*regexp.Regexp{"Server": caseInsensitive(`^microsoft-iis`)}, Body: []string{"iis windows server", "iisstart.png"}},
+	{Name: "Caddy", Headers: map[string]*regexp.Regexp{"Server": caseInsensitive(`^caddy`)}},
+	{Name: "Cloudflare", Headers: map[string]*regexp.Regexp{"Server": caseInsensitive(`^cloudflare`), "Cf-Ray": nil}},
+
+	// 语言和框架
+	{Name: "PHP", Headers: map[string]*regexp.Regexp{"X-Powered-By": caseInsensitive(`php`)}, Cookies: []string{"PHPSESSID"}},
+	{Name: "ASP.NET", Headers: map[string]*regexp.Regexp{"X-Powered-By": caseInsensitive(`asp\.net`), "X-Aspnet-Version": nil, "X-Aspnetmvc-Version": nil}, Body: []string{"__viewstate"}, Cookies: []string{"ASP.NET_SessionId", ".AspNetCore."}},
+	{Name: "Java", Cookies: []string{"JSESSIONID"}},
+	{Name: "Tomcat", Body: []string{"apache tomcat"}, Favicon: []string{"tomcat.png", "tomcat.ico"}},
+	{Name: "WebLogic", Body: []string{"oracle weblogic server", "<h2>error 404--not found</h2>"}},
+	{Name: "JBoss", Headers: map[string]*regexp.Regexp{"X-Powered-By": caseInsensitive(`jboss|wildfly|undertow`)}, Body: []string{"welcome to jboss", "<h1>jboss web"}},
+	{Name: "Spring Boot", Headers: map[string]*regexp.Regexp{"X-Application-Context": nil}, Body: []string{"whitelabel error page"}},
+	{Name: "Shiro", Cookies: []string{"rememberMe"}},
+	{Name: "Express", Headers: map[string]*regexp.Regexp{"X-Powered-By": caseInsensitive(`^express`)}},
+	{Name: "Next.js", Headers: map[string]*regexp.Regexp{"X-Powered-By": caseInsensitive(`next\.js`)}, Body: []string{"__next_data__", "/_next/static/"}},
+	{Name: "Nuxt", Body: []string{"window.__nuxt__", "/_nuxt/"}},
+	{Name: "Laravel", Cookies: []string{"laravel_session"}},
+	{Name: "ThinkPHP", Headers: map[string]*regexp.Regexp{"X-Powered-By": caseInsensitive(`thinkphp`)}, Body: []string{"thinkphp.cn"}},
+	{Name: "Django", Body: []string{"csrfmiddlewaretoken"}, Cookies: []string{"csrftoken", "django_language"}},
+	{Name: "Flask", Headers: map[string]*regexp.Regexp{"Server": caseInsensitive(`werkzeug`)}},
+
+	// 应用
+	{Name: "WordPress", Headers: map[string]*regexp.Regexp{"Link": caseInsensitive(`rel="https://api\.w\.org/"`)}, Body: []string{"/wp-content/", "/wp-includes/", `content="wordpress`}, Cookies: []string{"wordpress_", "wp-settings-"}, Favicon: []string{"/wp-content/"}},
+	{Name: "Drupal", Headers: map[string]*regexp.Regexp{"X-Generator": caseInsensitive(`drupal`), "X-Drupal-Cache": nil}, Body: []string{"drupal-settings-json", `content="drupal`}, Favicon: []string{"/misc/favicon.ico"}},
+	{Name: "Joomla", Body: []string{`content="joomla`, "/media/jui/"}},
+	{Name: "Jenkins", Headers: map[string]*regexp.Regexp{"X-Jenkins": nil}, Cookies: []string{"JSESSIONID."}},
+	{Name: "GitLab", Body: []string{"gitlab-logo", `content="gitlab`}, Cookies: []string{"_gitlab_session"}},
+	{Name: "Grafana", Body: []string{"grafana-app", "window.grafanabootdata"}, Cookies: []string{"grafana_session"}},
+	{Name: "Kibana", Headers: map[string]*regexp.Regexp{"Kbn-Name": nil, "Kbn-Version": nil}},
+	{Name: "Confluence", Headers: map[string]*regexp.Regexp{"X-Confluence-Request-Time": nil}, Body: []string{"confluence-context-path"}},
+	{Name: "Jira", Headers: map[string]*regexp.Regexp{"X-Arequestid": nil}, Body: []string{"jira-context-path"}},
+	{Name: "phpMyAdmin", Body: []string{"<title>phpmyadmin"}, Cookies: []string{"phpMyAdmin"}},
+	{Name: "Swagger UI", Body: []string{"swagger-ui"}},
+	{Name: "Nacos", Body: []string{"<title>nacos</title>"}},
+}
+
+// 页面中引用的图标地址
+var faviconRegex = regexp.MustCompile(`(?i)<link[^>]+rel=["']?(?:shortcut )?icon["']?[^>]*>`)
+var hrefRegex = regexp.MustCompile(`(?i)href=["']?([^"' >]+)`)
+
+// 根据响应头和已读取的页面内容识别使用的技术，不发送额外的请求；没有匹配时返回nil
+func detectFingerprints(header http.Header, body []byte) []string {
+	lowerBody := strings.ToLower(string(body))
+	var cookies []string
+	for _, line := range header.Values("Set-Cookie") {
+		if cookie, err := http.ParseSetCookie(line); err == nil {
+			cookies = append(cookies, cookie.Name)
+		}
+	}
+	var favicons []string
+	for _, link := range faviconRegex.FindAllString(lowerBody, -1) {
+		if match := hrefRegex.FindStringSubmatch(link); match != nil {
+			favicons = append(favicons, match[1])
+		}
+	}
+
+	var found []string
+	for _, rule := range fingerprintRules {
+		if rule.matches(header, lowerBody, cookies, favicons) {
+			found = append(found, rule.Name)
+		}
+	}
+	return found
+}
+
+// 规则是否匹配
+func (rule fingerprintRule) matches(header http.Header, lowerBody string, cookies, favicons []string) bool {
+	for name, pattern := range rule.Headers {
+		for _, value := range header.Values(name) {
+			if pattern == nil || pattern.MatchString(value) {
+				return true
+			}
+		}
+	}
+	for _, substr := range rule.Body {
+		if strings.Contains(lowerBody, substr) {
+			return true
+		}
+	}
+	for _, prefix := range rule.Cookies {
+		for _, cookie := range cookies {
+			if strings.HasPrefix(strings.ToLower(cookie), strings.ToLower(prefix)) {
+				return true
+			}
+		}
+	}
+	for _, hint := range rule.Favicon {
+		for _, favicon := range favicons {
+			if strings.Contains(favicon, hint) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package checker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"subdomain-checker/config"
+)
+
+// 检测单个目标并返回结果
+func checkOne(t *testing.T, target string, cfg config.Config) Result {
+	t.Helper()
+	out := make(chan Result, 1)
+	CheckDomain(target, cfg, out)
+	return <-out
+}
+
+// -head 时只发送HEAD，结果中没有标题；加 -head-title 时存活的目标再发送GET读取标题，以GET的结果为准
+func TestHeadTitle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/forbidden" && r.Method == http.MethodGet:
+			// HEAD返回200而GET返回403的服务器
+			w.WriteHeader(http.StatusForbidden)
+		case r.URL.Path == "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.Write([]byte("<html><title>Hello</title></html>"))
+		}
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	tests := []struct {
+		name       string
+		path       string
+		headTitle  bool
+		wantMethod string
+		wantStatus int
+		wantTitle  string
+	}{
+		{"只发送HEAD", "/", false, http.MethodHead, 200, ""},
+		{"HEAD后GET读取标题", "/", true, http.MethodGet, 200, "Hello"},
+		{"以GET的结果为准", "/forbidden", true, http.MethodGet, 403, ""},
+		{"错误页面不发送GET", "/missing", true, http.MethodHead, 404, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Config{Timeout: 5, Head: true, HeadTitle: tt.headTitle}
+			result := checkOne(t, host+tt.path, cfg)
+			if result.Method != tt.wantMethod || result.Status != tt.wantStatus || result.Title != tt.wantTitle {
+				t.Errorf("请求方法 %q、状态码 %d、标题 %q，应为 %q、%d、%q",
+					result.Method, result.Status, result.Title, tt.wantMethod, tt.wantStatus, tt.wantTitle)
+			}
+		})
+	}
+}
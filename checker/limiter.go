@@ -0,0 +1,70 @@
+package checker
+
+import (
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+
+	"subdomain-checker/config"
+	"subdomain-checker/utils"
+)
+
+// -rate-per-host 的限速器：同一主域名（eTLD+1）的请求之间至少间隔一段时间，不同主域名互不影响
+type apexLimiter struct {
+	mu   sync.Mutex
+	next map[string]time.Time // 各组下一个请求最早可以开始的时间
+}
+
+// 主机所属的主域名（eTLD+1，如 a.b.example.co.uk 属于 example.co.uk），用于限速分组和判断重定向是否跨站。
+// 国际化域名先转换为punycode，Unicode和punycode两种写法归入同一组；IP地址和无法识别的主机（如 localhost）各自成组
+func apexOfHost(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(strings.Trim(host, "[]")), ".")
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	if ascii, err := utils.ToASCIITarget(host); err == nil {
+		host = ascii
+	}
+	apex, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return apex
+}
+
+// 等待轮到该主机的请求：在锁内预约所属组的下一个时间点，然后在锁外等待，
+// 同一组的请求依次间隔 interval，其他组的请求不受影响
+func (l *apexLimiter) wait(host string, interval time.Duration) {
+	group := apexOfHost(host)
+	l.mu.Lock()
+	start := time.Now()
+	if next := l.next[group]; next.After(start) {
+		start = next
+	}
+	l.next[group] = start.Add(interval)
+	l.mu.Unlock()
+	time.Sleep(time.Until(start))
+}
+
+// 已限速的主域名分组数量
+func (l *apexLimiter) groups() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.next)
+}
+
+// 设置了 RatePerHost 时，等待与同一主域名上一个请求的间隔足够后再发送请求
+func (l *apexLimiter) waitRateLimit(target string, cfg config.Config) {
+	if cfg.RatePerHost <= 0 {
+		return
+	}
+	host := target
+	if u, err := url.Parse(target); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	l.wait(host, cfg.RatePerHost)
+}
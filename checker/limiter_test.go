@@ -0,0 +1,56 @@
+package checker
+
+import (
+	"testing"
+	"time"
+)
+
+// 主域名分组：国际化域名的两种写法归入同一组，IP地址各自成组，多级公共后缀取 eTLD+1，无法识别的主机按自身分组
+func TestApexOfHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"www.example.com", "example.com"},
+		{"WWW.Example.COM.", "example.com"},
+		{"a.bücher.example", "xn--bcher-kva.example"},
+		{"bücher.example", "xn--bcher-kva.example"},
+		{"xn--bcher-kva.example", "xn--bcher-kva.example"},
+		{"10.0.0.1", "10.0.0.1"},
+		{"10.0.0.2", "10.0.0.2"},
+		{"[::1]", "::1"},
+		{"::1", "::1"},
+		{"a.b.example.co.uk", "example.co.uk"},
+		{"example.co.uk", "example.co.uk"},
+		{"localhost", "localhost"},
+		{"intranet", "intranet"},
+	}
+	for _, tt := range tests {
+		if got := apexOfHost(tt.host); got != tt.want {
+			t.Errorf("%s 的主域名为 %q，应为 %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+// 同一主域名下的两个主机依次间隔 interval，其他主域名的请求不用等待
+func TestApexLimiterPacing(t *testing.T) {
+	const interval = 100 * time.Millisecond
+	l := &apexLimiter{next: make(map[string]time.Time)}
+
+	start := time.Now()
+	l.wait("a.example.com", interval)
+	l.wait("b.example.com", interval)
+	if elapsed := time.Since(start); elapsed < interval-10*time.Millisecond {
+		t.Errorf("同一主域名的第二个请求只等待了 %s，应至少 %s", elapsed, interval)
+	}
+
+	start = time.Now()
+	l.wait("www.example.org", interval)
+	l.wait("10.0.0.1", interval)
+	if elapsed := time.Since(start); elapsed > interval/2 {
+		t.Errorf("不同主域名的请求等待了 %s，应不受影响", elapsed)
+	}
+	if n := l.groups(); n != 3 {
+		t.Errorf("限速分组为 %d，应为 3", n)
+	}
+}
@@ -0,0 +1,85 @@
+package checker
+
+import (
+	"math/rand"
+	"net/url"
+	"sync"
+	"time"
+
+	"subdomain-checker/utils"
+)
+
+// 代理连续失败过多时暂停使用的时间
+const proxyBenchDuration = time.Minute
+
+// -proxy-file 的代理池：按目标依次或随机选择代理，连续失败超过 maxFails 次的代理暂停使用一段时间
+type proxyPool struct {
+	mu       sync.Mutex
+	proxies  []*pooledProxy
+	random   bool
+	maxFails int
+	next     int // 依次使用时下一个代理的序号
+}
+
+// 代理池中的一个代理
+type pooledProxy struct {
+	url          *url.URL
+	fails        int       // 连续失败次数
+	benchedUntil time.Time // 暂停使用到该时间，未暂停时为零值
+}
+
+// 创建按目标轮换的代理池，random 为true时随机选择，否则依次使用；列表为空时返回nil
+func newProxyPool(list []*url.URL, random bool, maxFails int) *proxyPool {
+	if len(list) == 0 {
+		return nil
+	}
+	pool := &proxyPool{random: random, maxFails: maxFails}
+	for _, proxyURL := range list {
+		pool.proxies = append(pool.proxies, &pooledProxy{url: proxyURL})
+	}
+	return pool
+}
+
+// 为一个目标选择代理。跳过暂停中的代理；全部暂停时选择最早恢复的一个，而不是让检测停下来
+func (p *proxyPool) pick() *pooledProxy {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	var available []*pooledProxy
+	for _, proxy := range p.proxies {
+		if now.After(proxy.benchedUntil) {
+			available = append(available, proxy)
+		}
+	}
+	if len(available) == 0 {
+		earliest := p.proxies[0]
+		for _, proxy := range p.proxies[1:] {
+			if proxy.benchedUntil.Before(earliest.benchedUntil) {
+				earliest = proxy
+			}
+		}
+		return earliest
+	}
+	if p.random {
+		return available[rand.Intn(len(available))]
+	}
+	proxy := available[p.next%len(available)]
+	p.next++
+	return proxy
+}
+
+// 记录一次检测中代理是否失败，连续失败超过 maxFails 次时暂停使用
+func (p *proxyPool) report(proxy *pooledProxy, failed bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !failed {
+		proxy.fails = 0
+		return
+	}
+	proxy.fails++
+	if proxy.fails > p.maxFails {
+		proxy.fails = 0
+		proxy.benchedUntil = time.Now().Add(proxyBenchDuration)
+		utils.Log().Warnf("⚠️  代理 %s 连续失败超过 %d 次，暂停使用 %s\n", proxy.url.Redacted(), p.maxFails, proxyBenchDuration)
+	}
+}
@@ -0,0 +1,55 @@
+package checker
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"subdomain-checker/config"
+	"subdomain-checker/utils"
+)
+
+// -store-response 保存的响应内容上限，超过的部分截断
+const maxStoredBody = 1 << 20
+
+// 是否需要保存该结果的原始响应，-store-response-alive 时只保存存活的主机
+func shouldStoreResponse(cfg config.Config, result Result) bool {
+	return cfg.StoreResponse != "" && (result.Alive || !cfg.StoreResponseAlive)
+}
+
+// 把最终响应（请求行、状态行、响应头和截断后的内容）写入 -store-response 目录，返回文件路径（使用正斜杠），
+// 失败时记录警告并返回空字符串。body 为已读取的内容，为nil时从响应中读取不超过上限的内容
+func storeResponse(dir, domain string, resp *http.Response, body []byte) string {
+	if body == nil {
+		body, _ = io.ReadAll(io.LimitReader(resp.Body, maxStoredBody+1))
+	}
+	truncated := len(body) > maxStoredBody
+	if truncated {
+		body = body[:maxStoredBody]
+	}
+
+	var buf bytes.Buffer
+	req := resp.Request
+	fmt.Fprintf(&buf, "%s %s %s\r\nHost: %s\r\n\r\n", req.Method, req.URL.RequestURI(), req.Proto, req.Host)
+	fmt.Fprintf(&buf, "%s %s\r\n", resp.Proto, resp.Status)
+	resp.Header.Write(&buf)
+	buf.WriteString("\r\n")
+	buf.Write(body)
+	if truncated {
+		fmt.Fprintf(&buf, "\n\n[响应内容超过 %d 字节，已截断]\n", maxStoredBody)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		utils.Log().Warnf("⚠️  创建响应保存目录失败: %s - %v\n", domain, err)
+		return ""
+	}
+	path := filepath.Join(dir, safeFilename(domain)+".txt")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		utils.Log().Warnf("⚠️  保存原始响应失败: %s - %v\n", domain, err)
+		return ""
+	}
+	return filepath.ToSlash(path)
+}
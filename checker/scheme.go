@@ -0,0 +1,39 @@
+package checker
+
+import (
+	"strings"
+
+	"subdomain-checker/config"
+)
+
+// -paths 时同一主机的各路径使用同一个协议：第一个开始检测的路径按常规顺序（先HTTPS后HTTP）选定协议，
+// 其余路径等它完成后直接使用该协议，不再各自先尝试一种协议再回退
+type schemeChoice struct {
+	ready  chan struct{} // 选定协议后关闭
+	scheme string        // 得到响应的协议，第一个路径没有得到响应时为空
+}
+
+// 按主机选定的协议检测目标。该主机第一个到达的目标按常规顺序检测并记录得到响应的协议；
+// 返回false表示该主机没有选定协议（第一个目标没有得到响应），由调用方按常规顺序检测
+func (s *Session) checkWithChosenScheme(domain, host string, cfg config.Config, resultChan chan<- Result) bool {
+	value, loaded := s.schemes.LoadOrStore(host, &schemeChoice{ready: make(chan struct{})})
+	choice := value.(*schemeChoice)
+	if !loaded {
+		cfg.Paths = ""
+		out := make(chan Result, 1)
+		s.CheckDomain(domain, cfg, out)
+		result := <-out
+		if result.Status != 0 {
+			choice.scheme, _, _ = strings.Cut(result.Domain, "://")
+		}
+		close(choice.ready)
+		resultChan <- result
+		return true
+	}
+	<-choice.ready
+	if choice.scheme == "" {
+		return false
+	}
+	s.checkSingleDomain(choice.scheme+"://"+domain, domain, cfg, resultChan)
+	return true
+}
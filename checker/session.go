@@ -0,0 +1,36 @@
+package checker
+
+import (
+	"net/url"
+	"sync"
+	"time"
+
+	"subdomain-checker/config"
+)
+
+// 检测会话：一次运行中所有检测共用的状态，包括 -rate-per-host 的限速器、-proxy-file 的代理池
+// 和 -paths 按主机选定的协议。不同的会话互不影响，会话不再使用后这些状态随之释放
+type Session struct {
+	limiter *apexLimiter
+	proxies *proxyPool // 未使用 -proxy-file 时为nil
+	schemes sync.Map   // 以主机（含端口）为键的 *schemeChoice
+}
+
+// 创建检测会话。proxies 不为空时按目标轮换使用其中的代理，轮换方式和暂停前允许的连续失败次数取自
+// cfg.ProxyRotation 和 cfg.ProxyMaxFails；为空时按 cfg.Proxy 直接连接或使用单个代理
+func NewSession(cfg config.Config, proxies []*url.URL) *Session {
+	return &Session{
+		limiter: &apexLimiter{next: make(map[string]time.Time)},
+		proxies: newProxyPool(proxies, cfg.ProxyRotation == "random", cfg.ProxyMaxFails),
+	}
+}
+
+// 检测单个目标，使用新的会话：不与其他检测共用限速、代理轮换和协议选择
+func CheckDomain(domain string, cfg config.Config, resultChan chan<- Result) {
+	NewSession(cfg, nil).CheckDomain(domain, cfg, resultChan)
+}
+
+// -rate-per-host 检测到的主域名分组数量，未启用时为0
+func (s *Session) RateLimitGroups() int {
+	return s.limiter.groups()
+}
@@ -0,0 +1,52 @@
+package checker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"subdomain-checker/config"
+)
+
+// 限速分组、协议选择和代理池只属于创建它们的会话，新的会话从头开始
+func TestSessionScope(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+	cfg := config.Config{Timeout: 5, RatePerHost: time.Millisecond, Paths: "/,/admin"}
+
+	first := NewSession(cfg, nil)
+	out := make(chan Result, 2)
+	first.CheckDomain(host, cfg, out)
+	first.CheckDomain(host+"/admin", cfg, out)
+	for i := 0; i < 2; i++ {
+		if result := <-out; !strings.HasPrefix(result.Domain, "http://") {
+			t.Errorf("检测结果为 %s，应使用HTTP", result.Domain)
+		}
+	}
+	if n := first.RateLimitGroups(); n != 1 {
+		t.Errorf("第一个会话的限速分组为 %d，应为 1", n)
+	}
+	if _, ok := first.schemes.Load(host); !ok {
+		t.Errorf("第一个会话没有记录 %s 的协议", host)
+	}
+
+	second := NewSession(cfg, nil)
+	if n := second.RateLimitGroups(); n != 0 {
+		t.Errorf("新会话的限速分组为 %d，应为 0", n)
+	}
+	if _, ok := second.schemes.Load(host); ok {
+		t.Errorf("新会话沿用了 %s 的协议", host)
+	}
+	if second.proxies != nil {
+		t.Error("没有代理列表的会话不应轮换代理")
+	}
+
+	proxyURL, _ := url.Parse("http://127.0.0.1:1")
+	if pool := NewSession(cfg, []*url.URL{proxyURL}).proxies; pool == nil || pool.proxies[0].url != proxyURL {
+		t.Error("代理列表没有用于新会话")
+	}
+}
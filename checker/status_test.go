@@ -0,0 +1,55 @@
+package checker
+
+import "testing"
+
+// 状态码对应的状态文本和是否存活：401、403、407 属于受保护，不计为存活
+func TestStatusTextAndAlive(t *testing.T) {
+	tests := []struct {
+		status    int
+		wantText  string
+		wantAlive bool
+	}{
+		{200, "存活", true},
+		{204, "存活", true},
+		{301, "重定向", true},
+		{302, "重定向", true},
+		{304, "存活", true},
+		{307, "存活", true},
+		{401, StatusAuthRequired, false},
+		{403, StatusProtected, false},
+		{404, "未找到", false},
+		{407, StatusProxyAuthRequired, false},
+		{429, StatusTooManyRequests, false},
+		{500, "服务器错误", false},
+		{502, "网关错误", false},
+		{503, "服务不可用", false},
+		{504, "无法访问", false},
+	}
+	for _, tt := range tests {
+		text, alive := getStatusTextAndAlive(tt.status)
+		if text != tt.wantText || alive != tt.wantAlive {
+			t.Errorf("状态码 %d 得到 %q、存活 %v，应为 %q、%v", tt.status, text, alive, tt.wantText, tt.wantAlive)
+		}
+	}
+}
+
+// 是否受保护只看状态码：以前版本的结果文件中 401 和 407 的状态文本也是"受保护"
+func TestIsProtected(t *testing.T) {
+	tests := []struct {
+		result Result
+		want   bool
+	}{
+		{Result{Status: 401, StatusText: StatusProtected}, true},
+		{Result{Status: 401, StatusText: StatusAuthRequired}, true},
+		{Result{Status: 403, StatusText: StatusProtected}, true},
+		{Result{Status: 407, StatusText: StatusProtected}, true},
+		{Result{Status: 429, StatusText: "无法访问"}, false},
+		{Result{Status: 200, Alive: true, StatusText: "存活"}, false},
+		{Result{StatusText: StatusProtected}, false},
+	}
+	for _, tt := range tests {
+		if got := IsProtected(tt.result); got != tt.want {
+			t.Errorf("%d %q 是否受保护为 %v，应为 %v", tt.result.Status, tt.result.StatusText, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package checkpoint
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"subdomain-checker/checker"
+)
+
+// 检查点文件格式版本，格式不兼容时递增
+const Version = 1
+
+// 两次写入检查点状态的最短间隔，结果本身每批都会追加写入
+const flushInterval = 5 * time.Second
+
+// 检查点状态，已检测的结果逐行追加到单独的JSONL文件中，
+// 状态文件只记录其中有效的行数，异常退出时多写的半行会在恢复时丢弃
+type State struct {
+	Version     int       `json:"version"`
+	Input       string    `json:"input"`        // 命令行中的目标参数
+	Targets     int       `json:"targets"`      // 目标总数
+	Processed   int       `json:"processed"`    // 结果文件中有效的结果数
+	ResultsFile string    `json:"results_file"` // 结果文件名，相对于检查点文件所在目录
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// 检查点写入器，并发安全
+type Writer struct {
+	path      string
+	state     State
+	results   *os.File
+	lastFlush time.Time
+	mu        sync.Mutex
+}
+
+// 结果文件的路径
+func resultsPath(path string, state State) string {
+	return filepath.Join(filepath.Dir(path), state.ResultsFile)
+}
+
+// 创建新的检查点，已存在的同名检查点会被覆盖
+func Create(path, input string, targets int) (*Writer, error) {
+	state := State{
+		Version:     Version,
+		Input:       input,
+		Targets:     targets,
+		ResultsFile: filepath.Base(path) + ".results.jsonl",
+	}
+	results, err := os.Create(resultsPath(path, state))
+	if err != nil {
+		return nil, fmt.Errorf("创建检查点结果文件失败: %v", err)
+	}
+	w := &Writer{path: path, state: state, results: results}
+	if err := w.writeState(); err != nil {
+		results.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// 读取检查点和其中已检测的结果，返回的写入器在原有结果之后继续追加
+func Resume(path string) (*Writer, []checker.Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取检查点失败: %v", err)
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, nil, fmt.Errorf("解析检查点 %s 失败: %v", path, err)
+	}
+	if state.Version != Version {
+		return nil, nil, fmt.Errorf("不支持的检查点版本: %d (当前版本: %d)", state.Version, Version)
+	}
+
+	results, err := os.OpenFile(resultsPath(path, state), os.O_RDWR, 0644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("打开检查点结果文件失败: %v", err)
+	}
+
+	// 只读取状态中记录的行数，并截掉之后未确认的内容
+	previous := make([]checker.Result, 0, state.Processed)
+	var offset int64
+	reader := bufio.NewReader(results)
+	for len(previous) < state.Processed {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			results.Close()
+			return nil, nil, fmt.Errorf("检查点结果文件不完整: 需要 %d 条结果，只读取到 %d 条", state.Processed, len(previous))
+		}
+		var result checker.Result
+		if err := json.Unmarshal(line, &result); err != nil {
+			results.Close()
+			return nil, nil, fmt.Errorf("解析检查点结果失败(第%d行): %v", len(previous)+1, err)
+		}
+		previous = append(previous, result)
+		offset += int64(len(line))
+	}
+	if err := results.Truncate(offset); err != nil {
+		results.Close()
+		return nil, nil, fmt.Errorf("截断检查点结果文件失败: %v", err)
+	}
+	if _, err := results.Seek(offset, 0); err != nil {
+		results.Close()
+		return nil, nil, err
+	}
+
+	return &Writer{path: path, state: state, results: results, lastFlush: time.Now()}, previous, nil
+}
+
+// 检查点文件路径
+func (w *Writer) Path() string {
+	return w.path
+}
+
+// 检查点状态
+func (w *Writer) State() State {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.state
+}
+
+// 追加一批结果，距上次写入状态超过 flushInterval 时同时更新状态文件
+func (w *Writer) Add(results []checker.Result) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	buf := bufio.NewWriter(w.results)
+	encoder := json.NewEncoder(buf)
+	for _, result := range results {
+		if err := encoder.Encode(result); err != nil {
+			return fmt.Errorf("写入检查点结果失败: %v", err)
+		}
+	}
+	if err := buf.Flush(); err != nil {
+		return fmt.Errorf("写入检查点结果失败: %v", err)
+	}
+	w.state.Processed += len(results)
+
+	if time.Since(w.lastFlush) < flushInterval {
+		return nil
+	}
+	return w.writeState()
+}
+
+// 写入状态并关闭结果文件
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.writeState()
+	if cerr := w.results.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// 先同步结果文件，再通过临时文件和重命名原子地替换状态文件
+func (w *Writer) writeState() error {
+	if err := w.results.Sync(); err != nil {
+		return fmt.Errorf("同步检查点结果文件失败: %v", err)
+	}
+	w.state.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(w.state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(w.path), filepath.Base(w.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("写入检查点失败: %v", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入检查点失败: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入检查点失败: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入检查点失败: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), w.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入检查点失败: %v", err)
+	}
+	w.lastFlush = time.Now()
+	return nil
+}
@@ -0,0 +1,79 @@
+package checkpoint
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"subdomain-checker/checker"
+)
+
+// 分块处理时暂存已完成结果的临时JSONL文件，结果写入后即可从内存中释放，结束时再统一读回
+type Spool struct {
+	file  *os.File
+	count int
+}
+
+// 在系统临时目录中创建暂存文件
+func NewSpool() (*Spool, error) {
+	file, err := os.CreateTemp("", "squirrel-chunks-*.jsonl")
+	if err != nil {
+		return nil, fmt.Errorf("创建分块暂存文件失败: %v", err)
+	}
+	return &Spool{file: file}, nil
+}
+
+// 已暂存的结果数
+func (s *Spool) Len() int {
+	return s.count
+}
+
+// 追加一批结果，非并发安全，调用方需自行加锁
+func (s *Spool) Add(results []checker.Result) error {
+	buf := bufio.NewWriter(s.file)
+	encoder := json.NewEncoder(buf)
+	for _, result := range results {
+		if err := encoder.Encode(result); err != nil {
+			return fmt.Errorf("写入分块暂存文件失败: %v", err)
+		}
+	}
+	if err := buf.Flush(); err != nil {
+		return fmt.Errorf("写入分块暂存文件失败: %v", err)
+	}
+	s.count += len(results)
+	return nil
+}
+
+// 按写入顺序读回全部结果，之后仍可继续追加
+func (s *Spool) ReadAll() ([]checker.Result, error) {
+	file, err := os.Open(s.file.Name())
+	if err != nil {
+		return nil, fmt.Errorf("读取分块暂存文件失败: %v", err)
+	}
+	defer file.Close()
+
+	results := make([]checker.Result, 0, s.count)
+	reader := bufio.NewReader(file)
+	for len(results) < s.count {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return nil, fmt.Errorf("分块暂存文件不完整: 需要 %d 条结果，只读取到 %d 条", s.count, len(results))
+		}
+		var result checker.Result
+		if err := json.Unmarshal(line, &result); err != nil {
+			return nil, fmt.Errorf("解析分块暂存结果失败(第%d行): %v", len(results)+1, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// 关闭并删除暂存文件
+func (s *Spool) Close() error {
+	err := s.file.Close()
+	if rerr := os.Remove(s.file.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
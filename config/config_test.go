@@ -0,0 +1,200 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// scan 子命令注册的全部参数及默认值。增加、删除参数或修改默认值时需要同时更新这里（和README中的参数说明）
+var scanFlags = map[string]string{
+	"adaptive":               "false",
+	"allow-large-cidr":       "false",
+	"append-ports":           "",
+	"auto-concurrency":       "false",
+	"checkpoint":             "",
+	"chunk-pause":            "0s",
+	"chunk-size":             "0",
+	"compress":               "false",
+	"concurrency":            "10",
+	"config":                 "",
+	"debug-pprof":            "",
+	"debug-stats":            "0s",
+	"dedupe":                 "false",
+	"diff":                   "",
+	"dingtalk-secret":        "",
+	"excel":                  "",
+	"excel-inline-thumbs":    "false",
+	"excel-no-images":        "false",
+	"exclude":                "",
+	"exclude-file":           "",
+	"excluded-output":        "",
+	"exec":                   "",
+	"exec-concurrency":       "4",
+	"exec-timeout":           "30s",
+	"extract":                "false",
+	"fail-on-alive":          "false",
+	"fail-on-new":            "",
+	"favicon":                "false",
+	"fields":                 "",
+	"filter-host":            "",
+	"follow":                 "false",
+	"head":                   "false",
+	"head-title":             "false",
+	"headers-capture":        "",
+	"history-file":           "",
+	"host-header":            "",
+	"html":                   "",
+	"http-only":              "false",
+	"https-only":             "false",
+	"include-protected":      "false",
+	"input-format":           "auto",
+	"interval":               "6h0m0s",
+	"json":                   "",
+	"jsonl":                  "",
+	"log-file":               "",
+	"log-json":               "false",
+	"log-level":              "debug",
+	"match-host":             "",
+	"max-hosts":              "0",
+	"max-memory":             "",
+	"max-redirects":          "10",
+	"monitor":                "false",
+	"no-auto-tune":           "false",
+	"no-color":               "false",
+	"no-emoji":               "false",
+	"no-history":             "false",
+	"notify":                 "",
+	"o":                      "",
+	"only-alive":             "false",
+	"only-alive-from-input":  "false",
+	"output":                 "",
+	"output-all":             "",
+	"output-failed":          "",
+	"overrides":              "",
+	"paths":                  "",
+	"plain":                  "false",
+	"plain-fields":           "",
+	"ports":                  "",
+	"preset":                 "",
+	"print-config":           "false",
+	"proxy":                  "",
+	"proxy-file":             "",
+	"proxy-max-fails":        "3",
+	"proxy-rotation":         "round-robin",
+	"random-ua":              "false",
+	"rate-per-host":          "0s",
+	"recheck-dead":           "false",
+	"resume":                 "",
+	"reverse":                "false",
+	"sample":                 "0",
+	"screenshot":             "false",
+	"screenshot-alive":       "false",
+	"screenshot-concurrency": "0",
+	"screenshot-dir":         "screenshots",
+	"screenshot-protected":   "false",
+	"seed":                   "0",
+	"shard":                  "",
+	"silent":                 "false",
+	"simple-html":            "",
+	"sort":                   "",
+	"sqlite":                 "",
+	"stats-file":             "",
+	"store-response":         "",
+	"store-response-alive":   "false",
+	"time":                   "false",
+	"timeout":                "10",
+	"top":                    "10",
+	"trace-file":             "",
+	"tui":                    "false",
+	"user-agent":             "",
+	"verbose":                "false",
+	"version":                "false",
+	"web":                    "",
+	"webhook":                "",
+	"webhook-header":         "",
+	"write-config":           "",
+}
+
+// 注册的参数名和默认值与 scanFlags 一致，report 和 history 子命令各多一个参数
+func TestFlagSurface(t *testing.T) {
+	tests := []struct {
+		command string
+		extra   map[string]string
+	}{
+		{CommandScan, nil},
+		{CommandReport, map[string]string{"from": ""}},
+		{CommandHistory, map[string]string{"last": "10"}},
+		{CommandMerge, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			args := []string{"placeholder"}
+			if tt.command == CommandReport {
+				args = []string{"results.json"}
+			}
+			parseArgs(t, tt.command, args...)
+			want := make(map[string]string, len(scanFlags)+len(tt.extra))
+			for name, value := range scanFlags {
+				want[name] = value
+			}
+			for name, value := range tt.extra {
+				want[name] = value
+			}
+			got := make(map[string]string)
+			flag.VisitAll(func(f *flag.Flag) {
+				got[f.Name] = f.DefValue
+			})
+			for name, value := range want {
+				if def, ok := got[name]; !ok {
+					t.Errorf("缺少参数 -%s", name)
+				} else if def != value {
+					t.Errorf("-%s 的默认值为 %q，应为 %q", name, def, value)
+				}
+			}
+			for name := range got {
+				if _, ok := want[name]; !ok {
+					t.Errorf("多出参数 -%s", name)
+				}
+			}
+		})
+	}
+}
+
+// 各子命令的位置参数
+func TestParseFlagsPositional(t *testing.T) {
+	tests := []struct {
+		command string
+		args    []string
+		check   func(c *Config) bool
+		wantErr string
+	}{
+		{CommandScan, []string{"-timeout", "3", "domains.txt"}, func(c *Config) bool { return c.Input == "domains.txt" && c.Timeout == 3 }, ""},
+		{CommandScan, []string{"domains.txt", "-timeout", "3"}, nil, "多余的参数: -timeout 3"},
+		{CommandReport, []string{"results.json"}, func(c *Config) bool { return c.From == "results.json" }, ""},
+		{CommandReport, nil, nil, "需要用 -from 指定结果文件"},
+		{CommandDiff, []string{"old.json", "new.json"}, func(c *Config) bool { return c.DiffBaseline == "old.json" && c.From == "new.json" }, ""},
+		{CommandDiff, []string{"old.json"}, nil, "需要指定基线结果文件和新结果文件两个参数"},
+		{CommandMerge, []string{"a.json", "b.json"}, func(c *Config) bool { return slices.Equal(c.MergeFrom, []string{"a.json", "b.json"}) }, ""},
+		{CommandResume, []string{"run.ckpt"}, func(c *Config) bool { return c.Resume == "run.ckpt" }, ""},
+		{CommandResume, nil, nil, "需要指定检查点文件"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command+" "+strings.Join(tt.args, " "), func(t *testing.T) {
+			if tt.wantErr == "" {
+				if cfg := parseArgs(t, tt.command, tt.args...); !tt.check(cfg) {
+					t.Errorf("解析结果不符: %+v", cfg)
+				}
+				return
+			}
+			flag.CommandLine = flag.NewFlagSet("squirrel", flag.ContinueOnError)
+			flag.CommandLine.SetOutput(io.Discard)
+			err := ParseFlags(&Config{}, tt.command, tt.args, nil)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("错误为 %v，应包含 %q", err, tt.wantErr)
+			}
+		})
+	}
+}
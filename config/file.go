@@ -0,0 +1,209 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 从配置文件(YAML或JSON)加载参数，需在 flag.Parse 之后调用。
+// 键名与命令行参数同名（可用下划线代替连字符），命令行中显式指定的参数优先于文件中的值，
+// 可重复指定的参数（如 webhook-header）在文件中写成列表，整个值写成 ${NAME} 时读取该环境变量
+func LoadFile(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %v", err)
+	}
+
+	values := make(map[string]interface{})
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		err = json.Unmarshal(data, &values)
+	} else {
+		err = yaml.Unmarshal(data, &values)
+	}
+	if err != nil {
+		return fmt.Errorf("解析配置文件 %s 失败: %v", filename, err)
+	}
+
+	// 命令行中显式指定的参数
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	// 按键名顺序处理，保证出错时的提示稳定
+	for _, key := range sortedKeys(values) {
+		name := strings.ReplaceAll(key, "_", "-")
+		f := flag.Lookup(name)
+		if f == nil || name == "config" {
+			return fmt.Errorf("配置文件 %s: 未知的配置项 %q%s", filename, key, suggestFlag(name))
+		}
+		if explicit[name] {
+			continue
+		}
+		if err := setFlag(f, values[key]); err != nil {
+			return fmt.Errorf("配置文件 %s: 配置项 %q 的值无效: %v", filename, key, err)
+		}
+	}
+	return nil
+}
+
+// 将配置文件中的值写入参数，列表只允许用于可重复指定的参数
+func setFlag(f *flag.Flag, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		if _, ok := f.Value.(*StringList); !ok {
+			return fmt.Errorf("该参数不能指定多个值")
+		}
+		for _, item := range v {
+			if err := setFlag(f, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		return fmt.Errorf("不支持嵌套的配置")
+	case string:
+		if name, ok := envReference(v); ok {
+			env, set := os.LookupEnv(name)
+			if !set {
+				return fmt.Errorf("环境变量 %s 未设置", name)
+			}
+			return flag.Set(f.Name, env)
+		}
+	case float64:
+		// JSON中的数字统一解析为 float64，整数值按整数写入
+		if v == float64(int64(v)) {
+			return flag.Set(f.Name, fmt.Sprint(int64(v)))
+		}
+	}
+	// 通过 flag.Set 写入，使配置文件中的值和命令行参数一样视为显式指定（预设不会覆盖）
+	return flag.Set(f.Name, fmt.Sprint(value))
+}
+
+// 解析 ${NAME} 形式的环境变量引用，只有整个值都是引用时才生效，避免误解析正则表达式中的 $
+func envReference(value string) (string, bool) {
+	if !strings.HasPrefix(value, "${") || !strings.HasSuffix(value, "}") {
+		return "", false
+	}
+	name := value[2 : len(value)-1]
+	for i, c := range name {
+		if !(c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || i > 0 && c >= '0' && c <= '9') {
+			return "", false
+		}
+	}
+	return name, name != ""
+}
+
+// 写入配置文件时改为环境变量引用的敏感参数（密钥、带token的地址和请求头）
+var secretFlags = map[string]bool{
+	"dingtalk-secret": true,
+	"webhook":         true,
+	"webhook-header":  true,
+	"notify":          true,
+}
+
+// 配置文件中引用的环境变量
+type EnvReference struct {
+	Name string // 环境变量名
+	Flag string // 对应的参数名
+}
+
+// 将生效参数中与默认值不同的部分写入配置文件（.json 结尾时为JSON，否则为YAML），用 -config 读回后得到相同的配置。
+// 敏感参数写成 ${SQUIRREL_参数名} 形式的环境变量引用（可重复参数有多个值时追加序号），返回这些引用供调用方提示
+func WriteFile(filename string) ([]EnvReference, error) {
+	values := configValues(true)
+	var refs []EnvReference
+	for _, name := range sortedKeys(values) {
+		if !secretFlags[name] {
+			continue
+		}
+		envName := "SQUIRREL_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		if list, ok := values[name].([]string); ok && len(list) > 1 {
+			masked := make([]string, len(list))
+			for i := range list {
+				ref := EnvReference{Name: fmt.Sprintf("%s_%d", envName, i+1), Flag: name}
+				masked[i] = "${" + ref.Name + "}"
+				refs = append(refs, ref)
+			}
+			values[name] = masked
+			continue
+		}
+		if _, ok := values[name].([]string); ok {
+			values[name] = []string{"${" + envName + "}"}
+		} else {
+			values[name] = "${" + envName + "}"
+		}
+		refs = append(refs, EnvReference{Name: envName, Flag: name})
+	}
+
+	var data []byte
+	var err error
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		data, err = json.MarshalIndent(values, "", "  ")
+		data = append(data, '\n')
+	} else {
+		data, err = yaml.Marshal(values)
+		data = append([]byte("# 由 -write-config 生成，使用 -config "+filepath.Base(filename)+" 加载\n"), data...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return nil, fmt.Errorf("写入配置文件失败: %v", err)
+	}
+	return refs, nil
+}
+
+// 按名称排序的键
+func sortedKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// 查找与未知键名最接近的参数名，用于提示拼写错误
+func suggestFlag(name string) string {
+	best, bestDist := "", 3
+	flag.VisitAll(func(f *flag.Flag) {
+		if d := editDistance(name, f.Name); d < bestDist {
+			best, bestDist = f.Name, d
+		}
+	})
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf("，是否为 %q？", best)
+}
+
+// 计算两个字符串的编辑距离
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// 在新的参数集上注册全部参数并解析 args，返回解析得到的配置
+func parseArgs(t *testing.T, command string, args ...string) *Config {
+	t.Helper()
+	flag.CommandLine = flag.NewFlagSet("squirrel", flag.ContinueOnError)
+	flag.CommandLine.SetOutput(io.Discard)
+	cfg := &Config{}
+	if err := ParseFlags(cfg, command, args, nil); err != nil {
+		t.Fatalf("解析参数 %q 失败: %v", args, err)
+	}
+	return cfg
+}
+
+// 写入测试用的配置文件
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+// 命令行参数优先于配置文件，配置文件优先于默认值；YAML和JSON配置文件的结果相同
+func TestLoadFilePrecedence(t *testing.T) {
+	t.Setenv("SQUIRREL_TEST_TOKEN", "Bearer abc")
+	files := map[string]string{
+		"squirrel.yaml": "timeout: 30\nconcurrency: 50\nfollow: true\ninterval: 2h\n" +
+			"webhook_header:\n  - \"X-A: 1\"\n  - ${SQUIRREL_TEST_TOKEN}\n",
+		"squirrel.json": `{"timeout": 30, "concurrency": 50, "follow": true, "interval": "2h",` +
+			` "webhook-header": ["X-A: 1", "${SQUIRREL_TEST_TOKEN}"]}`,
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			filename := writeConfig(t, name, content)
+			cfg := parseArgs(t, CommandScan, "-timeout", "5", "-config", filename, "example.com")
+			if err := LoadFile(filename); err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Timeout != 5 {
+				t.Errorf("-timeout 为 %d，命令行中的 5 应优先", cfg.Timeout)
+			}
+			if cfg.Concurrency != 50 || !cfg.FollowRedirects || cfg.Interval.Hours() != 2 {
+				t.Errorf("配置文件中的值未生效: concurrency=%d follow=%v interval=%s", cfg.Concurrency, cfg.FollowRedirects, cfg.Interval)
+			}
+			if want := []string{"X-A: 1", "Bearer abc"}; !slices.Equal(cfg.WebhookHeaders, want) {
+				t.Errorf("-webhook-header 为 %q，应为 %q", cfg.WebhookHeaders, want)
+			}
+			if cfg.MaxRedirects != 10 || cfg.ScreenshotDir != "screenshots" {
+				t.Errorf("未指定的参数应为默认值: max-redirects=%d screenshot-dir=%s", cfg.MaxRedirects, cfg.ScreenshotDir)
+			}
+			if cfg.Input != "example.com" {
+				t.Errorf("目标参数为 %q", cfg.Input)
+			}
+		})
+	}
+}
+
+// 配置文件中的错误：未知的键（提示相近的参数名）、不能重复的参数写成列表、未设置的环境变量
+func TestLoadFileErrors(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"timout: 5\n", `未知的配置项 "timout"，是否为 "timeout"？`},
+		{"timeout: [1, 2]\n", `配置项 "timeout" 的值无效: 该参数不能指定多个值`},
+		{"webhook: ${SQUIRREL_TEST_UNSET}\n", `配置项 "webhook" 的值无效: 环境变量 SQUIRREL_TEST_UNSET 未设置`},
+		{"timeout: abc\n", `配置项 "timeout" 的值无效`},
+	}
+	for _, tt := range tests {
+		filename := writeConfig(t, "squirrel.yaml", tt.content)
+		parseArgs(t, CommandScan)
+		err := LoadFile(filename)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q 的错误为 %v，应包含 %q", tt.content, err, tt.want)
+		}
+	}
+}
+
+// -write-config 写出的配置用 -config 读回后得到相同的配置，敏感参数写成环境变量引用
+func TestWriteFileRoundTrip(t *testing.T) {
+	for _, name := range []string{"squirrel.yaml", "squirrel.json"} {
+		t.Run(name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), name)
+			want := parseArgs(t, CommandScan, "-preset", "thorough", "-timeout", "7", "-interval", "90m",
+				"-sample", "0.25", "-seed", "9", "-exclude", "*.cdn.example.com", "-exclude", "10.0.0.0/8",
+				"-webhook", "https://hooks.example.com/t/secret", "-webhook-header", "X-A: 1", "-webhook-header", "X-B: 2",
+				"-html", "report.html", "-write-config", filename)
+			if _, err := ApplyPreset(want, want.Preset); err != nil {
+				t.Fatal(err)
+			}
+			refs, err := WriteFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantRefs := []EnvReference{
+				{Name: "SQUIRREL_WEBHOOK", Flag: "webhook"},
+				{Name: "SQUIRREL_WEBHOOK_HEADER_1", Flag: "webhook-header"},
+				{Name: "SQUIRREL_WEBHOOK_HEADER_2", Flag: "webhook-header"},
+			}
+			if !slices.Equal(refs, wantRefs) {
+				t.Errorf("环境变量引用为 %+v，应为 %+v", refs, wantRefs)
+			}
+			data, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(data), "secret") || strings.Contains(string(data), "X-A") {
+				t.Errorf("配置文件中包含敏感参数的值:\n%s", data)
+			}
+
+			t.Setenv("SQUIRREL_WEBHOOK", want.Webhook)
+			t.Setenv("SQUIRREL_WEBHOOK_HEADER_1", "X-A: 1")
+			t.Setenv("SQUIRREL_WEBHOOK_HEADER_2", "X-B: 2")
+			got := parseArgs(t, CommandScan, "-config", filename)
+			if err := LoadFile(filename); err != nil {
+				t.Fatal(err)
+			}
+			want.Preset, want.WriteConfig, got.ConfigFile = "", "", ""
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("读回的配置为\n%+v\n应为\n%+v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 单条逐目标参数覆盖，键名与命令行参数相同，未设置的项沿用全局参数
+type Override struct {
+	Timeout        *int     `yaml:"timeout"`
+	HostHeader     *string  `yaml:"host-header"`
+	Follow         *bool    `yaml:"follow"`
+	Extract        *bool    `yaml:"extract"`
+	SkipScreenshot bool     `yaml:"skip-screenshot"`
+	Cookie         string   `yaml:"cookie"`
+	Headers        []string `yaml:"header"` // 附加的请求头，格式 "Name: value"
+}
+
+type overrideRule struct {
+	pattern  string
+	override Override
+}
+
+// -overrides 加载的逐目标参数覆盖规则，加载后只读，可在多个goroutine中使用。
+// 精确规则用map查找，通配符规则在加载时按具体程度排好序，匹配时不需要再编译或排序。
+// 一个目标只应用最具体的一条规则：带端口的精确规则 > 主机名的精确规则 > 非通配字符更多的通配符规则
+type Overrides struct {
+	exact    map[string]overrideRule
+	patterns []overrideRule
+}
+
+// 从YAML（或JSON）文件加载覆盖规则，键为主机名（可带端口）或通配符（如 *.internal.example.com）
+func LoadOverrides(filename string) (*Overrides, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("读取覆盖规则文件失败: %v", err)
+	}
+	rules := make(map[string]Override)
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&rules); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("解析覆盖规则文件 %s 失败: %v", filename, err)
+	}
+
+	o := &Overrides{exact: make(map[string]overrideRule)}
+	for pattern, override := range rules {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if err := override.check(); err != nil {
+			return nil, fmt.Errorf("覆盖规则 %s: %v", pattern, err)
+		}
+		rule := overrideRule{pattern: pattern, override: override}
+		if !strings.ContainsAny(pattern, "*?[") {
+			o.exact[pattern] = rule
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("无效的覆盖规则: %s", pattern)
+		}
+		o.patterns = append(o.patterns, rule)
+	}
+	sort.Slice(o.patterns, func(i, j int) bool {
+		a, b := literalLen(o.patterns[i].pattern), literalLen(o.patterns[j].pattern)
+		if a != b {
+			return a > b
+		}
+		return o.patterns[i].pattern < o.patterns[j].pattern
+	})
+	return o, nil
+}
+
+// 检查覆盖项的取值
+func (ov Override) check() error {
+	if ov.Timeout != nil && *ov.Timeout <= 0 {
+		return fmt.Errorf("timeout 必须大于0，当前为 %d", *ov.Timeout)
+	}
+	for _, header := range ov.Headers {
+		if name, _, ok := strings.Cut(header, ":"); !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("请求头格式应为 \"Name: value\"，当前为 %q", header)
+		}
+	}
+	return nil
+}
+
+// 通配符规则中非通配字符的数量，越多越具体
+func literalLen(pattern string) int {
+	return len(pattern) - strings.Count(pattern, "*") - strings.Count(pattern, "?")
+}
+
+// 规则数量
+func (o *Overrides) Len() int {
+	return len(o.exact) + len(o.patterns)
+}
+
+// 查找适用于目标（host 或 host:port）的最具体的规则，返回规则的模式，没有匹配时返回空字符串
+func (o *Overrides) Match(target string) (string, *Override) {
+	target = strings.ToLower(target)
+	host := target
+	if h, _, err := net.SplitHostPort(target); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+
+	if rule, ok := o.exact[target]; ok {
+		return rule.pattern, &rule.override
+	}
+	if rule, ok := o.exact[host]; ok {
+		return rule.pattern, &rule.override
+	}
+	for i := range o.patterns {
+		rule := &o.patterns[i]
+		// 带端口的通配符规则匹配 host:port，其余只匹配主机名
+		name := host
+		if strings.Contains(rule.pattern, ":") {
+			name = target
+		}
+		if ok, _ := path.Match(rule.pattern, name); ok {
+			return rule.pattern, &rule.override
+		}
+	}
+	return "", nil
+}
+
+// 返回应用了目标匹配规则的配置和规则的模式，没有匹配时原样返回配置和空字符串。
+// 返回的配置不再带有覆盖规则，避免重复应用
+func (o *Overrides) Apply(cfg Config, target string) (Config, string) {
+	pattern, ov := o.Match(target)
+	if ov == nil {
+		return cfg, ""
+	}
+	cfg.Overrides = nil
+	if ov.Timeout != nil {
+		cfg.Timeout = *ov.Timeout
+	}
+	if ov.HostHeader != nil {
+		cfg.HostHeader = *ov.HostHeader
+	}
+	if ov.Follow != nil {
+		cfg.FollowRedirects = *ov.Follow
+	}
+	if ov.Extract != nil {
+		cfg.ExtractInfo = *ov.Extract
+	}
+	if ov.SkipScreenshot {
+		cfg.Screenshot, cfg.ScreenshotAlive, cfg.ScreenshotProtect = false, false, false
+	}
+	headers := append([]string(nil), cfg.RequestHeaders...)
+	headers = append(headers, ov.Headers...)
+	if ov.Cookie != "" {
+		headers = append(headers, "Cookie: "+ov.Cookie)
+	}
+	cfg.RequestHeaders = headers
+	return cfg, pattern
+}
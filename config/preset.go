@@ -0,0 +1,169 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 扫描预设：一组协调好的参数，作为对 Config 的具名修改
+type Preset struct {
+	Name        string
+	Description string
+	Apply       func(c *Config)
+}
+
+// 内置预设
+var Presets = []Preset{
+	{
+		Name:        "fast",
+		Description: "高并发、短超时、不截图不提取，适合快速筛选大量目标",
+		Apply: func(c *Config) {
+			c.Concurrency = 100
+			c.Timeout = 3
+			c.Screenshot = false
+			c.ScreenshotAlive = false
+			c.ExtractInfo = false
+			c.RecheckDead = false
+		},
+	},
+	{
+		Name:        "thorough",
+		Description: "提取页面信息、截图存活网页、跟随重定向、复查无法访问的目标并使用较长超时",
+		Apply: func(c *Config) {
+			c.Timeout = 20
+			c.ExtractInfo = true
+			c.ScreenshotAlive = true
+			c.FollowRedirects = true
+			c.RecheckDead = true
+		},
+	},
+	{
+		Name:        "stealth",
+		Description: "低并发、较长超时，降低对目标的请求压力",
+		Apply: func(c *Config) {
+			c.Concurrency = 2
+			c.ScreenshotWorkers = 2
+			c.Timeout = 15
+		},
+	},
+}
+
+// 预设名称列表
+func PresetNames() []string {
+	names := make([]string, len(Presets))
+	for i, p := range Presets {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// 按名称查找预设
+func FindPreset(name string) *Preset {
+	for i := range Presets {
+		if Presets[i].Name == name {
+			return &Presets[i]
+		}
+	}
+	return nil
+}
+
+// 应用预设，需在 flag.Parse 和 LoadFile 之后调用。
+// 命令行和配置文件中显式指定的参数保持不变，返回预设实际修改的参数及其新值
+func ApplyPreset(c *Config, name string) (map[string]string, error) {
+	preset := FindPreset(name)
+	if preset == nil {
+		return nil, fmt.Errorf("未知的预设 %q（可选 %s）", name, strings.Join(PresetNames(), "|"))
+	}
+
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	before := make(map[string]string)
+	flag.VisitAll(func(f *flag.Flag) {
+		before[f.Name] = f.Value.String()
+	})
+
+	preset.Apply(c)
+
+	changed := make(map[string]string)
+	var err error
+	flag.VisitAll(func(f *flag.Flag) {
+		value := f.Value.String()
+		if value == before[f.Name] {
+			return
+		}
+		if explicit[f.Name] {
+			// 显式指定的参数优先，恢复原值
+			if setErr := f.Value.Set(before[f.Name]); setErr != nil && err == nil {
+				err = setErr
+			}
+			return
+		}
+		changed[f.Name] = value
+	})
+	return changed, err
+}
+
+// 打印 -print-config 和 -write-config 时跳过的参数：本身不是扫描配置，或是其他参数的别名
+var printConfigSkip = map[string]bool{
+	"config":       true,
+	"preset":       true,
+	"print-config": true,
+	"write-config": true,
+	"version":      true,
+	"output-all":   true,
+	"plain":        true,
+}
+
+// 以YAML格式输出生效的全部参数，输出可直接作为 -config 的配置文件
+func PrintConfig() (string, error) {
+	data, err := yaml.Marshal(configValues(false))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// 生效的参数值，键为参数名；onlyChanged 为 true 时只包含与默认值不同的参数
+func configValues(onlyChanged bool) map[string]interface{} {
+	values := make(map[string]interface{})
+	flag.VisitAll(func(f *flag.Flag) {
+		if printConfigSkip[f.Name] || (onlyChanged && f.Value.String() == f.DefValue) {
+			return
+		}
+		switch v := f.Value.(type) {
+		case *StringList:
+			values[f.Name] = []string(*v)
+		case flag.Getter:
+			// 时长以 2s 这样的形式输出，保证可以重新读入
+			if d, ok := v.Get().(time.Duration); ok {
+				values[f.Name] = d.String()
+			} else {
+				values[f.Name] = v.Get()
+			}
+		default:
+			values[f.Name] = f.Value.String()
+		}
+	})
+	return values
+}
+
+// 预设修改的参数，按名称排序后以 名称=值 列出
+func FormatPresetChanges(changed map[string]string) string {
+	names := make([]string, 0, len(changed))
+	for name := range changed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + "=" + changed[name]
+	}
+	return strings.Join(parts, " ")
+}
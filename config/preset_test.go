@@ -0,0 +1,70 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// 预设只修改未显式指定的参数：命令行和配置文件中的值优先，返回预设实际修改的参数
+func TestApplyPresetPrecedence(t *testing.T) {
+	filename := writeConfig(t, "squirrel.yaml", "concurrency: 40\n")
+	tests := []struct {
+		name        string
+		preset      string
+		args        []string
+		file        bool
+		timeout     int
+		concurrency int
+		changed     string
+	}{
+		{"只有预设", "fast", nil, false, 3, 100, "concurrency=100 timeout=3"},
+		{"命令行优先", "fast", []string{"-timeout", "8"}, false, 8, 100, "concurrency=100"},
+		{"配置文件优先", "fast", nil, true, 3, 40, "timeout=3"},
+		{"命令行和配置文件都优先", "stealth", []string{"-timeout", "8"}, true, 8, 40, "screenshot-concurrency=2"},
+		{"与默认值相同的显式参数", "fast", []string{"-concurrency", "10"}, false, 3, 10, "timeout=3"},
+		{"显式关闭预设打开的参数", "thorough", []string{"-follow=false", "-extract=false"}, false, 20, 10, "recheck-dead=true screenshot-alive=true timeout=20"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := parseArgs(t, CommandScan, tt.args...)
+			if tt.file {
+				if err := LoadFile(filename); err != nil {
+					t.Fatal(err)
+				}
+			}
+			changed, err := ApplyPreset(cfg, tt.preset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Timeout != tt.timeout || cfg.Concurrency != tt.concurrency {
+				t.Errorf("timeout=%d concurrency=%d，应为 %d 和 %d", cfg.Timeout, cfg.Concurrency, tt.timeout, tt.concurrency)
+			}
+			if got := FormatPresetChanges(changed); got != tt.changed {
+				t.Errorf("预设修改的参数为 %q，应为 %q", got, tt.changed)
+			}
+		})
+	}
+}
+
+// 显式关闭的开关不会被预设打开
+func TestApplyPresetKeepsExplicitFalse(t *testing.T) {
+	cfg := parseArgs(t, CommandScan, "-follow=false", "-extract=false")
+	if _, err := ApplyPreset(cfg, "thorough"); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.FollowRedirects || cfg.ExtractInfo {
+		t.Errorf("follow=%v extract=%v，显式关闭的参数应保持关闭", cfg.FollowRedirects, cfg.ExtractInfo)
+	}
+	if !cfg.ScreenshotAlive || !cfg.RecheckDead {
+		t.Error("未显式指定的参数应由预设打开")
+	}
+}
+
+// 未知的预设返回错误并列出可选的预设
+func TestApplyPresetUnknown(t *testing.T) {
+	cfg := parseArgs(t, CommandScan)
+	_, err := ApplyPreset(cfg, "turbo")
+	if err == nil || !strings.Contains(err.Error(), "fast|thorough|stealth") {
+		t.Errorf("错误为 %v", err)
+	}
+}
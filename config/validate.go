@@ -0,0 +1,233 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/http/httpguts"
+
+	"subdomain-checker/utils"
+)
+
+// 检查参数取值，返回发现的全部问题（没有问题时返回nil），需在加载配置文件之后调用
+func (c *Config) Validate() []error {
+	var problems []error
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Errorf(format, args...))
+	}
+
+	// 数值范围
+	if c.Timeout <= 0 {
+		addf("-timeout 必须大于0，当前为 %d", c.Timeout)
+	}
+	if c.Concurrency <= 0 {
+		addf("-concurrency 必须大于0，当前为 %d", c.Concurrency)
+	}
+	if c.ScreenshotWorkers < 0 {
+		addf("-screenshot-concurrency 不能为负数，当前为 %d", c.ScreenshotWorkers)
+	}
+	if c.Top < 0 {
+		addf("-top 不能为负数，当前为 %d", c.Top)
+	}
+
+	if c.MaxHosts < 0 {
+		addf("-max-hosts 不能为负数，当前为 %d", c.MaxHosts)
+	}
+	if c.Sample < 0 || c.Sample > 1 {
+		addf("-sample 必须在 0 到 1 之间，当前为 %g", c.Sample)
+	}
+	if c.Shard != "" {
+		if _, _, err := ParseShard(c.Shard); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	// 互斥和依赖关系
+	if c.ChunkSize < 0 {
+		addf("-chunk-size 不能为负数")
+	}
+	if c.ChunkPause < 0 {
+		addf("-chunk-pause 不能为负数")
+	}
+	if c.MaxMemory != "" {
+		if _, err := utils.ParseSize(c.MaxMemory); err != nil {
+			addf("-max-memory: %v", err)
+		}
+	}
+	for _, name := range strings.Split(c.HeadersCapture, ",") {
+		if name = strings.TrimSpace(name); name != "" && !httpguts.ValidHeaderFieldName(name) {
+			addf("-headers-capture: 无效的响应头名称 %q", name)
+		}
+	}
+	if c.RatePerHost < 0 {
+		addf("-rate-per-host 不能为负数")
+	}
+	if c.MaxRedirects <= 0 {
+		addf("-max-redirects 必须大于0，当前为 %d", c.MaxRedirects)
+	}
+	if c.DebugStats < 0 {
+		addf("-debug-stats 不能为负数")
+	}
+	if c.Exec != "" {
+		if c.ExecConcurrency <= 0 {
+			addf("-exec-concurrency 必须大于0，当前为 %d", c.ExecConcurrency)
+		}
+		if c.ExecTimeout <= 0 {
+			addf("-exec-timeout 必须大于0")
+		}
+	}
+	if c.Monitor {
+		if c.Interval <= 0 {
+			addf("-interval 必须大于0")
+		}
+		if c.Checkpoint != "" || c.Resume != "" {
+			addf("-monitor 不能与 -checkpoint、-resume 或 resume 子命令一起使用")
+		}
+		if c.DiffBaseline != "" {
+			addf("-monitor 会自动与上一轮的结果对比，不能再指定 -diff")
+		}
+		if c.Web != "" {
+			addf("-web 不能与 -monitor 一起使用")
+		}
+		if c.TUI {
+			addf("-tui 不能与 -monitor 一起使用")
+		}
+	}
+	screenshots := c.Screenshot || c.ScreenshotAlive
+	if c.Screenshot && c.ScreenshotAlive {
+		addf("-screenshot 和 -screenshot-alive 不能同时使用")
+	}
+	if c.ScreenshotProtect && !c.ScreenshotAlive {
+		addf("-screenshot-protected 需要与 -screenshot-alive 一起使用")
+	}
+	if screenshots && c.ExcelFile == "" && c.HTMLFile == "" && c.SimpleHTMLFile == "" && c.OutputAll == "" {
+		addf("启用截图功能时必须指定 -excel、-html、-simple-html 或 -o 选项")
+	}
+	if c.HTTPSOnly && c.HTTPOnly {
+		addf("-https-only 和 -http-only 不能同时使用")
+	}
+	if c.Proxy != "" {
+		if _, err := ParseProxy(c.Proxy); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	if c.ProxyFile != "" {
+		if c.Proxy != "" {
+			addf("-proxy 和 -proxy-file 不能同时使用")
+		}
+		switch c.ProxyRotation {
+		case "round-robin", "random":
+		default:
+			addf("-proxy-rotation: 不支持的轮换方式 %q（可选 round-robin|random）", c.ProxyRotation)
+		}
+		if c.ProxyMaxFails <= 0 {
+			addf("-proxy-max-fails 必须大于0，当前为 %d", c.ProxyMaxFails)
+		}
+	}
+	if c.UserAgent != "" && c.RandomUA {
+		addf("-user-agent 和 -random-ua 不能同时使用")
+	}
+	if c.StoreResponseAlive && c.StoreResponse == "" {
+		addf("-store-response-alive 需要与 -store-response 一起使用")
+	}
+	if c.IncludeProtected && !c.OnlyAlive {
+		addf("-include-protected 需要与 -only-alive 一起使用")
+	}
+	if c.Adaptive && c.AutoConcurrency {
+		addf("-adaptive 和 -auto-concurrency 不能同时使用（-auto-concurrency 已包含出错时降低并发）")
+	}
+	if c.NoAutoTune && c.ScreenshotWorkers == 0 {
+		addf("-no-auto-tune 需要与 -screenshot-concurrency 一起使用")
+	}
+	if c.Seed != 0 && c.Sample == 0 {
+		addf("-seed 需要与 -sample 一起使用")
+	}
+	if c.Reverse && c.Sort == "" {
+		addf("-reverse 需要与 -sort 一起使用")
+	}
+	if c.HeadTitle && !c.Head {
+		addf("-head-title 需要与 -head 一起使用")
+	}
+	if c.Checkpoint != "" && c.Resume != "" && c.Checkpoint != c.Resume {
+		addf("-checkpoint 和 -resume 不能指定不同的文件（-resume 会继续写入原检查点）")
+	}
+	if _, err := ParsePorts(c.AppendPorts); err != nil {
+		addf("-append-ports: %v", err)
+	}
+	if _, err := ParsePorts(c.Ports); err != nil {
+		addf("-ports: %v", err)
+	}
+	if c.Ports != "" && c.AppendPorts != "" {
+		addf("-ports 和 -append-ports 不能同时使用")
+	}
+	if _, err := ParsePaths(c.Paths); err != nil {
+		addf("-paths: %v", err)
+	}
+	switch c.InputFormat {
+	case "auto", "txt", "csv", "json", "xlsx":
+	default:
+		addf("-input-format: 不支持的格式 %q（可选 auto|txt|csv|json|xlsx）", c.InputFormat)
+	}
+	if c.LogLevel != "" {
+		if _, err := utils.ParseLevel(c.LogLevel); err != nil {
+			addf("-log-level: %v", err)
+		}
+	}
+
+	// 输出目录必须可以创建并写入
+	outputs := []struct{ flag, path string }{
+		{"-output", c.OutputFile},
+		{"-output-failed", c.OutputFailed},
+		{"-excel", c.ExcelFile},
+		{"-json", c.JSONFile},
+		{"-jsonl", c.JSONLFile},
+		{"-sqlite", c.SQLiteFile},
+		{"-html", c.HTMLFile},
+		{"-simple-html", c.SimpleHTMLFile},
+		{"-o", c.OutputAll},
+		{"-stats-file", c.StatsFile},
+		{"-log-file", c.LogFile},
+		{"-checkpoint", c.Checkpoint},
+		{"-excluded-output", c.ExcludedOutput},
+	}
+	checked := make(map[string]error)
+	for _, output := range outputs {
+		if output.path == "" {
+			continue
+		}
+		dir := filepath.Dir(output.path)
+		if _, ok := checked[dir]; !ok {
+			checked[dir] = checkWritableDir(dir)
+		}
+		if err := checked[dir]; err != nil {
+			addf("%s %s: 输出目录不可写: %v", output.flag, output.path, err)
+		}
+	}
+	if screenshots {
+		if err := checkWritableDir(c.ScreenshotDir); err != nil {
+			addf("-screenshot-dir %s: 截图目录不可写: %v", c.ScreenshotDir, err)
+		}
+	}
+	if c.StoreResponse != "" {
+		if err := checkWritableDir(c.StoreResponse); err != nil {
+			addf("-store-response %s: 响应保存目录不可写: %v", c.StoreResponse, err)
+		}
+	}
+
+	return problems
+}
+
+// 确认目录存在（不存在时创建）且可以写入文件
+func checkWritableDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	file, err := os.CreateTemp(dir, ".squirrel-check-*")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 每条校验规则一个无效的取值，只报告该问题，错误信息指明参数和原因
+func TestValidate(t *testing.T) {
+	// 普通文件下的路径无法创建目录
+	blocker := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	unwritable := filepath.Join(blocker, "out", "results.csv")
+
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   string
+	}{
+		{"timeout", func(c *Config) { c.Timeout = 0 }, "-timeout 必须大于0，当前为 0"},
+		{"concurrency", func(c *Config) { c.Concurrency = -1 }, "-concurrency 必须大于0，当前为 -1"},
+		{"screenshot-concurrency", func(c *Config) { c.ScreenshotWorkers = -2 }, "-screenshot-concurrency 不能为负数，当前为 -2"},
+		{"top", func(c *Config) { c.Top = -1 }, "-top 不能为负数，当前为 -1"},
+		{"max-hosts", func(c *Config) { c.MaxHosts = -1 }, "-max-hosts 不能为负数，当前为 -1"},
+		{"sample", func(c *Config) { c.Sample = 1.5 }, "-sample 必须在 0 到 1 之间，当前为 1.5"},
+		{"shard", func(c *Config) { c.Shard = "4/4" }, "-shard 的分片序号 i 必须在 0 到 3 之间，当前为 4"},
+		{"chunk-size", func(c *Config) { c.ChunkSize = -1 }, "-chunk-size 不能为负数"},
+		{"chunk-pause", func(c *Config) { c.ChunkPause = -time.Second }, "-chunk-pause 不能为负数"},
+		{"max-memory", func(c *Config) { c.MaxMemory = "lots" }, "-max-memory: "},
+		{"headers-capture", func(c *Config) { c.HeadersCapture = "Server,Bad Header" }, `-headers-capture: 无效的响应头名称 "Bad Header"`},
+		{"rate-per-host", func(c *Config) { c.RatePerHost = -time.Second }, "-rate-per-host 不能为负数"},
+		{"max-redirects", func(c *Config) { c.MaxRedirects = 0 }, "-max-redirects 必须大于0，当前为 0"},
+		{"debug-stats", func(c *Config) { c.DebugStats = -time.Second }, "-debug-stats 不能为负数"},
+		{"exec-concurrency", func(c *Config) { c.Exec, c.ExecConcurrency = "echo {domain}", 0 }, "-exec-concurrency 必须大于0，当前为 0"},
+		{"exec-timeout", func(c *Config) { c.Exec, c.ExecTimeout = "echo {domain}", 0 }, "-exec-timeout 必须大于0"},
+		{"interval", func(c *Config) { c.Monitor, c.Interval = true, 0 }, "-interval 必须大于0"},
+		{"monitor checkpoint", func(c *Config) { c.Monitor, c.Resume = true, "x.ckpt" }, "-monitor 不能与 -checkpoint、-resume 或 resume 子命令一起使用"},
+		{"monitor diff", func(c *Config) { c.Monitor, c.DiffBaseline = true, "old.json" }, "-monitor 会自动与上一轮的结果对比，不能再指定 -diff"},
+		{"monitor web", func(c *Config) { c.Monitor, c.Web = true, ":8080" }, "-web 不能与 -monitor 一起使用"},
+		{"monitor tui", func(c *Config) { c.Monitor, c.TUI = true, true }, "-tui 不能与 -monitor 一起使用"},
+		{"screenshot modes", func(c *Config) { c.Screenshot, c.ScreenshotAlive = true, true }, "-screenshot 和 -screenshot-alive 不能同时使用"},
+		{"screenshot-protected", func(c *Config) { c.ScreenshotProtect = true }, "-screenshot-protected 需要与 -screenshot-alive 一起使用"},
+		{"screenshot output", func(c *Config) { c.ScreenshotAlive, c.HTMLFile = true, "" }, "启用截图功能时必须指定 -excel、-html、-simple-html 或 -o 选项"},
+		{"scheme", func(c *Config) { c.HTTPSOnly, c.HTTPOnly = true, true }, "-https-only 和 -http-only 不能同时使用"},
+		{"proxy", func(c *Config) { c.Proxy = "ftp://127.0.0.1:21" }, `-proxy 不支持的协议 "ftp"`},
+		{"proxy and proxy-file", func(c *Config) { c.Proxy, c.ProxyFile = "http://127.0.0.1:8080", "proxies.txt" }, "-proxy 和 -proxy-file 不能同时使用"},
+		{"proxy-rotation", func(c *Config) { c.ProxyFile, c.ProxyRotation = "proxies.txt", "sticky" }, `-proxy-rotation: 不支持的轮换方式 "sticky"`},
+		{"proxy-max-fails", func(c *Config) { c.ProxyFile, c.ProxyMaxFails = "proxies.txt", 0 }, "-proxy-max-fails 必须大于0，当前为 0"},
+		{"user-agent", func(c *Config) { c.UserAgent, c.RandomUA = "curl/8", true }, "-user-agent 和 -random-ua 不能同时使用"},
+		{"store-response-alive", func(c *Config) { c.StoreResponseAlive = true }, "-store-response-alive 需要与 -store-response 一起使用"},
+		{"include-protected", func(c *Config) { c.IncludeProtected = true }, "-include-protected 需要与 -only-alive 一起使用"},
+		{"adaptive", func(c *Config) { c.Adaptive, c.AutoConcurrency = true, true }, "-adaptive 和 -auto-concurrency 不能同时使用"},
+		{"no-auto-tune", func(c *Config) { c.NoAutoTune = true }, "-no-auto-tune 需要与 -screenshot-concurrency 一起使用"},
+		{"seed", func(c *Config) { c.Seed = 42 }, "-seed 需要与 -sample 一起使用"},
+		{"reverse", func(c *Config) { c.Reverse = true }, "-reverse 需要与 -sort 一起使用"},
+		{"head-title", func(c *Config) { c.HeadTitle = true }, "-head-title 需要与 -head 一起使用"},
+		{"checkpoint and resume", func(c *Config) { c.Checkpoint, c.Resume = "a.ckpt", "b.ckpt" }, "-checkpoint 和 -resume 不能指定不同的文件"},
+		{"append-ports", func(c *Config) { c.AppendPorts = "80,http" }, "-append-ports: "},
+		{"ports", func(c *Config) { c.Ports = "70000" }, "-ports: "},
+		{"ports and append-ports", func(c *Config) { c.Ports, c.AppendPorts = "80", "8080" }, "-ports 和 -append-ports 不能同时使用"},
+		{"input-format", func(c *Config) { c.InputFormat = "xml" }, `-input-format: 不支持的格式 "xml"`},
+		{"log-level", func(c *Config) { c.LogLevel = "loud" }, "-log-level: 无效的日志级别: loud"},
+		{"output dir", func(c *Config) { c.OutputFile = unwritable }, "-output " + unwritable + ": 输出目录不可写"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := parseArgs(t, CommandScan)
+			cfg.HTMLFile = filepath.Join(t.TempDir(), "report.html")
+			cfg.ScreenshotDir = filepath.Join(t.TempDir(), "screenshots")
+			if problems := cfg.Validate(); len(problems) != 0 {
+				t.Fatalf("默认配置有问题: %v", problems)
+			}
+			tt.modify(cfg)
+			problems := cfg.Validate()
+			if len(problems) != 1 || !strings.Contains(problems[0].Error(), tt.want) {
+				t.Errorf("问题为 %v，应只有包含 %q 的一项", problems, tt.want)
+			}
+		})
+	}
+}
+
+// 多个问题一次全部报告
+func TestValidateReportsAll(t *testing.T) {
+	cfg := parseArgs(t, CommandScan)
+	cfg.Timeout, cfg.Concurrency, cfg.Reverse = 0, 0, true
+	if problems := cfg.Validate(); len(problems) != 3 {
+		t.Errorf("问题为 %v，应为3项", problems)
+	}
+}
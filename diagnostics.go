@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	_ "net/http/pprof" // 注册到 http.DefaultServeMux，只在 -debug-pprof 时对外提供
+	"os"
+	"runtime"
+	"runtime/trace"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"subdomain-checker/config"
+	"subdomain-checker/screenshot"
+	"subdomain-checker/squirrel"
+	"subdomain-checker/utils"
+)
+
+// 诊断功能（-debug-pprof、-trace-file、-debug-stats），只用于排查性能问题，默认全部关闭
+type diagnostics struct {
+	mu        sync.Mutex
+	server    *http.Server
+	traceFile *os.File
+	done      chan struct{} // 关闭时停止定期自检报告
+	stopped   bool
+}
+
+// 按配置启动 pprof 接口和运行时跟踪，地址被占用或跟踪文件无法创建时返回错误
+func startDiagnostics(cfg *config.Config) (*diagnostics, error) {
+	d := &diagnostics{done: make(chan struct{})}
+	if cfg.DebugPprof != "" {
+		listener, err := net.Listen("tcp", cfg.DebugPprof)
+		if err != nil {
+			return nil, fmt.Errorf("启动pprof诊断接口失败: %v", err)
+		}
+		d.server = &http.Server{Handler: http.DefaultServeMux, ReadHeaderTimeout: 10 * time.Second}
+		go d.server.Serve(listener)
+		utils.Log().Infof("🩺 pprof诊断接口: http://%s/debug/pprof/\n", listener.Addr())
+	}
+	if cfg.TraceFile != "" {
+		f, err := os.Create(cfg.TraceFile)
+		if err != nil {
+			d.Stop()
+			return nil, fmt.Errorf("创建跟踪文件失败: %v", err)
+		}
+		if err := trace.Start(f); err != nil {
+			f.Close()
+			d.Stop()
+			return nil, fmt.Errorf("启动运行时跟踪失败: %v", err)
+		}
+		d.traceFile = f
+		utils.Log().Infof("🩺 运行时跟踪写入 %s，结束后用 go tool trace 查看\n", cfg.TraceFile)
+	}
+	return d, nil
+}
+
+// 每隔 interval 调用一次 report，interval 为0时不启动
+func (d *diagnostics) reportEvery(interval time.Duration, report func()) {
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				report()
+			case <-d.done:
+				return
+			}
+		}
+	}()
+}
+
+// 停止自检报告、关闭 pprof 接口并写完跟踪文件，可以多次调用
+func (d *diagnostics) Stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopped {
+		return
+	}
+	d.stopped = true
+	close(d.done)
+	if d.server != nil {
+		d.server.Close()
+	}
+	if d.traceFile != nil {
+		trace.Stop()
+		d.traceFile.Close()
+		utils.Log().Infof("🩺 运行时跟踪已写入 %s\n", d.traceFile.Name())
+	}
+}
+
+// 生成 -debug-stats 的自检报告：goroutine数、堆内存、打开的文件数、检测和截图队列及计数。
+// 通过日志记录器输出，和其他日志一样受 -silent 和 -log-file 控制
+func diagnosticsReport(processed, alive, dead *int32, total int, runner *squirrel.Runner, pool *screenshot.ScreenshotPool) func() {
+	return func() {
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+		done := int(atomic.LoadInt32(processed))
+		args := []interface{}{
+			"goroutines", runtime.NumGoroutine(),
+			"heap_mb", fmt.Sprintf("%.1f", float64(mem.HeapInuse)/(1<<20)),
+			"open_files", utils.OpenFileCount(),
+			"in_flight", runner.InFlight(),
+			"pending", total - done,
+			"processed", done,
+			"alive", atomic.LoadInt32(alive),
+			"dead", atomic.LoadInt32(dead),
+		}
+		if pool != nil {
+			stats := pool.Stats()
+			args = append(args, "screenshot_queue", pool.QueueLen(),
+				"screenshot_done", stats.Total, "screenshot_failed", stats.Failed)
+		}
+		utils.Log().Record(utils.LevelInfo, "🩺 诊断", args...)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"subdomain-checker/config"
+	"subdomain-checker/history"
+	"subdomain-checker/utils"
+)
+
+// 历史文件路径，-no-history 或无法确定默认位置时为空
+func historyPath(cfg *config.Config) string {
+	if cfg.NoHistory {
+		return ""
+	}
+	if cfg.HistoryFile != "" {
+		return cfg.HistoryFile
+	}
+	return history.DefaultPath()
+}
+
+// 把本次运行记入历史，并显示与同一目标范围上一次运行相比的变化。
+// 历史只是附加信息，写入失败时只记录警告
+func recordHistory(cfg *config.Config, run history.Run) {
+	path := historyPath(cfg)
+	if path == "" {
+		return
+	}
+	previous, err := history.Record(path, run)
+	if err != nil {
+		utils.Log().Warnf("⚠️  保存运行历史失败: %s\n", err)
+		return
+	}
+	if previous == nil || cfg.Silent {
+		return
+	}
+	when := previous.EndTime.Format("2006-01-02 15:04")
+	if changes := history.Changes(*previous, run); len(changes) > 0 {
+		fmt.Printf("与上次运行 (%s) 相比: %s\n", when, strings.Join(changes, "，"))
+	} else {
+		fmt.Printf("与上次运行 (%s) 相比没有变化\n", when)
+	}
+}
+
+// 显示同一目标范围最近几次运行的变化（history 子命令），未指定目标参数时使用最近一次运行的目标范围
+func runHistory(cfg *config.Config) int {
+	path := historyPath(cfg)
+	if path == "" {
+		fmt.Println("错误: 未启用运行历史（-no-history 或无法确定用户配置目录，可用 -history-file 指定）")
+		return exitUsage
+	}
+	file, err := history.Load(path)
+	if err != nil {
+		fmt.Printf("错误: %s（下次运行检测时会重新创建）\n", err)
+		return exitUsage
+	}
+	if len(file.Runs) == 0 {
+		fmt.Printf("%s 中还没有运行记录\n", path)
+		return exitOK
+	}
+
+	scope := file.Runs[len(file.Runs)-1].Scope
+	if cfg.Input != "" {
+		scope = ""
+		for _, run := range file.Runs {
+			if run.Input == cfg.Input || run.Scope == cfg.Input {
+				scope = run.Scope
+			}
+		}
+		if scope == "" {
+			fmt.Printf("没有目标参数或目标范围为 %s 的运行记录\n", cfg.Input)
+			return exitUsage
+		}
+	}
+	runs := file.ForScope(scope)
+	if cfg.HistoryLast > 0 && len(runs) > cfg.HistoryLast {
+		runs = runs[len(runs)-cfg.HistoryLast:]
+	}
+	history.Print(runs)
+	return exitOK
+}
@@ -0,0 +1,214 @@
+package history
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"subdomain-checker/utils"
+	"subdomain-checker/view"
+)
+
+// 历史文件格式版本，格式不兼容时递增，旧版本的文件会被重新创建
+const Version = 1
+
+// 文件中最多保留的运行记录数，超过时删除最早的
+const maxRuns = 1000
+
+// 一次运行的摘要，由终端总结、统计文件和通知共用的 view.RunStats 生成
+type Run struct {
+	Scope         string         `json:"scope"` // 目标范围的哈希，相同的目标列表得到相同的值
+	Input         string         `json:"input"` // 命令行中的目标参数
+	StartTime     time.Time      `json:"start_time"`
+	EndTime       time.Time      `json:"end_time"`
+	Total         int            `json:"total"`
+	Checked       int            `json:"checked"`
+	Alive         int            `json:"alive"`
+	Protected     int            `json:"protected"`
+	Dead          int            `json:"dead"`
+	StatusCounts  map[string]int `json:"status_counts"`
+	PageTypes     map[string]int `json:"page_types"`
+	AvgResponseMs int64          `json:"avg_response_ms"` // 存活主机的平均响应时间
+}
+
+// 历史文件
+type File struct {
+	Version int   `json:"version"`
+	Runs    []Run `json:"runs"` // 按结束时间从早到晚
+}
+
+// 默认的历史文件：用户配置目录下的 squirrel/history.json，无法确定配置目录时为空
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "squirrel", "history.json")
+}
+
+// 目标范围的哈希：与目标的顺序和大小写无关
+func Scope(targets []string) string {
+	sorted := make([]string, len(targets))
+	for i, target := range targets {
+		sorted[i] = strings.ToLower(target)
+	}
+	sort.Strings(sorted)
+	sum := sha256.Sum256([]byte(strings.Join(sorted, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// 从运行元数据和汇总统计生成运行摘要
+func NewRun(scope, input string, meta *view.RunMeta, stats *view.RunStats) Run {
+	return Run{
+		Scope:         scope,
+		Input:         input,
+		StartTime:     meta.StartTime,
+		EndTime:       meta.EndTime,
+		Total:         stats.Total,
+		Checked:       stats.Checked,
+		Alive:         stats.Alive,
+		Protected:     stats.Protected,
+		Dead:          stats.Dead,
+		StatusCounts:  stats.StatusCounts,
+		PageTypes:     stats.PageTypes,
+		AvgResponseMs: stats.ResponseTimes.Avg.Milliseconds(),
+	}
+}
+
+// 读取历史文件，文件不存在时返回空的历史；内容损坏或版本不兼容时返回错误
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &File{Version: Version}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("历史文件 %s 已损坏: %v", path, err)
+	}
+	if file.Version != Version {
+		return nil, fmt.Errorf("历史文件 %s 的版本为 %d，当前版本为 %d", path, file.Version, Version)
+	}
+	return &file, nil
+}
+
+// 把本次运行追加到历史文件，返回同一目标范围的上一次运行（没有时为nil）。
+// 历史文件损坏或版本不兼容时备份为 .bad 后重新创建，不影响检测
+func Record(path string, run Run) (*Run, error) {
+	file, err := Load(path)
+	if err != nil {
+		utils.Log().Warnf("⚠️  %s，已备份为 %s.bad 并重新创建\n", err, path)
+		os.Rename(path, path+".bad")
+		file = &File{Version: Version}
+	}
+	var previous *Run
+	if runs := file.ForScope(run.Scope); len(runs) > 0 {
+		previous = &runs[len(runs)-1]
+	}
+	file.Runs = append(file.Runs, run)
+	if len(file.Runs) > maxRuns {
+		file.Runs = file.Runs[len(file.Runs)-maxRuns:]
+	}
+	return previous, file.save(path)
+}
+
+// 通过临时文件和重命名原子地写入，中途失败不会破坏原有的历史
+func (f *File) save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// 同一目标范围的运行，按时间从早到晚（返回的是副本）
+func (f *File) ForScope(scope string) []Run {
+	var runs []Run
+	for _, run := range f.Runs {
+		if run.Scope == scope {
+			runs = append(runs, run)
+		}
+	}
+	return runs
+}
+
+// 与上一次运行相比的变化，如 "存活 +12"、"登录页面 +3"、"平均响应时间 -40ms"，没有变化时为空
+func Changes(previous, current Run) []string {
+	var changes []string
+	add := func(label string, delta int64, unit string) {
+		if delta != 0 {
+			changes = append(changes, fmt.Sprintf("%s %+d%s", label, delta, unit))
+		}
+	}
+	add("目标", int64(current.Total-previous.Total), "")
+	add("存活", int64(current.Alive-previous.Alive), "")
+	add("受保护", int64(current.Protected-previous.Protected), "")
+	add("无法访问", int64(current.Dead-previous.Dead), "")
+	pageTypes := make([]string, 0, len(current.PageTypes))
+	for pageType := range current.PageTypes {
+		pageTypes = append(pageTypes, pageType)
+	}
+	for pageType := range previous.PageTypes {
+		if _, ok := current.PageTypes[pageType]; !ok {
+			pageTypes = append(pageTypes, pageType)
+		}
+	}
+	sort.Strings(pageTypes)
+	for _, pageType := range pageTypes {
+		add(pageType, int64(current.PageTypes[pageType]-previous.PageTypes[pageType]), "")
+	}
+	if previous.AvgResponseMs > 0 && current.AvgResponseMs > 0 {
+		add("平均响应时间", current.AvgResponseMs-previous.AvgResponseMs, "ms")
+	}
+	return changes
+}
+
+// 打印同一目标范围最近的运行，每次运行显示与前一次相比的变化
+func Print(runs []Run) {
+	if len(runs) == 0 {
+		return
+	}
+	last := runs[len(runs)-1]
+	fmt.Printf("目标范围 %s (%s) 最近 %d 次运行:\n", last.Scope, last.Input, len(runs))
+	for i, run := range runs {
+		fmt.Printf("  %s  目标 %d，存活 %d，受保护 %d，无法访问 %d，平均响应 %dms\n", run.EndTime.Format("2006-01-02 15:04:05"),
+			run.Total, run.Alive, run.Protected, run.Dead, run.AvgResponseMs)
+		if i == 0 {
+			continue
+		}
+		if changes := Changes(runs[i-1], run); len(changes) > 0 {
+			fmt.Printf("      变化: %s\n", strings.Join(changes, "，"))
+		} else {
+			fmt.Printf("      无变化\n")
+		}
+	}
+}
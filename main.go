@@ -1,201 +1,126 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"net"
 	"net/url"
 	"os"
 	"os/exec"
 	"os/signal"
+	"regexp"
 	"runtime"
-	"strconv"
+	"runtime/debug"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
-	"syscall"
 	"time"
 
 	"subdomain-checker/checker"
+	"subdomain-checker/checkpoint"
 	"subdomain-checker/config"
+	"subdomain-checker/history"
+	"subdomain-checker/notify"
 	"subdomain-checker/screenshot"
+	"subdomain-checker/squirrel"
 	"subdomain-checker/utils"
 	"subdomain-checker/view"
+	"subdomain-checker/web"
 )
 
-// 获取系统内存信息（GB）
-func getSystemMemoryGB() float64 {
-	if runtime.GOOS == "windows" {
-		// Windows系统：使用wmic命令获取真实的系统内存
-		cmd := exec.Command("wmic", "computersystem", "get", "TotalPhysicalMemory", "/value")
-		output, err := cmd.Output()
-		if err == nil {
-			outputStr := string(output)
-			// 解析输出，查找TotalPhysicalMemory=数值
-			lines := strings.Split(outputStr, "\n")
-			for _, line := range lines {
-				line = strings.TrimSpace(line)
-				if strings.HasPrefix(line, "TotalPhysicalMemory=") {
-					memoryStr := strings.TrimPrefix(line, "TotalPhysicalMemory=")
-					memoryStr = strings.TrimSpace(memoryStr)
-					if memoryBytes, err := strconv.ParseUint(memoryStr, 10, 64); err == nil {
-						return float64(memoryBytes) / (1024 * 1024 * 1024) // 转换为GB
-					}
-				}
-			}
-		}
-
-		// 如果wmic失败，尝试使用PowerShell
-		cmd = exec.Command("powershell", "-Command", "(Get-CimInstance Win32_PhysicalMemory | Measure-Object -Property capacity -Sum).sum")
-		output, err = cmd.Output()
-		if err == nil {
-			outputStr := strings.TrimSpace(string(output))
-			if memoryBytes, err := strconv.ParseUint(outputStr, 10, 64); err == nil {
-				return float64(memoryBytes) / (1024 * 1024 * 1024) // 转换为GB
-			}
-		}
-	} else {
-		// Linux/Mac系统：使用/proc/meminfo或其他方法
-		if data, err := os.ReadFile("/proc/meminfo"); err == nil {
-			lines := strings.Split(string(data), "\n")
-			for _, line := range lines {
-				if strings.HasPrefix(line, "MemTotal:") {
-					fields := strings.Fields(line)
-					if len(fields) >= 2 {
-						if memoryKB, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
-							return float64(memoryKB) / (1024 * 1024) // 转换为GB (KB -> GB)
-						}
-					}
-				}
-			}
-		}
+// 系统的总内存和可用内存（GB），无法读取时使用估计值并提示
+func systemMemoryGB() (total, available float64) {
+	mem := utils.SystemMemory()
+	if mem.Estimated {
+		utils.Log().Warnf("⚠️  无法读取系统内存，按估计值 %.0fGB 计算截图并发数，可以用 -screenshot-concurrency 直接指定\n", utils.BytesToGB(mem.Total))
 	}
-
-	// 如果所有方法都失败，使用runtime估算（但给出警告）
-	var memStats runtime.MemStats
-	runtime.ReadMemStats(&memStats)
-
-	// 使用更保守的估算，假设至少16GB内存（现代计算机的常见配置）
-	estimatedMemoryGB := 16.0
-	fmt.Printf("⚠️  无法准确检测系统内存，估算为%.1fGB\n", estimatedMemoryGB)
-
-	return estimatedMemoryGB
+	return utils.BytesToGB(mem.Total), utils.BytesToGB(mem.Available)
 }
 
-// 智能计算合理的截图并发数 - CPU+内存综合评估
-func calculateOptimalScreenshotConcurrency(requestedConcurrency int, totalDomains int) int {
-	// 获取系统资源信息
-	numCPU := runtime.NumCPU()
-	memoryGB := getSystemMemoryGB()
-
-	// 显示系统资源信息
-	fmt.Printf("💻 系统资源: CPU=%d核心, 内存=%.1fGB\n", numCPU, memoryGB)
-
-	// 基于CPU计算推荐并发数 - 更激进的策略，充分利用多核
-	var cpuBasedConcurrency int
-	if numCPU >= 16 {
-		cpuBasedConcurrency = numCPU * 2 // 高性能CPU：每核心2个Chrome实例
-	} else if numCPU >= 8 {
-		cpuBasedConcurrency = numCPU * 2 // 中等性能CPU：每核心2个
-	} else if numCPU >= 4 {
-		cpuBasedConcurrency = numCPU * 1 // 低性能CPU：每核心1个
-	} else {
-		cpuBasedConcurrency = numCPU // 极低性能CPU：总核心数
-	}
+// 显式指定 -screenshot-concurrency 时的上限（另受内存限制），-no-auto-tune 时不限制
+const maxScreenshotConcurrency = 100
 
-	// 基于内存计算推荐并发数（每个Chrome实例约需150MB，更精确的估算）
-	chromeMemoryPerInstance := 0.15            // 150MB per Chrome instance (优化后)
-	availableMemoryForChrome := memoryGB * 0.7 // 使用70%的内存给Chrome (更激进)
-	memoryBasedConcurrency := int(availableMemoryForChrome / chromeMemoryPerInstance)
+// 检测期间内存中最多保留的结果数，超过后转存到临时暂存文件，结束时再读回生成输出
+const spillThreshold = 10000
 
-	// 取CPU和内存限制的较小值
-	optimalConcurrency := cpuBasedConcurrency
-	limitingFactor := "CPU"
-	if memoryBasedConcurrency < cpuBasedConcurrency {
-		optimalConcurrency = memoryBasedConcurrency
-		limitingFactor = "内存"
-		fmt.Printf("🧠 内存成为限制因素: 内存支持最多%d个Chrome实例\n", memoryBasedConcurrency)
-	} else {
-		fmt.Printf("⚡ CPU成为限制因素: CPU支持最多%d个Chrome实例\n", cpuBasedConcurrency)
-	}
-
-	// 智能并发限制 - 基于系统稳定性和性能的动态调整
-	// 针对大量域名（4万+）的特殊优化
-	if totalDomains > 20000 {
-		// 超大规模域名处理，强制降低并发
-		if optimalConcurrency > 15 {
-			optimalConcurrency = 15
-			fmt.Printf("🔥 超大规模处理: 检测到%d个域名，限制为15个并发\n", totalDomains)
-			fmt.Printf("💡 提示: 大量域名处理需要保守的并发数以避免系统崩溃\n")
-		}
-	} else if totalDomains > 10000 {
-		// 大规模域名处理
-		if optimalConcurrency > 25 {
-			optimalConcurrency = 25
-			fmt.Printf("🚀 大规模处理: 检测到%d个域名，限制为25个并发\n", totalDomains)
-			fmt.Printf("💡 提示: 大量域名处理时，过高并发会导致网络错误增加\n")
-		}
-	} else if optimalConcurrency > 50 {
-		optimalConcurrency = 50
-		fmt.Printf("🚀 高并发限制: 限制为50个并发以避免网络拥塞\n")
-		fmt.Printf("💡 提示: 处理大量域名时，过高并发会导致网络错误增加\n")
-	}
-
-	if optimalConcurrency > 30 {
-		fmt.Printf("⚠️  中高并发模式: %d个并发，适合大量域名处理\n", optimalConcurrency)
-		fmt.Printf("💡 建议: 监控网络错误率，如果过高请降低并发数\n")
-	} else if optimalConcurrency > 20 {
-		fmt.Printf("⚖️  平衡模式: %d个并发 (限制因素: %s)\n", optimalConcurrency, limitingFactor)
-	} else {
-		fmt.Printf("✅ 推荐并发数: %d个 (限制因素: %s)\n", optimalConcurrency, limitingFactor)
+// 开始检测前检查代理：-proxy 连接不上时返回错误；-proxy-file 中连接不上的代理被跳过（从 cfg.Proxies 中去掉），
+// 全部连接不上时返回错误。截图不轮换，使用 -proxy 或代理列表中第一个可用的代理
+func setupProxies(cfg *config.Config) error {
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	var screenshotProxy *url.URL
+	if cfg.Proxy != "" {
+		proxyURL, _ := config.ParseProxy(cfg.Proxy)
+		if err := checker.CheckProxy(proxyURL, timeout); err != nil {
+			return err
+		}
+		screenshotProxy = proxyURL
 	}
-
-	// 如果用户请求的并发数较小，使用用户设置
-	if requestedConcurrency < optimalConcurrency {
-		optimalConcurrency = requestedConcurrency
+	if len(cfg.Proxies) > 0 {
+		errs := make([]error, len(cfg.Proxies))
+		var wg sync.WaitGroup
+		for i, proxyURL := range cfg.Proxies {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = checker.CheckProxy(proxyURL, timeout)
+			}()
+		}
+		wg.Wait()
+		var usable []*url.URL
+		for i, err := range errs {
+			if err != nil {
+				utils.Log().Warnf("⚠️  跳过代理: %v\n", err)
+				continue
+			}
+			usable = append(usable, cfg.Proxies[i])
+		}
+		if len(usable) == 0 {
+			return fmt.Errorf("%s 中的 %d 个代理都无法连接", cfg.ProxyFile, len(cfg.Proxies))
+		}
+		utils.Log().Infof("🔀 轮换使用 %d 个代理 (%s)\n", len(usable), cfg.ProxyRotation)
+		cfg.Proxies = usable
+		screenshotProxy = usable[0]
 	}
-
-	// 显示资源评估结果
-	fmt.Printf("📈 资源评估: CPU支持%d个, 内存支持%d个, 推荐%d个\n",
-		cpuBasedConcurrency, memoryBasedConcurrency, optimalConcurrency)
-
-	// 根据最终并发数给出性能预期和建议
-	if optimalConcurrency <= numCPU {
-		fmt.Printf("✅ 稳定模式: %d个工作者 (预期成功率: 95%%+, 速度稳定)\n", optimalConcurrency)
-		fmt.Printf("📈 性能预期: 低资源占用，高成功率，适合长时间运行\n")
-	} else if optimalConcurrency <= numCPU*2 {
-		fmt.Printf("⚖️  平衡模式: %d个工作者 (预期成功率: 85-95%%, 速度较快)\n", optimalConcurrency)
-		fmt.Printf("📈 性能预期: 中等资源占用，良好成功率，速度与稳定性平衡\n")
-	} else if optimalConcurrency <= numCPU*3 {
-		fmt.Printf("⚡ 高速模式: %d个工作者 (预期成功率: 75-85%%, 高速度)\n", optimalConcurrency)
-		fmt.Printf("📈 性能预期: 高资源占用，中等成功率，最大化处理速度\n")
-	} else {
-		fmt.Printf("🚀 极速模式: %d个工作者 (预期成功率: 60-75%%, 极高速度)\n", optimalConcurrency)
-		fmt.Printf("📈 性能预期: 极高资源占用，可能出现更多失败，但处理速度最快\n")
-		fmt.Printf("⚠️  警告: 建议监控系统资源使用情况\n")
+	if screenshotProxy != nil && (cfg.Screenshot || cfg.ScreenshotAlive) {
+		return screenshot.SetProxy(screenshotProxy)
 	}
+	return nil
+}
 
-	// 如果用户请求的并发数过高，给出警告
-	if requestedConcurrency > optimalConcurrency {
-		fmt.Printf("🔧 智能优化: %d -> %d (基于CPU和内存资源自动调整)\n", requestedConcurrency, optimalConcurrency)
-		fmt.Printf("💡 提示: 系统资源限制，使用推荐值可获得最佳性能\n")
+// 确定截图工作池的大小：未指定 -screenshot-concurrency 时根据CPU和内存自动计算，
+// 指定时直接使用，只受内存和 maxScreenshotConcurrency 的限制（-no-auto-tune 时不限制）
+func screenshotConcurrency(cfg *config.Config, totalDomains int) int {
+	requested := cfg.ScreenshotWorkers
+	if requested <= 0 {
+		return calculateOptimalScreenshotConcurrency(0, totalDomains)
 	}
-
-	// 确保至少有1个工作者
-	if optimalConcurrency < 1 {
-		optimalConcurrency = 1
+	if !cfg.NoAutoTune {
+		_, available := systemMemoryGB()
+		if limit := max(1, min(maxScreenshotConcurrency, screenshot.MemoryLimit(available))); requested > limit {
+			utils.Log().Warnf("⚠️  -screenshot-concurrency %d 超过上限，已限制为 %d（使用 -no-auto-tune 跳过限制）\n", requested, limit)
+			requested = limit
+		}
 	}
+	return max(1, min(requested, totalDomains))
+}
 
-	// 确保不超过域名总数
-	if optimalConcurrency > totalDomains {
-		optimalConcurrency = totalDomains
-	}
+// 根据CPU和内存自动计算截图并发数。终端只显示结果和限制因素，计算过程在 -verbose 时显示
+func calculateOptimalScreenshotConcurrency(requestedConcurrency int, totalDomains int) int {
+	_, availableGB := systemMemoryGB()
+	r := screenshot.Recommend(runtime.NumCPU(), availableGB, totalDomains, requestedConcurrency)
 
-	return optimalConcurrency
+	utils.Log().Debugf("💻 系统资源: CPU=%d核心, 可用内存 %.1fGB\n", r.CPUs, r.AvailableGB)
+	utils.Log().Debugf("📈 资源评估: CPU支持%d个, 内存支持%d个, 按目标数量最多%d个\n",
+		r.CPUBased, r.MemoryBased, r.TargetLimit)
+	utils.Log().Infof("✅ 截图并发数: %d个 (限制因素: %s，可用 -screenshot-concurrency 指定)\n", r.Chosen, r.LimitingFactor)
+	return r.Chosen
 }
 
 // 清理所有Chrome进程
 func cleanupChromeProcesses() {
-	fmt.Printf("🧹 正在检查并清理Chrome进程...\n")
+	utils.Log().Infof("🧹 正在检查并清理Chrome进程...\n")
 
 	cleanedCount := 0
 
@@ -216,7 +141,7 @@ func cleanupChromeProcesses() {
 				cmd := exec.Command("taskkill", "/F", "/IM", process)
 				output, err := cmd.CombinedOutput()
 				if err == nil {
-					fmt.Printf("✅ 已清理进程: %s\n", process)
+					utils.Log().Infof("✅ 已清理进程: %s\n", process)
 					cleanedCount++
 				} else {
 					// 只在真正的错误时显示（不是"进程未找到"）
@@ -224,7 +149,7 @@ func cleanupChromeProcesses() {
 					if !strings.Contains(outputStr, "没有找到进程") &&
 						!strings.Contains(outputStr, "not found") &&
 						!strings.Contains(outputStr, "No tasks") {
-						fmt.Printf("⚠️  清理进程 %s 时出错: %v\n", process, err)
+						utils.Log().Warnf("⚠️  清理进程 %s 时出错: %v\n", process, err)
 					}
 				}
 			}
@@ -242,45 +167,265 @@ func cleanupChromeProcesses() {
 	}
 
 	if cleanedCount > 0 {
-		fmt.Printf("✅ Chrome进程清理完成，清理了 %d 个进程\n", cleanedCount)
+		utils.Log().Infof("✅ Chrome进程清理完成，清理了 %d 个进程\n", cleanedCount)
 	} else {
-		fmt.Printf("✅ 无需清理，Chrome进程已正常退出\n")
+		utils.Log().Infof("✅ 无需清理，Chrome进程已正常退出\n")
 	}
 }
 
-// 优雅关闭处理器
-func setupGracefulShutdown(screenshotPool *screenshot.ScreenshotPool) {
+// 优雅关闭处理器，onInterrupt 在退出前调用（可为nil）。
+// 返回停止处理中断信号的函数，以及不经过信号直接触发相同处理的函数（如交互式界面中按 q）
+func setupGracefulShutdown(screenshotPool *screenshot.ScreenshotPool, onInterrupt func()) (stop, interrupt func()) {
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	signal.Notify(c, shutdownSignals...)
+	notifyConsoleClose(c)
 
 	go func() {
-		<-c
-		fmt.Printf("\n🛑 接收到中断信号，正在优雅关闭...\n")
+		sig := <-c
+		gracefulShutdown(sig, c, screenshotPool, onInterrupt)
+		os.Exit(exitInterrupted)
+	}()
+	stop = func() { signal.Stop(c) }
+	interrupt = func() {
+		select {
+		case c <- os.Interrupt:
+		default:
+		}
+	}
+	return stop, interrupt
+}
+
+// 收到中断信号后等待关闭流程完成的最长时间（Windows控制台关闭等事件的时限更短）
+const shutdownGrace = 30 * time.Second
+
+// 中断时的关闭流程：停止截图工作池、清理Chrome进程，再执行 onInterrupt（保存已有结果等）。
+// 超过宽限时间仍未完成或从 c 再次收到信号时不再等待，只清理Chrome进程，避免留下无头Chrome进程；
+// 未完成的保存随进程退出而放弃
+func gracefulShutdown(sig os.Signal, c <-chan os.Signal, screenshotPool *screenshot.ScreenshotPool, onInterrupt func()) {
+	if sig == os.Interrupt {
+		utils.Log().Infof("\n🛑 接收到中断信号 (%s)，正在优雅关闭...（再次按 Ctrl+C 立即退出）\n", sig)
+	} else {
+		utils.Log().Infof("\n🛑 接收到中断信号 (%s)，正在优雅关闭...\n", sig)
+	}
+	grace := shutdownGraceFor(sig)
 
-		// 停止截图工作池
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// 停止截图工作池并清理Chrome进程
 		if screenshotPool != nil {
-			fmt.Printf("📸 正在停止截图工作池...\n")
+			utils.Log().Infof("📸 正在停止截图工作池...\n")
 			screenshotPool.Stop()
+			cleanupChromeProcesses()
+		}
+		if onInterrupt != nil {
+			onInterrupt()
 		}
+	}()
 
-		// 清理Chrome进程
-		cleanupChromeProcesses()
+	select {
+	case <-done:
+		utils.Log().Infof("👋 程序已安全退出\n")
+	case <-time.After(grace):
+		utils.Log().Warnf("⚠️  关闭流程超过 %s 未完成，清理Chrome进程后直接退出\n", grace)
+		if screenshotPool != nil {
+			cleanupChromeProcesses()
+		}
+	case sig := <-c:
+		utils.Log().Warnf("⚠️  再次接收到中断信号 (%s)，放弃未完成的保存，清理Chrome进程后立即退出\n", sig)
+		if screenshotPool != nil {
+			cleanupChromeProcesses()
+		}
+	}
+}
 
-		fmt.Printf("👋 程序已安全退出\n")
-		os.Exit(0)
-	}()
+// 检测结束后继续提供Web界面，直到收到中断信号再关闭服务器
+func serveUntilInterrupt(server *web.Server) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, shutdownSignals...)
+	defer signal.Stop(c)
+	utils.Log().Infof("🌐 检测完成，Web界面仍在 %s 提供最终结果和文件下载，按 Ctrl+C 退出\n", server.URL())
+	<-c
+	server.Shutdown()
+	utils.Log().Infof("👋 Web界面已关闭\n")
 }
 
-func main() {
-	// 确保程序退出时清理资源
-	defer func() {
-		if r := recover(); r != nil {
-			fmt.Printf("🚨 程序异常退出: %v\n", r)
-			cleanupChromeProcesses()
+// 编译主机名过滤的正则表达式，未指定时返回nil
+func compileHostFilter(name, pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%s 不是有效的正则表达式: %v", name, err)
+	}
+	return re, nil
+}
+
+// 保留主机名满足 keep 的目标，并报告保留和去除的数量
+func filterTargets(domains []string, name string, keep func(host string) bool) []string {
+	var kept []string
+	for _, domain := range domains {
+		if keep(utils.TargetHost(domain)) {
+			kept = append(kept, domain)
 		}
-	}()
+	}
+	utils.Log().Infof("🔎 %s: 保留 %d 个，去除 %d 个\n", name, len(kept), len(domains)-len(kept))
+	return kept
+}
+
+// 以较低并发和较长超时复查无法访问的目标（受保护的目标已有响应，不复查），结果有变化时原地替换。
+// only 不为nil时只复查其中的目标（Input）。返回复查的数量和恢复存活的结果
+func recheckDead(results []checker.Result, only map[string]bool, cfg config.Config, screenshotPool *screenshot.ScreenshotPool) (int, []checker.Result) {
+	var targets []int
+	for i, result := range results {
+		if !result.Alive && !checker.IsProtected(result) && (only == nil || only[result.Input]) {
+			targets = append(targets, i)
+		}
+	}
+	if len(targets) == 0 {
+		return 0, nil
+	}
+
+	// 大规模运行中的失败常由本机连接数或DNS解析器过载引起，复查时降低并发并延长超时
+	cfg.Concurrency = min(10, max(1, cfg.Concurrency/4))
+	cfg.Timeout *= 2
+	cfg.Adaptive = false
+	cfg.AutoConcurrency = false
+	utils.Log().Infof("🔁 第二轮复查 %d 个无法访问的目标 (并发: %d，超时: %d秒)\n", len(targets), cfg.Concurrency, cfg.Timeout)
+
+	startTime := time.Now()
+	var processed, alive, protected, dead int32
+	doneChan := make(chan struct{})
+	progressDone := make(chan struct{})
+	if cfg.Silent {
+		close(progressDone)
+	} else {
+		go view.ShowProgress("复查", &processed, &alive, &protected, &dead, len(targets), startTime, nil, nil, doneChan, progressDone)
+	}
+
+	// 复查结果按输入的目标对应回原结果的下标
+	inputs := make([]string, len(targets))
+	indexOf := make(map[string]int, len(targets))
+	for n, i := range targets {
+		input := results[i].Input
+		if input == "" {
+			input = results[i].Domain
+		}
+		inputs[n] = input
+		indexOf[input] = i
+	}
 
-	fmt.Println(`
+	var recovered []checker.Result
+	runner, err := squirrel.NewRunner(cfg, squirrel.Options{
+		ScreenshotPool: screenshotPool,
+		Proxies:        cfg.Proxies,
+		OnResult: func(result checker.Result) {
+			i, ok := indexOf[result.Input]
+			if !ok {
+				return
+			}
+			old := results[i]
+			if result.Alive != old.Alive || result.Status != old.Status || result.StatusText != old.StatusText {
+				result.Original = old.Original
+				result.Note = old.Note
+				results[i] = result
+				if result.Alive {
+					recovered = append(recovered, result)
+				}
+			}
+		},
+		OnProgress: func(p squirrel.Progress) {
+			atomic.StoreInt32(&processed, int32(p.Processed))
+			atomic.StoreInt32(&alive, int32(p.Alive))
+			atomic.StoreInt32(&protected, int32(p.Protected))
+			atomic.StoreInt32(&dead, int32(p.Dead))
+		},
+	})
+	if err != nil {
+		utils.Log().Errorf("复查失败: %s\n", err)
+		close(doneChan)
+		<-progressDone
+		return 0, nil
+	}
+	runner.Run(context.Background(), inputs)
+	close(doneChan)
+	<-progressDone
+
+	if !cfg.Silent && utils.IsTerminal(os.Stdout) {
+		fmt.Printf("\r%-80s\r", " ")
+	}
+	utils.Log().Infof("🔁 复查完成: %d 个恢复存活，耗时 %.2f 秒\n", len(recovered), time.Since(startTime).Seconds())
+	return len(targets), recovered
+}
+
+// 写入检查点的最终状态并关闭，提示如何继续运行
+func saveCheckpoint(ckpt *checkpoint.Writer) {
+	path := ckpt.Path()
+	if err := ckpt.Close(); err != nil {
+		utils.Log().Errorf("保存检查点时出错: %s\n", err)
+		return
+	}
+	state := ckpt.State()
+	if state.Processed < state.Targets {
+		utils.Log().Infof("💾 检查点已保存到 %s (%d/%d)，使用 -resume %s 继续\n", path, state.Processed, state.Targets, path)
+	} else {
+		utils.Log().Infof("💾 检查点已保存到 %s，所有目标均已检测\n", path)
+	}
+}
+
+// 生成运行元数据，正常结束和中断时使用相同的字段
+func newRunMeta(cfg *config.Config, startTime time.Time, totalTime time.Duration, targets int) *view.RunMeta {
+	return &view.RunMeta{
+		Version:     buildInfo(),
+		Command:     view.SanitizeArgs(os.Args),
+		StartTime:   startTime,
+		EndTime:     startTime.Add(totalTime),
+		Targets:     targets,
+		Concurrency: cfg.Concurrency,
+		Timeout:     cfg.Timeout,
+		Shard:       cfg.Shard,
+	}
+}
+
+// 退出码
+const (
+	exitOK           = 0 // 成功
+	exitUsage        = 1 // 参数错误、启动失败或异常退出
+	exitOutputFailed = 2 // 检测完成但写入输出文件失败
+	exitInterrupted  = 3 // 被中断
+	exitFound        = 4 // 启用 -fail-on-alive/-fail-on-new 且发现了存活/新存活的主机
+)
+
+// 版本信息，发布构建时通过 -ldflags "-X main.version=... -X main.commit=... -X main.buildDate=..." 注入
+var (
+	version   = "v1.3-dev"
+	commit    = "dev"
+	buildDate = "unknown"
+)
+
+// 单行的版本信息，写入运行元数据、报告和统计文件
+func buildInfo() string {
+	return fmt.Sprintf("%s (commit %s, built %s, %s)", version, buildCommit(), buildDate, runtime.Version())
+}
+
+// 构建时的git提交，未通过 -ldflags 注入时尝试读取 go build 自动嵌入的版本控制信息
+func buildCommit() string {
+	if commit != "dev" {
+		return commit
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" && len(setting.Value) >= 7 {
+				return setting.Value[:7]
+			}
+		}
+	}
+	return commit
+}
+
+// 启动横幅（%s 为版本号）
+const banner = `
                                /$$                             /$$
                               |__/                            | $$
   /$$$$$$$  /$$$$$$  /$$   /$$ /$$  /$$$$$$  /$$$$$$  /$$$$$$ | $$
@@ -292,223 +437,1249 @@ func main() {
                 | $$
                 | $$
                 |__/
-                    松鼠子域名检测工具 v1.3
-`)
+                    松鼠子域名检测工具 %s
+`
 
-	// 解析命令行参数
+func main() {
+	// 确保程序退出时清理资源
+	defer func() {
+		if r := recover(); r != nil {
+			utils.Log().Errorf("🚨 程序异常退出: %v\n", r)
+			cleanupChromeProcesses()
+			os.Exit(exitUsage)
+		}
+	}()
+
+	// 解析子命令和参数，所有子命令共用同一套参数；-fields 的可选字段来自字段注册表，帮助信息随注册表自动更新
+	command, args := config.SplitCommand(os.Args[1:])
 	cfg := config.Config{}
-	config.ParseFlags(&cfg)
+	if err := config.ParseFlags(&cfg, command, args, view.FieldNames()); err != nil {
+		// 错误信息和用法已由 ParseFlags 输出
+		if err == flag.ErrHelp {
+			os.Exit(exitOK)
+		}
+		os.Exit(exitUsage)
+	}
+	if cfg.Version {
+		fmt.Printf("squirrel %s\n  提交: %s\n  构建时间: %s\n  Go版本: %s\n", version, buildCommit(), buildDate, runtime.Version())
+		os.Exit(exitOK)
+	}
+	if cfg.ConfigFile != "" {
+		if err := config.LoadFile(cfg.ConfigFile); err != nil {
+			fmt.Printf("错误: %s\n", err)
+			os.Exit(exitUsage)
+		}
+	}
+	// 预设在命令行和配置文件之后应用，只修改未显式指定的参数
+	var presetChanges map[string]string
+	if cfg.Preset != "" {
+		var err error
+		presetChanges, err = config.ApplyPreset(&cfg, cfg.Preset)
+		if err != nil {
+			fmt.Printf("错误: -preset: %s\n", err)
+			os.Exit(exitUsage)
+		}
+	}
+	// 输出不是终端时自动去掉颜色和表情符号，-no-color、-no-emoji 和 NO_COLOR 环境变量可以在终端中关闭
+	utils.SetOutputStyle(cfg.NoColor, cfg.NoEmoji)
+	if cfg.WriteConfig != "" {
+		refs, err := config.WriteFile(cfg.WriteConfig)
+		if err != nil {
+			fmt.Printf("错误: -write-config: %s\n", err)
+			os.Exit(exitUsage)
+		}
+		fmt.Printf("配置已写入 %s，使用 -config %s 加载\n", cfg.WriteConfig, cfg.WriteConfig)
+		if len(refs) > 0 {
+			fmt.Println("以下敏感参数写成了环境变量引用，使用该配置前需要设置:")
+			for _, ref := range refs {
+				fmt.Printf("  %s  (-%s)\n", ref.Name, ref.Flag)
+			}
+		}
+		os.Exit(exitOK)
+	}
+	if cfg.PrintConfig {
+		if cfg.Preset != "" {
+			fmt.Printf("# 预设 %s: %s\n", cfg.Preset, config.FormatPresetChanges(presetChanges))
+		}
+		out, err := config.PrintConfig()
+		if err != nil {
+			fmt.Printf("错误: %s\n", err)
+			os.Exit(exitUsage)
+		}
+		fmt.Print(out)
+		os.Exit(exitOK)
+	}
 
-	// HTML输出选项
-	var htmlOutput, simpleHTML string
-	flag.StringVar(&htmlOutput, "html", "", "输出结果到HTML文件")
-	flag.StringVar(&simpleHTML, "simple-html", "", "输出结果到简化版HTML文件")
-	flag.Parse()
+	// 校验参数，一次列出所有问题，避免运行到一半才出错
+	problems := cfg.Validate()
+	for _, target := range cfg.Notify {
+		if _, _, err := notify.ParseTarget(target); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	if err := view.SortResults(nil, cfg.Sort, cfg.Reverse); err != nil {
+		problems = append(problems, err)
+	}
 
-	if flag.NArg() < 1 {
-		fmt.Println("用法: squirrel [选项] <域名列表文件或逗号分隔的域名列表>")
-		fmt.Println("\n选项:")
-		flag.PrintDefaults()
-		os.Exit(1)
+	// 解析输出字段：CSV默认使用完整列，静默模式默认只输出URL，-plain-fields 优先于 -fields
+	csvFieldNames, plainFieldNames := view.DefaultCSVFields, "url"
+	for _, name := range cfg.CapturedHeaders()[len(config.DefaultCapturedHeaders):] {
+		csvFieldNames += ",header:" + name // -headers-capture 指定的响应头追加在默认列之后
+	}
+	if cfg.Favicon {
+		csvFieldNames += ",favicon_hash"
+	}
+	if cfg.Fields != "" {
+		csvFieldNames, plainFieldNames = cfg.Fields, cfg.Fields
+	}
+	if cfg.PlainFields != "" {
+		plainFieldNames = cfg.PlainFields
+	}
+	csvFields, err := view.ParseFields(csvFieldNames)
+	if err != nil {
+		problems = append(problems, err)
+	}
+	plainFields, err := view.ParseFields(plainFieldNames)
+	if err != nil && plainFieldNames != csvFieldNames {
+		problems = append(problems, err)
 	}
+	cfg.FieldHeaders = view.FieldHeaders(slices.Concat(csvFields, plainFields))
 
-	if (cfg.Screenshot || cfg.ScreenshotAlive) && cfg.ExcelFile == "" && htmlOutput == "" && simpleHTML == "" {
-		fmt.Println("错误: 启用截图功能时必须指定 -excel、-html 或 -simple-html 选项")
-		os.Exit(1)
+	// 主机名过滤的正则表达式只编译一次
+	matchHost, err := compileHostFilter("-match-host", cfg.MatchHost)
+	if err != nil {
+		problems = append(problems, err)
+	}
+	filterHost, err := compileHostFilter("-filter-host", cfg.FilterHost)
+	if err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.OverridesFile != "" {
+		if cfg.Overrides, err = config.LoadOverrides(cfg.OverridesFile); err != nil {
+			problems = append(problems, fmt.Errorf("-overrides: %v", err))
+		}
+	}
+	if cfg.ProxyFile != "" {
+		if cfg.Proxies, err = config.LoadProxyFile(cfg.ProxyFile); err != nil {
+			problems = append(problems, fmt.Errorf("-proxy-file: %v", err))
+		}
 	}
 
-	var domains []string
+	if len(problems) > 0 {
+		fmt.Println("参数错误:")
+		for _, problem := range problems {
+			fmt.Printf("  - %s\n", problem)
+		}
+		os.Exit(exitUsage)
+	}
+
+	// 初始化日志：终端默认只显示信息级别以上的日志，-verbose 时显示调试日志
+	consoleLevel := utils.LevelInfo
+	if cfg.Verbose {
+		consoleLevel = utils.LevelDebug
+	}
+	if cfg.LogFile != "" {
+		fileLevel, err := utils.ParseLevel(cfg.LogLevel)
+		if err != nil {
+			fmt.Printf("错误: %s\n", err)
+			os.Exit(exitUsage)
+		}
+		logFile, err := os.OpenFile(cfg.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			fmt.Printf("错误: 无法打开日志文件: %s\n", err)
+			os.Exit(exitUsage)
+		}
+		defer logFile.Close()
+		utils.SetLogger(utils.NewFileLogger(consoleLevel, logFile, fileLevel, cfg.LogJSON))
+	} else {
+		utils.SetLogger(utils.NewLogger(consoleLevel))
+	}
+
+	switch command {
+	case config.CommandReport:
+		os.Exit(runReport(&cfg, csvFields))
+	case config.CommandDiff:
+		os.Exit(runDiff(&cfg))
+	case config.CommandMerge:
+		os.Exit(runMerge(&cfg, csvFields))
+	case config.CommandHistory:
+		os.Exit(runHistory(&cfg))
+	}
+	if cfg.Monitor && !isMonitorCycle() {
+		if !cfg.Silent {
+			fmt.Printf(banner, version)
+		}
+		os.Exit(runMonitor(&cfg))
+	}
+	runScan(cfg, presetChanges, csvFields, plainFields, matchHost, filterHost)
+}
+
+// 检测目标（scan 和 resume 子命令），resume 的检查点文件已由参数解析写入 cfg.Resume
+func runScan(cfg config.Config, presetChanges map[string]string, csvFields, plainFields []view.Field, matchHost, filterHost *regexp.Regexp) {
 	var err error
-	arg := flag.Arg(0)
-	if strings.Contains(arg, ",") {
+
+	// 静默模式：结果写入标准输出，其余提示信息全部转到标准错误
+	plainOut := os.Stdout
+	if cfg.Silent {
+		os.Stdout = os.Stderr
+	} else if !isMonitorCycle() {
+		fmt.Printf(banner, version)
+	}
+	if cfg.Preset != "" {
+		utils.Log().Infof("使用预设 %s: %s", cfg.Preset, config.FormatPresetChanges(presetChanges))
+	}
+
+	// 从检查点恢复：读取已检测的结果，未指定目标参数时使用检查点中记录的参数
+	var ckpt *checkpoint.Writer
+	var previousResults []checker.Result
+	if cfg.Resume != "" {
+		if ckpt, previousResults, err = checkpoint.Resume(cfg.Resume); err != nil {
+			fmt.Printf("错误: %s\n", err)
+			os.Exit(exitUsage)
+		}
+	}
+	arg := cfg.Input
+	if arg == "" && ckpt != nil {
+		arg = ckpt.State().Input
+	}
+	// 未指定目标且标准输入不是终端（如管道）时从标准输入读取
+	if arg == "" && !utils.IsTerminal(os.Stdin) {
+		arg = "-"
+	}
+
+	if arg == "" {
+		config.PrintUsage(config.CommandScan)
+		os.Exit(exitUsage)
+	}
+
+	resolveOutputs(&cfg)
+
+	// 代理不可用时直接退出，而不是把每个目标都记为无法访问
+	if err := setupProxies(&cfg); err != nil {
+		utils.Log().Errorf("%s: %v\n", checker.ErrorProxy, err)
+		os.Exit(exitUsage)
+	}
+	if isMonitorCycle() {
+		stampOutputs(&cfg, os.Getenv(monitorCycleEnv))
+		cfg.DiffBaseline = os.Getenv(monitorBaselineEnv)
+	}
+
+	// 读取 -fail-on-new 的基线文件
+	var baseline []checker.Result
+	if cfg.FailOnNew != "" {
+		var err error
+		if baseline, err = view.LoadBaseline(cfg.FailOnNew); err != nil {
+			fmt.Printf("错误: 无法读取基线文件: %s\n", err)
+			os.Exit(exitUsage)
+		}
+	}
+
+	// 读取 -diff 的基线文件
+	var diffBaseline []checker.Result
+	if cfg.DiffBaseline != "" {
+		var err error
+		if diffBaseline, err = view.LoadBaseline(cfg.DiffBaseline); err != nil {
+			fmt.Printf("错误: 无法读取基线文件: %s\n", err)
+			os.Exit(exitUsage)
+		}
+	}
+
+	var domains []string
+	var notes map[string]string // 输入文件中的行尾备注
+	if arg == "-" {
+		// 先读取全部输入再开始检测，以便显示总数和进度条
+		domains, notes, err = utils.ReadAnnotatedDomains(os.Stdin)
+		if err != nil {
+			fmt.Printf("无法读取标准输入: %s\n", err)
+			os.Exit(exitUsage)
+		}
+	} else if strings.Contains(arg, ",") || utils.IsCIDR(arg) {
 		domains = strings.Split(arg, ",")
+	} else if cfg.InputFormat == view.InputText {
+		domains, notes, err = utils.ReadAnnotatedDomainsFromFile(arg)
+		if err != nil {
+			fmt.Printf("无法读取文件: %s\n", err)
+			os.Exit(exitUsage)
+		}
 	} else {
-		domains, err = utils.ReadDomainsFromFile(arg)
+		// 输入可以是上一次运行输出的CSV、JSON或Excel结果文件
+		domains, notes, err = view.LoadTargets(arg, cfg.InputFormat, cfg.OnlyAliveFromInput)
 		if err != nil {
 			fmt.Printf("无法读取文件: %s\n", err)
-			os.Exit(1)
+			os.Exit(exitUsage)
 		}
 	}
-	// 新增：归一化域名，支持 http(s):// 前缀
-	domainMap := make(map[string]bool)
-	var uniqueDomains []string
+	// 展开CIDR范围，需在去重和计数之前完成，保证总数和进度正确
+	var expanded []string
 	for _, d := range domains {
 		d = strings.TrimSpace(d)
-		if strings.HasPrefix(d, "http://") || strings.HasPrefix(d, "https://") {
-			if u, err := url.Parse(d); err == nil && u.Host != "" {
-				if !domainMap[u.Host] {
-					domainMap[u.Host] = true
-					uniqueDomains = append(uniqueDomains, u.Host)
+		if !utils.IsCIDR(d) {
+			expanded = append(expanded, d)
+			continue
+		}
+		ips, err := utils.ExpandCIDR(d, cfg.AllowLargeCIDR)
+		if err != nil {
+			fmt.Printf("错误: %s\n", err)
+			os.Exit(exitUsage)
+		}
+		utils.Log().Infof("🌐 %s 展开为 %d 个地址\n", d, len(ips))
+		expanded = append(expanded, ips...)
+		// CIDR行的备注适用于展开后的每个地址
+		if note := notes[d]; note != "" {
+			for _, ip := range ips {
+				if _, ok := notes[ip]; !ok {
+					notes[ip] = note
 				}
+			}
+		}
+	}
+	domains = expanded
+
+	// 归一化后去重：去掉协议和默认端口，主机名转小写并去掉末尾的点，同一目标的不同写法只检测一次。
+	// 记录每个目标第一次出现时的原始写法，显示在结果中；备注取第一条非空的
+	domainMap := make(map[string]bool)
+	originals := make(map[string]string)
+	targetNotes := make(map[string]string)
+	var uniqueDomains []string
+	addDomain := func(d, original, note string) {
+		if !domainMap[d] {
+			domainMap[d] = true
+			uniqueDomains = append(uniqueDomains, d)
+			if original != d {
+				originals[d] = original
+			}
+		}
+		if _, ok := targetNotes[d]; note != "" && !ok {
+			targetNotes[d] = note
+		}
+	}
+	appendPorts, _ := config.ParsePorts(cfg.AppendPorts)
+	ports, _ := config.ParsePorts(cfg.Ports)
+	// -paths 时不带路径的目标展开为各路径上的目标，"/" 对应原目标；已带路径的目标保持不变，重复的目标由 addDomain 去掉
+	paths, _ := config.ParsePaths(cfg.Paths)
+	addTarget := func(d, original, note string) {
+		if len(paths) == 0 || strings.Contains(d, "/") {
+			addDomain(d, original, note)
+			return
+		}
+		for _, path := range paths {
+			if path == "/" {
+				addDomain(d, original, note)
 			} else {
-				if !domainMap[d] {
-					domainMap[d] = true
-					uniqueDomains = append(uniqueDomains, d)
+				addDomain(d+path, d+path, note)
+			}
+		}
+	}
+	for _, original := range domains {
+		original = strings.TrimSpace(original)
+		note := notes[original]
+		d := utils.NormalizeTarget(original)
+		// 国际化域名转换为punycode，Unicode和punycode写法视为同一目标；
+		// 无效的域名保持原样，检测时会单独报告
+		if ascii, err := utils.ToASCIITarget(d); err == nil {
+			d = ascii
+		}
+		// -ports 时不带端口的主机展开为各端口上的目标，代替原目标；443 归一化后就是原目标，先HTTPS后HTTP检测，
+		// 80 保留在目标中，与其他非HTTPS端口一样先HTTP后HTTPS。已带端口或路径的目标保持不变
+		if len(ports) > 0 && !strings.Contains(d, "/") {
+			if _, _, err := net.SplitHostPort(d); err != nil {
+				for _, port := range ports {
+					target := utils.WithPort(d, port)
+					switch normalized := utils.NormalizeTarget(target); {
+					case port == "80":
+						addTarget(target, target, note)
+					case normalized != d:
+						addTarget(normalized, target, note)
+					default:
+						addTarget(d, original, note)
+					}
 				}
+				continue
 			}
-		} else {
-			if !domainMap[d] {
-				domainMap[d] = true
-				uniqueDomains = append(uniqueDomains, d)
+		}
+		addTarget(d, original, note)
+
+		// 为不带端口的主机追加 -append-ports 指定的端口，已带端口或路径的目标保持不变
+		if len(appendPorts) > 0 && !strings.Contains(d, "/") {
+			if _, _, err := net.SplitHostPort(d); err != nil {
+				for _, port := range appendPorts {
+					target := utils.WithPort(d, port)
+					addTarget(utils.NormalizeTarget(target), target, note)
+				}
 			}
 		}
 	}
 	domains = uniqueDomains
 	if len(domains) == 0 {
 		fmt.Println("没有找到需要检测的域名")
-		os.Exit(1)
+		os.Exit(exitUsage)
+	}
+
+	// 按主机名（不含协议和端口）过滤目标
+	if matchHost != nil {
+		domains = filterTargets(domains, "-match-host", func(host string) bool { return matchHost.MatchString(host) })
+	}
+	if filterHost != nil {
+		domains = filterTargets(domains, "-filter-host", func(host string) bool { return !filterHost.MatchString(host) })
+	}
+	if len(domains) == 0 {
+		fmt.Println("没有符合过滤条件的域名")
+		os.Exit(exitUsage)
 	}
 
-	fmt.Printf("总共需要检测 %d 个域名，并发数: %d，超时: %d秒\n",
+	// 去除范围外的目标，被排除的目标不会被检测或截图
+	var excluded []string
+	if cfg.ExcludeFile != "" || len(cfg.Exclude) > 0 {
+		rules := append([]string(nil), cfg.Exclude...)
+		if cfg.ExcludeFile != "" {
+			fileRules, err := utils.ReadDomainsFromFile(cfg.ExcludeFile)
+			if err != nil {
+				fmt.Printf("无法读取排除列表: %s\n", err)
+				os.Exit(exitUsage)
+			}
+			rules = append(rules, fileRules...)
+		}
+		excluder, err := utils.NewExcluder(rules)
+		if err != nil {
+			fmt.Printf("错误: %s\n", err)
+			os.Exit(exitUsage)
+		}
+		var kept []string
+		for _, domain := range domains {
+			if excluder.Match(domain) {
+				excluded = append(excluded, domain)
+			} else {
+				kept = append(kept, domain)
+			}
+		}
+		domains = kept
+		utils.Log().Infof("🚫 根据 %d 条排除规则排除了 %d 个目标\n", excluder.Len(), len(excluded))
+
+		if cfg.ExcludedOutput != "" {
+			content := strings.Join(excluded, "\n")
+			if content != "" {
+				content += "\n"
+			}
+			if err := os.WriteFile(cfg.ExcludedOutput, []byte(content), 0644); err != nil {
+				utils.Log().Errorf("保存被排除的目标时出错: %s\n", err)
+			} else {
+				utils.Log().Infof("被排除的目标已保存到 %s\n", cfg.ExcludedOutput)
+			}
+		}
+		if len(domains) == 0 {
+			fmt.Println("所有目标均已被排除，没有需要检测的域名")
+			os.Exit(exitUsage)
+		}
+	}
+
+	// 只保留本机负责的分片，其余目标由其他机器检测，不计入未检测的数量
+	if cfg.Shard != "" {
+		index, count, _ := config.ParseShard(cfg.Shard)
+		before := len(domains)
+		domains = utils.ShardTargets(domains, index, count)
+		utils.Log().Infof("🧩 分片 %s: 从 %d 个目标中分到 %d 个\n", cfg.Shard, before, len(domains))
+		if len(domains) == 0 {
+			fmt.Println("该分片没有需要检测的目标")
+			os.Exit(exitUsage)
+		}
+	}
+
+	// 运行历史按抽样和上限之前的目标范围归类，同一目标列表的多次运行可以对比
+	scope := history.Scope(domains)
+
+	// 在过滤和排除之后抽样，再截取前 -max-hosts 个目标
+	var limitNotes []string
+	beforeLimit := len(domains)
+	if cfg.Sample > 0 && cfg.Sample < 1 {
+		seed := cfg.Seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		domains = utils.SampleTargets(domains, cfg.Sample, seed)
+		limitNotes = append(limitNotes, fmt.Sprintf("随机抽样 %g%% (种子 %d)", cfg.Sample*100, seed))
+		utils.Log().Infof("🎲 随机抽样 %g%%: 从 %d 个目标中抽取 %d 个，使用 -seed %d 可复现\n", cfg.Sample*100, beforeLimit, len(domains), seed)
+	}
+	if cfg.MaxHosts > 0 && len(domains) > cfg.MaxHosts {
+		utils.Log().Infof("✂️  -max-hosts: 只检测前 %d 个目标，跳过 %d 个\n", cfg.MaxHosts, len(domains)-cfg.MaxHosts)
+		domains = domains[:cfg.MaxHosts]
+		limitNotes = append(limitNotes, fmt.Sprintf("上限 %d 个", cfg.MaxHosts))
+	}
+
+	// 跳过检查点中已检测的目标
+	totalTargets := len(domains)
+	if ckpt != nil {
+		done := make(map[string]bool, len(previousResults))
+		for _, result := range previousResults {
+			done[result.Input] = true
+		}
+		var remaining []string
+		for _, domain := range domains {
+			if !done[domain] {
+				remaining = append(remaining, domain)
+			}
+		}
+		utils.Log().Infof("♻️  从检查点 %s 恢复: 已检测 %d 个，剩余 %d 个\n", cfg.Resume, len(previousResults), len(remaining))
+		domains = remaining
+	} else if cfg.Checkpoint != "" {
+		if ckpt, err = checkpoint.Create(cfg.Checkpoint, arg, totalTargets); err != nil {
+			fmt.Printf("错误: %s\n", err)
+			os.Exit(exitUsage)
+		}
+	}
+
+	utils.Log().Infof("总共需要检测 %d 个域名，并发数: %d，超时: %d秒\n",
 		len(domains), cfg.Concurrency, cfg.Timeout)
+	if cfg.AutoConcurrency {
+		utils.Log().Infof("📈 自动并发: 从较低的并发开始逐步提高，最高 %d (-auto-concurrency)\n", cfg.Concurrency)
+	}
+
+	// 实时Web界面，从检查点恢复的结果一开始就显示
+	var webServer *web.Server
+	if cfg.Web != "" {
+		webServer = web.NewServer(cfg.Web, cfg.ScreenshotDir, totalTargets)
+		if err := webServer.Start(); err != nil {
+			fmt.Printf("错误: %s\n", err)
+			os.Exit(exitUsage)
+		}
+		webServer.Add(previousResults)
+		utils.Log().Infof("🌐 Web界面: %s\n", webServer.URL())
+	}
+
+	// 诊断功能（-debug-pprof、-trace-file），-debug-stats 的自检报告在检测器创建后启动
+	diag, err := startDiagnostics(&cfg)
+	if err != nil {
+		fmt.Printf("错误: %s\n", err)
+		os.Exit(exitUsage)
+	}
+
+	// 内存预算：Go的软内存上限，并在本次运行的内存占用接近预算时暂缓截图和检测
+	var memoryGuard *utils.MemoryGuard
+	if cfg.MaxMemory != "" {
+		limit, _ := utils.ParseSize(cfg.MaxMemory) // 已在参数校验时检查
+		debug.SetMemoryLimit(int64(limit))
+		if memoryGuard, err = utils.NewMemoryGuard(limit); err != nil {
+			utils.Log().Warnf("⚠️  %s，-max-memory 只限制本程序的Go内存，不会暂缓截图和检测\n", err)
+		} else {
+			utils.Log().Infof("🧠 内存预算: %.1fGB\n", utils.BytesToGB(limit))
+		}
+	}
 
 	startTime := time.Now()
 	totalDomains := len(domains)
 
-	resultChan := make(chan checker.Result, totalDomains*2)
-	domainChan := make(chan string, totalDomains)
+	// 分块处理时每块的结果暂存到磁盘后从内存中释放；不分块时内存中的结果超过 spillThreshold 条后
+	// 同样转存到暂存文件，运行中只保留计数和等待截图的结果
+	chunkSize := totalDomains
+	if cfg.ChunkSize > 0 && cfg.ChunkSize < totalDomains {
+		chunkSize = cfg.ChunkSize
+		utils.Log().Infof("📦 分块处理: 每块 %d 个目标，共 %d 块\n", chunkSize, (totalDomains+chunkSize-1)/chunkSize)
+	}
+
 	doneChan := make(chan struct{})
 	progressDone := make(chan struct{})
-	var wg sync.WaitGroup
 
+	// 结果钩子：运行结束时发送通知，-exec 对每个存活的结果执行命令
+	hooks := []squirrel.ResultHook{notifyHook{&cfg}}
+	if cfg.Exec != "" {
+		execHook, err := squirrel.NewExecHook(cfg.Exec, cfg.ExecConcurrency, cfg.ExecTimeout)
+		if err != nil {
+			fmt.Printf("错误: -exec: %s\n", err)
+			os.Exit(exitUsage)
+		}
+		hooks = append(hooks, execHook)
+	}
+
+	// 流式JSONL输出：从检查点恢复的结果先写入，之后每批已完成的结果立即追加
+	var jsonl *view.JSONLWriter
+	if cfg.JSONLFile != "" {
+		if jsonl, err = view.CreateJSONL(cfg.JSONLFile); err != nil {
+			fmt.Printf("错误: -jsonl: %s\n", err)
+			os.Exit(exitUsage)
+		}
+		if err := jsonl.Write(previousResults); err != nil {
+			utils.Log().Warnf("⚠️  写入JSONL文件失败: %s\n", err)
+		}
+		utils.Log().Infof("📝 检测结果将逐条写入 %s\n", cfg.JSONLFile)
+	}
+
+	// 截图工作池由主流程创建，检测和复查共用，在复查结束后停止。
+	// 启动后退出前都要停止工作池，可能因参数错误退出的准备工作放在启动之前
 	var screenshotPool *screenshot.ScreenshotPool
 	if cfg.Screenshot || cfg.ScreenshotAlive {
-		// 使用智能资源感知计算最优并发数
-		screenshotWorkers := calculateOptimalScreenshotConcurrency(cfg.Concurrency, len(domains))
-
-		// 设置全局并发数，用于动态调整超时
-		screenshot.SetConcurrency(screenshotWorkers)
+		// 截图并发与HTTP并发相互独立，截图超时随工作者数量调整
+		screenshotWorkers := screenshotConcurrency(&cfg, len(domains))
 
-		fmt.Printf("🚀 最终截图并发数: %d 个工作者\n", screenshotWorkers)
-		screenshotPool = screenshot.NewScreenshotPool(screenshotWorkers)
+		utils.Log().Infof("🚀 HTTP并发数: %d，截图并发数: %d 个工作者\n", cfg.Concurrency, screenshotWorkers)
+		screenshotPool = screenshot.NewScreenshotPool(screenshotWorkers, utils.Log())
+		screenshotPool.SetMemoryGuard(memoryGuard)
 		screenshotPool.Start()
-
-		// 设置优雅关闭处理器
-		setupGracefulShutdown(screenshotPool)
 	}
 
-	var processed int32 = 0
-	go view.ShowProgress(&processed, totalDomains, startTime, doneChan, progressDone)
+	var tui *view.TUI
 
-	var resultsMutex sync.Mutex
-	allResults := make([]checker.Result, 0, totalDomains)
-	var alive, dead int32
-	var pageTypeCountMutex sync.Mutex
-	var pageTypeCount = make(map[string]int)
-	var screenshotCount int32 = 0
+	// 输出文件只写一次：正常结束时写入完整结果，结束前被中断时写入已处理的部分
+	var outputMutex sync.Mutex
+	outputsWritten := false
 
-	const batchSize = 10
-	resultBatchChan := make(chan []checker.Result, totalDomains/batchSize+1)
-	go func() {
-		for resultBatch := range resultBatchChan {
-			resultsMutex.Lock()
-			for _, result := range resultBatch {
-				if result.Alive {
-					atomic.AddInt32(&alive, 1)
-					if result.PageInfo != nil {
-						pageTypeCountMutex.Lock()
-						pageTypeCount[result.PageInfo.Type]++
-						pageTypeCountMutex.Unlock()
-					}
-				} else {
-					atomic.AddInt32(&dead, 1)
+	// 汇总结果：已完成的结果每凑满一批写入检查点和JSONL并推送到Web界面，截图在检测之后完成，补上截图后再交出
+	aggregator := squirrel.NewAggregator(previousResults, squirrel.AggregatorOptions{
+		SpillThreshold: min(chunkSize, spillThreshold),
+		BatchSize:      10,
+		OnBatch: func(batch []checker.Result) {
+			if ckpt != nil {
+				if err := ckpt.Add(batch); err != nil {
+					utils.Log().Warnf("⚠️  %s\n", err)
 				}
-				if result.Screenshot != "" {
-					if cfg.ScreenshotAlive {
-						if result.Alive {
-							atomic.AddInt32(&screenshotCount, 1)
-						}
-					} else if cfg.Screenshot {
-						atomic.AddInt32(&screenshotCount, 1)
-					}
+			}
+			if jsonl != nil {
+				if err := jsonl.Write(batch); err != nil {
+					utils.Log().Warnf("⚠️  写入JSONL文件失败: %s\n", err)
 				}
-				allResults = append(allResults, result)
 			}
-			resultsMutex.Unlock()
+			if webServer != nil {
+				webServer.Add(batch)
+			}
+		},
+		Hooks: hooks,
+	})
+	processed, alive, protected, dead := aggregator.Counters()
+	reports := []string{}
+
+	// 生成运行结束通知的统计摘要
+	buildSummary := func(stats *view.RunStats, interrupted bool) notify.Summary {
+		return notify.Summary{
+			Name:         arg,
+			Time:         time.Now().Format("2006-01-02 15:04:05"),
+			Interrupted:  interrupted,
+			Total:        stats.Total,
+			Checked:      stats.Checked,
+			Alive:        stats.Alive,
+			Protected:    stats.Protected,
+			Dead:         stats.Dead,
+			Screenshots:  stats.Screenshots,
+			LoginPages:   stats.PageTypes["登录页面"],
+			AdminPages:   stats.PageTypes["管理后台"],
+			Duration:     stats.Duration.Seconds(),
+			Reports:      reports,
+			TopPageTypes: notify.TopPageTypes(stats.PageTypes, 5),
 		}
-	}()
+	}
 
-	go func() {
-		var resultBatch []checker.Result
-		for result := range resultChan {
-			atomic.AddInt32(&processed, 1)
-			resultBatch = append(resultBatch, result)
-			if len(resultBatch) >= batchSize || atomic.LoadInt32(&processed) == int32(totalDomains) {
-				resultBatchChan <- resultBatch
-				resultBatch = nil
+	// 保存未存活的目标，返回是否成功
+	saveFailed := func(results []checker.Result) bool {
+		path, ok := saveOutput(cfg.OutputFailed, "失败目标", func(path string) error {
+			return view.SaveFailedTargets(results, path)
+		})
+		if path != "" {
+			reports = append(reports, path)
+		}
+		return ok
+	}
+
+	// 检测器在中断处理之后创建，统计中（包括中断时）通过它读取自适应并发的调整情况
+	var currentRunner atomic.Pointer[squirrel.Runner]
+
+	// 关闭JSONL输出，返回是否成功；中断处理和正常结束中先调用的一次有效
+	closeJSONL := sync.OnceValue(func() bool {
+		if jsonl == nil {
+			return true
+		}
+		if err := jsonl.Close(); err != nil {
+			utils.Log().Errorf("保存JSONL文件时出错: %s\n", err)
+			return false
+		}
+		utils.Log().Infof("JSONL文件已保存到 %s（%d 行）\n", cfg.JSONLFile, jsonl.Lines())
+		reports = append(reports, cfg.JSONLFile)
+		return true
+	})
+
+	// 汇总统计，截图工作池的统计在停止后读取
+	computeStats := func(results []checker.Result, totalTime time.Duration) *view.RunStats {
+		var shots *screenshot.Stats
+		if screenshotPool != nil {
+			s := screenshotPool.Stats()
+			shots = &s
+		}
+		stats := view.ComputeRunStats(results, totalTargets, cfg.ScreenshotAlive, shots, totalTime)
+		stats.Excluded = len(excluded)
+		stats.Limited, stats.LimitNote = beforeLimit-totalTargets, strings.Join(limitNotes, "，")
+		stats.Throttled = memoryGuard.ThrottledTime()
+		if runner := currentRunner.Load(); runner != nil {
+			if cfg.RatePerHost > 0 {
+				stats.RateGroups = runner.RateLimitGroups()
 			}
+			adaptive := runner.AdaptiveStats()
+			stats.SlowDowns, stats.MinConc = adaptive.SlowDowns, adaptive.MinConcurrency
+			auto := runner.AutoConcurrencyStats()
+			stats.AutoMin, stats.AutoMax, stats.AutoTypical = auto.Min, auto.Max, auto.Typical
 		}
-		if len(resultBatch) > 0 {
-			resultBatchChan <- resultBatch
+		return stats
+	}
+
+	// 保存统计文件，返回是否成功
+	saveStats := func(stats *view.RunStats, meta *view.RunMeta) bool {
+		path, ok := saveOutput(cfg.StatsFile, "统计文件", func(path string) error {
+			return view.SaveStatsFile(stats, path, meta)
+		})
+		if path != "" {
+			reports = append(reports, path)
 		}
-		close(resultBatchChan)
-		close(doneChan)
-	}()
+		return ok
+	}
+
+	// 设置优雅关闭处理器，中断时把已处理部分写入输出文件、失败目标和统计并发送通知，最后关闭Web界面
+	stopShutdownHandler, interrupt := setupGracefulShutdown(screenshotPool, func() {
+		if tui != nil {
+			tui.Stop()
+		}
+		// 交出未凑满一批的结果并删除暂存文件，再取副本：检测goroutine之后添加的结果只保留在内存中，排序也不影响汇总器
+		aggregator.Close()
+		processedResults := aggregator.Snapshot()
+		if ckpt != nil {
+			saveCheckpoint(ckpt)
+		}
+		closeJSONL()
+		if cfg.OutputFailed != "" {
+			saveFailed(processedResults)
+		}
+		totalTime := time.Since(startTime)
+		stats := computeStats(processedResults, totalTime)
+		stats.Partial = true
+		meta := newRunMeta(&cfg, startTime, totalTime, totalTargets)
+		meta.Partial = true
+		// processedResults 是副本，检测goroutine继续追加结果不影响写入；不与基线对比，未检测的目标会被误报为消失
+		outputMutex.Lock()
+		if !outputsWritten {
+			view.SortResults(processedResults, cfg.Sort, cfg.Reverse)
+			written, _ := writeOutputs(&cfg, processedResults, meta, stats, nil, csvFields)
+			reports = append(reports, written...)
+			outputsWritten = true
+		}
+		outputMutex.Unlock()
+		if cfg.StatsFile != "" {
+			saveStats(stats, meta)
+		}
+		aggregator.Complete(buildSummary(stats, true))
+		if webServer != nil {
+			webServer.Shutdown()
+		}
+		diag.Stop()
+	})
 
-	for i := 0; i < cfg.Concurrency; i++ {
-		wg.Add(1)
-		go func(workerId int) {
-			defer wg.Done()
-			for domain := range domainChan {
-				checker.CheckDomain(domain, cfg, resultChan, screenshotPool)
+	// 静默模式输出截图字段时，等截图完成后再输出
+	plainNeedsShot := false
+	for _, field := range plainFields {
+		plainNeedsShot = plainNeedsShot || field.Name == "screenshot"
+	}
+
+	runner, err := squirrel.NewRunner(cfg, squirrel.Options{
+		ScreenshotPool: screenshotPool,
+		Proxies:        cfg.Proxies,
+		MemoryGuard:    memoryGuard,
+		OnResult: func(result checker.Result) {
+			result.Original = originals[result.Input]
+			result.Note = targetNotes[result.Input]
+			if cfg.Silent && result.Alive {
+				if !result.ScreenshotPending || !plainNeedsShot {
+					fmt.Fprintln(plainOut, view.FormatPlain(result, plainFields))
+				}
+			} else if tui != nil {
+				tui.Add(result)
+			} else if cfg.Verbose {
+				view.PrintResult(result, cfg.ShowResponseTime)
+			}
+
+			aggregator.Add(result)
+		},
+		OnScreenshot: func(shot squirrel.Screenshot) {
+			result, ok := aggregator.AddScreenshot(shot)
+			if ok && cfg.Silent && result.Alive && plainNeedsShot {
+				fmt.Fprintln(plainOut, view.FormatPlain(result, plainFields))
 			}
-		}(i)
+		},
+	})
+	if err != nil {
+		fmt.Printf("错误: %s\n", err)
+		// 截图工作池已经启动，退出前停止并清理Chrome进程
+		if screenshotPool != nil {
+			screenshotPool.Stop()
+			cleanupChromeProcesses()
+		}
+		os.Exit(exitUsage)
 	}
-	for _, domain := range domains {
-		domainChan <- domain
+	currentRunner.Store(runner)
+
+	// 暂停或恢复检测，交互式界面中按 p 和 SIGUSR1/SIGUSR2 信号共用
+	setPaused := func(paused bool) {
+		if paused == runner.Paused() {
+			return
+		}
+		if paused {
+			runner.Pause()
+			utils.Log().Infof("⏸️  检测已暂停，进行中的检测会照常完成\n")
+		} else {
+			runner.Resume()
+			utils.Log().Infof("▶️  继续检测\n")
+		}
+		if tui != nil {
+			tui.SetPaused(paused)
+		}
+	}
+
+	// 交互式界面代替进度条，终端不支持时自动改用进度条
+	if cfg.TUI && !cfg.Silent {
+		if view.TUISupported() {
+			controls := view.TUIControls{
+				Pause: setPaused,
+				Quit:  interrupt,
+			}
+			if screenshotPool != nil {
+				controls.QueueLen = func() int {
+					done, total := runner.ScreenshotProgress()
+					return total - done
+				}
+			}
+			if tui, err = view.StartTUI(totalDomains, startTime, controls); err != nil {
+				utils.Log().Warnf("⚠️  %s，使用进度条显示\n", err)
+			}
+		} else {
+			utils.Log().Infof("终端不支持交互式界面，使用进度条显示\n")
+		}
+	}
+	if cfg.Silent || tui != nil {
+		close(progressDone)
+	} else {
+		var shots func() (int, int)
+		if screenshotPool != nil {
+			shots = runner.ScreenshotProgress
+		}
+		go view.ShowProgress("", processed, alive, protected, dead, totalDomains, startTime, runner.Paused, shots, doneChan, progressDone)
+	}
+	// 交互式界面创建后再处理信号，避免与上面对 tui 的赋值竞争
+	stopPauseSignals := notifyPauseSignals(func() { setPaused(true) }, func() { setPaused(false) })
+	diag.reportEvery(cfg.DebugStats, diagnosticsReport(processed, alive, dead, totalDomains, runner, screenshotPool))
+
+	for start := 0; start < len(domains); start += chunkSize {
+		end := min(start+chunkSize, len(domains))
+		runner.Run(context.Background(), domains[start:end])
+
+		// 一块的结果收齐后写入暂存文件并释放，再开始下一块
+		aggregator.Flush()
+		if end < len(domains) {
+			// 可选暂停让连接和Chrome进程回收
+			utils.Log().Debugf("第 %d 块完成 (%d/%d)\n", end/chunkSize, end, len(domains))
+			if cfg.ChunkPause > 0 {
+				time.Sleep(cfg.ChunkPause)
+			}
+		}
+	}
+	// 全部目标检测完成后不再响应暂停，最后一个目标派发后才暂停时恢复截图工作池，以免复查和关闭时阻塞
+	stopPauseSignals()
+	setPaused(false)
+	if tui != nil {
+		tui.Stop()
+	}
+	runner.Close()
+	close(doneChan)
+	<-progressDone
+
+	// 从暂存文件读回所有分块的结果，生成最终输出
+	allResults, _ := aggregator.Close()
+
+	if ckpt != nil {
+		saveCheckpoint(ckpt)
+	}
+
+	// 以较低并发和较长超时复查未存活的目标，截图工作池需在复查结束后再关闭。
+	// 未指定 -recheck-dead 时只复查 -adaptive 记录的失败突发期间超时或连接被拒绝的目标
+	var rechecked int
+	var recovered []checker.Result
+	var only map[string]bool
+	if suspects := runner.AdaptiveStats().Suspects; !cfg.RecheckDead && len(suspects) > 0 {
+		only = make(map[string]bool, len(suspects))
+		for _, input := range suspects {
+			only[input] = true
+		}
+	}
+	if cfg.RecheckDead || only != nil {
+		// 在副本上复查，复查期间中断时写入复查前的结果
+		updated := append([]checker.Result(nil), allResults...)
+		rechecked, recovered = recheckDead(updated, only, cfg, screenshotPool)
+		aggregator.Replace(updated)
+		allResults = updated
+		// 恢复存活的结果追加到JSONL中，同一目标以后出现的行为准
+		if jsonl != nil && len(recovered) > 0 {
+			if err := jsonl.Write(recovered); err != nil {
+				utils.Log().Warnf("⚠️  写入JSONL文件失败: %s\n", err)
+			}
+		}
+		for _, result := range recovered {
+			aggregator.RunHooks(result)
+			if cfg.Silent {
+				fmt.Fprintln(plainOut, view.FormatPlain(result, plainFields))
+			} else if cfg.Verbose {
+				view.PrintResult(result, cfg.ShowResponseTime)
+			}
+		}
 	}
-	close(domainChan)
-	wg.Wait()
 
 	// 在所有域名检查完成后，关闭截图工作池
 	if screenshotPool != nil {
-		fmt.Printf("📸 正在停止截图工作池...\n")
+		utils.Log().Infof("📸 正在停止截图工作池...\n")
 		screenshotPool.Stop()
 	}
 
-	close(resultChan)
-	<-doneChan
-	<-progressDone
-
 	// 程序正常结束时清理资源
 	if cfg.Screenshot || cfg.ScreenshotAlive {
 		cleanupChromeProcesses()
 	}
 
-	fmt.Printf("\r%-80s\r", " ")
+	if !cfg.Silent && utils.IsTerminal(os.Stdout) {
+		fmt.Printf("\r%-80s\r", " ")
+	}
 	totalTime := time.Since(startTime)
-	view.PrintSummary(len(domains), int(atomic.LoadInt32(&alive)), int(atomic.LoadInt32(&dead)), &cfg, pageTypeCount, &pageTypeCountMutex, atomic.LoadInt32(&screenshotCount), totalTime)
 
-	if cfg.OutputFile != "" {
-		err := view.SaveResultsToFile(allResults, cfg.OutputFile)
-		if err != nil {
-			fmt.Printf("保存结果到文件时出错: %s\n", err)
+	// 运行元数据只生成一次，所有输出使用相同的值
+	meta := newRunMeta(&cfg, startTime, totalTime, totalTargets)
+
+	// 按指定字段排序，所有输出使用相同的顺序
+	aggregator.Update(func(results []checker.Result) {
+		view.SortResults(results, cfg.Sort, cfg.Reverse)
+	})
+	stats := computeStats(allResults, totalTime)
+	stats.Rechecked, stats.Recovered = rechecked, len(recovered)
+	// 静默模式下标准输出只有存活的URL，总结和对比结果只写入输出文件
+	if !cfg.Silent {
+		view.PrintSummary(stats, &cfg, allResults)
+	}
+	recordHistory(&cfg, history.NewRun(scope, arg, meta, stats))
+
+	// 与基线对比
+	var diff *view.Diff
+	if cfg.DiffBaseline != "" {
+		diff = view.DiffResults(allResults, diffBaseline, cfg.DiffBaseline)
+		if !cfg.Silent {
+			view.PrintDiff(diff)
+		}
+	}
+
+	exitCode := exitOK
+	if !closeJSONL() {
+		exitCode = exitOutputFailed
+	}
+	outputMutex.Lock()
+	written, ok := writeOutputs(&cfg, allResults, meta, stats, diff, csvFields)
+	reports = append(reports, written...)
+	outputsWritten = true
+	outputMutex.Unlock()
+	if !ok {
+		exitCode = exitOutputFailed
+	}
+	// 监控模式下把本轮完整结果交给父进程对比
+	if path := os.Getenv(monitorResultsEnv); path != "" {
+		if err := view.SaveResultsToJSON(allResults, path, view.ExportFilter{}, meta); err != nil {
+			utils.Log().Errorf("保存本轮监控结果时出错: %s\n", err)
+			exitCode = exitOutputFailed
+		}
+	}
+
+	if cfg.OutputFailed != "" && !saveFailed(allResults) {
+		exitCode = exitOutputFailed
+	}
+	if cfg.StatsFile != "" && !saveStats(stats, meta) {
+		exitCode = exitOutputFailed
+	}
+
+	if cfg.OutputAll != "" && len(reports) > 0 {
+		utils.Log().Infof("📁 已生成 %d 个文件: %s\n", len(reports), strings.Join(reports, ", "))
+	}
+
+	aggregator.Complete(buildSummary(stats, false))
+	diag.Stop()
+
+	// 输出全部写入后，Web界面切换为最终结果并提供文件下载，直到按 Ctrl+C 再退出
+	if webServer != nil {
+		stopShutdownHandler()
+		webServer.Finish(allResults, reports)
+		serveUntilInterrupt(webServer)
+	}
+
+	// 输出文件写入失败时优先返回 exitOutputFailed
+	if exitCode == exitOK {
+		if n := stats.Alive; cfg.FailOnAlive && n > 0 {
+			utils.Log().Warnf("发现 %d 个存活主机 (-fail-on-alive)\n", n)
+			exitCode = exitFound
+		}
+		if cfg.FailOnNew != "" {
+			if newly := view.NewlyAlive(allResults, baseline); len(newly) > 0 {
+				utils.Log().Warnf("相比基线新增 %d 个存活主机 (-fail-on-new):\n", len(newly))
+				for _, result := range newly {
+					utils.Log().Warnf("  %s\n", result.Domain)
+				}
+				exitCode = exitFound
+			}
+		}
+	}
+	if exitCode != exitOK {
+		os.Exit(exitCode)
+	}
+}
+
+// 使用共同的文件名前缀输出所有格式（单独指定的格式参数优先），并按 -compress 为CSV和JSON输出追加 .gz
+func resolveOutputs(cfg *config.Config) {
+	if cfg.OutputAll != "" {
+		if cfg.OutputFile == "" {
+			cfg.OutputFile = cfg.OutputAll + ".csv"
+		}
+		if cfg.ExcelFile == "" {
+			cfg.ExcelFile = cfg.OutputAll + ".xlsx"
+		}
+		if cfg.HTMLFile == "" {
+			cfg.HTMLFile = cfg.OutputAll + ".html"
+		}
+		if cfg.JSONFile == "" {
+			cfg.JSONFile = cfg.OutputAll + ".json"
+		}
+	}
+
+	// Excel和HTML本身已是压缩或独立的格式，不做处理
+	if cfg.Compress {
+		if cfg.OutputFile != "" && !strings.HasSuffix(cfg.OutputFile, ".gz") {
+			cfg.OutputFile += ".gz"
+		}
+		if cfg.JSONFile != "" && !strings.HasSuffix(cfg.JSONFile, ".gz") {
+			cfg.JSONFile += ".gz"
+		}
+	}
+}
+
+// 写入CSV、Excel、JSON和HTML输出，返回已生成的文件和是否全部写入成功
+func writeOutputs(cfg *config.Config, results []checker.Result, meta *view.RunMeta, stats *view.RunStats, diff *view.Diff, csvFields []view.Field) ([]string, bool) {
+	var written []string
+	ok := true
+	exportFilter := view.ExportFilter{OnlyAlive: cfg.OnlyAlive, IncludeProtected: cfg.IncludeProtected}
+	save := func(filename, label string, write func(path string) error) {
+		if filename == "" {
+			return
+		}
+		path, saved := saveOutput(filename, label, write)
+		if path != "" {
+			written = append(written, path)
+		}
+		ok = ok && saved
+	}
+
+	save(cfg.OutputFile, "CSV文件", func(path string) error {
+		return view.SaveResultsToFile(results, path, exportFilter, meta, csvFields)
+	})
+	save(cfg.ExcelFile, "Excel文件", func(path string) error {
+		return view.SaveResultsToExcel(results, path, cfg, meta, stats, diff)
+	})
+	save(cfg.JSONFile, "JSON文件", func(path string) error {
+		return view.SaveResultsToJSON(results, path, exportFilter, meta)
+	})
+	save(cfg.HTMLFile, "HTML报告", func(path string) error {
+		return view.SaveResultsToHTML(results, path, exportFilter, meta, stats.StatusCounts, diff, cfg.Top)
+	})
+	save(cfg.SimpleHTMLFile, "简化版HTML报告", func(path string) error {
+		return view.SaveResultsToSimpleHTML(results, path, exportFilter, meta, stats.StatusCounts, diff)
+	})
+	// SQLite数据库追加写入已有的文件，不能先写临时文件再替换；写入在一个事务中完成，失败时数据库保持不变
+	if cfg.SQLiteFile != "" {
+		if err := view.SaveResultsToSQLite(results, cfg.SQLiteFile, exportFilter, meta); err != nil {
+			utils.Log().Errorf("保存SQLite数据库时出错: %s\n", err)
+			ok = false
 		} else {
-			fmt.Printf("结果已保存到 %s\n", cfg.OutputFile)
+			utils.Log().Infof("SQLite数据库已保存到 %s\n", cfg.SQLiteFile)
+			written = append(written, cfg.SQLiteFile)
 		}
 	}
-	if cfg.ExcelFile != "" {
-		err := view.SaveResultsToExcel(allResults, cfg.ExcelFile, cfg.OnlyAlive)
-		if err != nil {
-			fmt.Printf("保存结果到Excel文件时出错: %s\n", err)
+
+	// 有输出没能保存到指定位置时，把全部结果另存一份JSON，避免长时间检测的结果丢失
+	if !ok {
+		if path, err := view.DumpResults(results, meta); err != nil {
+			utils.Log().Errorf("保存结果备份时出错: %s\n", err)
 		} else {
-			fmt.Printf("结果已保存到 %s\n", cfg.ExcelFile)
+			utils.Log().Errorf("‼️  全部结果已备份到 %s，可用 report 子命令从中重新生成报告\n", path)
 		}
 	}
-	if htmlOutput != "" {
-		err := view.SaveResultsToHTML(allResults, htmlOutput, cfg.OnlyAlive)
+	return written, ok
+}
+
+// 保存一个输出文件（原子写入，失败时重试并改存到系统临时目录），返回实际保存的路径（两处都失败时为空）
+// 和是否保存到了指定位置
+func saveOutput(filename, label string, write func(path string) error) (string, bool) {
+	path, err := view.SaveOutput(filename, write)
+	switch {
+	case path == "":
+		utils.Log().Errorf("保存%s时出错: %s\n", label, err)
+		return "", false
+	case err != nil:
+		utils.Log().Errorf("‼️  无法保存%s到 %s: %s\n", label, filename, err)
+		utils.Log().Errorf("‼️  %s已改为保存到 %s\n", label, path)
+		return path, false
+	}
+	utils.Log().Infof("%s已保存到 %s\n", label, path)
+	return path, true
+}
+
+// 从保存的结果文件重新生成报告（report 子命令），不发起任何网络请求
+func runReport(cfg *config.Config, csvFields []view.Field) int {
+	if !hasReportOutput(cfg) {
+		fmt.Println("错误: report 需要至少指定一个输出（-output、-excel、-json、-html、-simple-html、-sqlite、-o、-output-failed 或 -stats-file）")
+		return exitUsage
+	}
+
+	results, meta, err := view.LoadReport(cfg.From, cfg.InputFormat)
+	if err != nil {
+		fmt.Printf("错误: 无法读取结果文件: %s\n", err)
+		return exitUsage
+	}
+	utils.Log().Infof("从 %s 读取了 %d 条结果\n", cfg.From, len(results))
+	return writeReport(cfg, results, meta, csvFields)
+}
+
+// 合并多个结果文件（merge 子命令），按主机去重后生成报告，用于汇总 -shard 各分片的输出
+func runMerge(cfg *config.Config, csvFields []view.Field) int {
+	if !hasReportOutput(cfg) {
+		fmt.Println("错误: merge 需要至少指定一个输出（-output、-excel、-json、-html、-simple-html、-sqlite、-o、-output-failed 或 -stats-file）")
+		return exitUsage
+	}
+
+	sets := make([][]checker.Result, 0, len(cfg.MergeFrom))
+	metas := make([]*view.RunMeta, 0, len(cfg.MergeFrom))
+	count := 0
+	for _, filename := range cfg.MergeFrom {
+		results, meta, err := view.LoadReport(filename, cfg.InputFormat)
 		if err != nil {
-			fmt.Printf("保存结果到HTML文件时出错: %s\n", err)
-		} else {
-			fmt.Printf("HTML报告已保存到 %s\n", htmlOutput)
+			fmt.Printf("错误: 无法读取结果文件 %s: %s\n", filename, err)
+			return exitUsage
 		}
+		utils.Log().Infof("从 %s 读取了 %d 条结果\n", filename, len(results))
+		sets = append(sets, results)
+		metas = append(metas, meta)
+		count += len(results)
+	}
+	for _, problem := range view.CheckShards(metas) {
+		utils.Log().Warnf("⚠️  %s\n", problem)
+	}
+
+	results := view.MergeResults(sets...)
+	meta := view.MergeMeta(metas)
+	if meta != nil && meta.Targets < len(results) {
+		meta.Targets = len(results)
+	}
+	utils.Log().Infof("合并 %d 个文件: 共 %d 条结果，去重后 %d 条\n", len(cfg.MergeFrom), count, len(results))
+	return writeReport(cfg, results, meta, csvFields)
+}
+
+// 是否指定了 report/merge 可生成的输出
+func hasReportOutput(cfg *config.Config) bool {
+	resolveOutputs(cfg)
+	return cfg.OutputFile != "" || cfg.ExcelFile != "" || cfg.JSONFile != "" || cfg.HTMLFile != "" ||
+		cfg.SimpleHTMLFile != "" || cfg.SQLiteFile != "" || cfg.OutputFailed != "" || cfg.StatsFile != ""
+}
+
+// 从已读取的结果生成总结和报告（report 和 merge 子命令），meta 为nil时以本次生成报告的信息代替
+func writeReport(cfg *config.Config, results []checker.Result, meta *view.RunMeta, csvFields []view.Field) int {
+	// 只有JSON结果带有原始运行的元数据，其余格式以本次生成报告的信息代替
+	total := len(results)
+	if meta == nil {
+		meta = newRunMeta(cfg, time.Now(), 0, total)
+	} else if meta.Targets > total {
+		total = meta.Targets
 	}
-	if simpleHTML != "" {
-		err := view.SaveResultsToSimpleHTML(allResults, simpleHTML, cfg.OnlyAlive)
+
+	var diff *view.Diff
+	if cfg.DiffBaseline != "" {
+		baseline, err := view.LoadBaseline(cfg.DiffBaseline)
 		if err != nil {
-			fmt.Printf("保存结果到简化版HTML文件时出错: %s\n", err)
+			fmt.Printf("错误: 无法读取基线文件: %s\n", err)
+			return exitUsage
+		}
+		diff = view.DiffResults(results, baseline, cfg.DiffBaseline)
+	}
+
+	view.SortResults(results, cfg.Sort, cfg.Reverse)
+	stats := view.ComputeRunStats(results, total, cfg.ScreenshotAlive, nil, meta.Duration())
+	if !cfg.Silent {
+		view.PrintSummary(stats, cfg, results)
+		if diff != nil {
+			view.PrintDiff(diff)
+		}
+	}
+
+	exitCode := exitOK
+	if _, ok := writeOutputs(cfg, results, meta, stats, diff, csvFields); !ok {
+		exitCode = exitOutputFailed
+	}
+	if cfg.OutputFailed != "" {
+		if _, ok := saveOutput(cfg.OutputFailed, "失败目标", func(path string) error {
+			return view.SaveFailedTargets(results, path)
+		}); !ok {
+			exitCode = exitOutputFailed
+		}
+	}
+	if cfg.StatsFile != "" {
+		if _, ok := saveOutput(cfg.StatsFile, "统计文件", func(path string) error {
+			return view.SaveStatsFile(stats, path, meta)
+		}); !ok {
+			exitCode = exitOutputFailed
+		}
+	}
+	return exitCode
+}
+
+// 对比两个保存的结果文件（diff 子命令），不发起任何网络请求。
+// 指定 -fail-on-alive 时有新存活的主机以退出码 4 结束
+func runDiff(cfg *config.Config) int {
+	baseline, err := view.LoadBaseline(cfg.DiffBaseline)
+	if err != nil {
+		fmt.Printf("错误: 无法读取基线文件: %s\n", err)
+		return exitUsage
+	}
+	results, err := view.LoadResults(cfg.From, cfg.InputFormat)
+	if err != nil {
+		fmt.Printf("错误: 无法读取结果文件: %s\n", err)
+		return exitUsage
+	}
+	diff := view.DiffResults(results, baseline, cfg.DiffBaseline)
+	view.PrintDiff(diff)
+	if n := len(view.NewlyAlive(results, baseline)); cfg.FailOnAlive && n > 0 {
+		utils.Log().Warnf("相比基线新增 %d 个存活主机 (-fail-on-alive)\n", n)
+		return exitFound
+	}
+	return exitOK
+}
+
+// 运行结束或中断时发送通知的结果钩子，监控模式由父进程在有变化时统一发送
+type notifyHook struct {
+	cfg *config.Config
+}
+
+func (h notifyHook) OnResult(checker.Result) {}
+
+func (h notifyHook) OnComplete(summary notify.Summary) {
+	if !isMonitorCycle() {
+		sendNotifications(h.cfg, summary)
+	}
+}
+
+// 发送运行结束通知
+func sendNotifications(cfg *config.Config, summary notify.Summary) {
+	if cfg.Webhook != "" {
+		if err := notify.SendWebhook(cfg.Webhook, cfg.WebhookHeaders, summary); err != nil {
+			utils.Log().Errorf("⚠️  发送Webhook通知失败: %s\n", err)
+		} else {
+			utils.Log().Infof("📨 已发送Webhook通知\n")
+		}
+	}
+	for _, target := range cfg.Notify {
+		provider, _, _ := notify.ParseTarget(target)
+		if err := notify.Send(target, cfg.DingTalkSecret, summary); err != nil {
+			utils.Log().Errorf("⚠️  发送%s通知失败: %s\n", provider, err)
 		} else {
-			fmt.Printf("简化版HTML报告已保存到 %s\n", simpleHTML)
+			utils.Log().Infof("📨 已发送%s通知\n", provider)
 		}
 	}
 }
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"subdomain-checker/checker"
+	"subdomain-checker/config"
+	"subdomain-checker/utils"
+	"subdomain-checker/view"
+)
+
+// 写入失败的输出会改存到系统临时目录（每个进程只创建一次），测试期间指向单独的目录
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "squirrel-test-")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("TMPDIR", dir)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// 测试期间不在终端输出日志，返回记录到的错误日志
+func captureErrors(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	logs := &bytes.Buffer{}
+	utils.SetLogger(utils.NewFileLogger(utils.LevelError+4, logs, utils.LevelError, false))
+	t.Cleanup(func() { utils.SetLogger(utils.NewLogger(utils.LevelInfo)) })
+	return logs
+}
+
+// 测试用的结果：存活、受保护和无法访问各一个
+func sampleResults() []checker.Result {
+	return []checker.Result{
+		{Domain: "https://www.example.com", Input: "www.example.com", Status: 200, Alive: true, StatusText: "存活", Title: "Home", BodySize: 1024},
+		{Domain: "https://admin.example.com", Input: "admin.example.com", Status: 403, StatusText: "受保护", BodySize: -1},
+		{Domain: "dead.example.com", Input: "dead.example.com", StatusText: "无法访问", ErrorClass: checker.ErrorTimeout, BodySize: -1},
+	}
+}
+
+// 测试用的运行元数据
+func sampleMeta() *view.RunMeta {
+	return &view.RunMeta{Version: "test", StartTime: time.Unix(0, 0), EndTime: time.Unix(60, 0), Targets: len(sampleResults())}
+}
+
+// -compress 的CSV和JSON输出解压后与不压缩的输出逐字节相同
+func TestWriteOutputsCompress(t *testing.T) {
+	dir := t.TempDir()
+	results := sampleResults()
+	meta := sampleMeta()
+	stats := view.ComputeRunStats(results, len(results), false, nil, time.Minute)
+
+	plain := &config.Config{OutputAll: filepath.Join(dir, "plain")}
+	resolveOutputs(plain)
+	plain.ExcelFile, plain.HTMLFile = "", ""
+	compressed := &config.Config{OutputAll: filepath.Join(dir, "compressed"), Compress: true}
+	resolveOutputs(compressed)
+	compressed.ExcelFile, compressed.HTMLFile = "", ""
+
+	for _, cfg := range []*config.Config{plain, compressed} {
+		if _, ok := writeOutputs(cfg, results, meta, stats, nil, nil); !ok {
+			t.Fatalf("写入 %s 失败", cfg.OutputAll)
+		}
+	}
+
+	for _, pair := range [][2]string{{plain.OutputFile, compressed.OutputFile}, {plain.JSONFile, compressed.JSONFile}} {
+		if filepath.Ext(pair[1]) != ".gz" {
+			t.Fatalf("压缩输出的文件名为 %s", pair[1])
+		}
+		want, err := os.ReadFile(pair[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		file, err := os.Open(pair[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			t.Fatalf("%s 不是gzip文件: %v", pair[1], err)
+		}
+		got, err := io.ReadAll(gz)
+		file.Close()
+		if err != nil {
+			t.Fatalf("解压 %s 失败: %v", pair[1], err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s 解压后与 %s 不同", pair[1], pair[0])
+		}
+	}
+}
+
+// 输出目录不可写时报告错误、改存到临时目录并以 exitOutputFailed 退出，而不是panic
+func TestWriteReportUnwritable(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "results.csv")
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		// 只读目录对root和Windows不生效，改用普通文件作为父目录
+		blocker := filepath.Join(dir, "file")
+		if err := os.WriteFile(blocker, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+		target = filepath.Join(blocker, "results.csv")
+	} else {
+		if err := os.Chmod(dir, 0555); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.Chmod(dir, 0755) })
+	}
+	logs := captureErrors(t)
+
+	cfg := &config.Config{OutputFile: target, Silent: true}
+	if code := writeReport(cfg, sampleResults(), nil, nil); code != exitOutputFailed {
+		t.Errorf("退出码为 %d，应为 %d", code, exitOutputFailed)
+	}
+	if _, err := os.Stat(target); err == nil {
+		t.Errorf("%s 不应被写入", target)
+	}
+	if !strings.Contains(logs.String(), "无法保存CSV文件到 "+target) {
+		t.Errorf("没有报告写入失败，日志为:\n%s", logs.String())
+	}
+}
+
+// 同一组结果写入的每种输出行数相同，并且都遵守 -only-alive 和 -include-protected
+func TestWriteOutputsAgree(t *testing.T) {
+	tests := []struct {
+		name             string
+		onlyAlive        bool
+		includeProtected bool
+		want             int
+	}{
+		{"全部结果", false, false, 3},
+		{"只导出存活", true, false, 1},
+		{"保留受保护", true, true, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captureErrors(t)
+			cfg := &config.Config{OutputAll: filepath.Join(t.TempDir(), "out"), OnlyAlive: tt.onlyAlive, IncludeProtected: tt.includeProtected}
+			resolveOutputs(cfg)
+			cfg.SimpleHTMLFile = cfg.OutputAll + "-simple.html"
+			results := sampleResults()
+			stats := view.ComputeRunStats(results, len(results), false, nil, time.Minute)
+			written, ok := writeOutputs(cfg, results, sampleMeta(), stats, nil, nil)
+			if !ok || len(written) != 5 {
+				t.Fatalf("写入 %v，ok=%v", written, ok)
+			}
+			for _, filename := range []string{cfg.OutputFile, cfg.ExcelFile, cfg.JSONFile} {
+				if got := loadedRows(t, filename); got != tt.want {
+					t.Errorf("%s 有 %d 行结果，应为 %d", filepath.Base(filename), got, tt.want)
+				}
+			}
+			for _, filename := range []string{cfg.HTMLFile, cfg.SimpleHTMLFile} {
+				if got := htmlRows(t, filename); got != tt.want {
+					t.Errorf("%s 有 %d 个结果，应为 %d", filepath.Base(filename), got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// 一种输出写入失败时其余输出照常写入全部结果，失败向调用方返回
+func TestWriteOutputsPropagatesError(t *testing.T) {
+	logs := captureErrors(t)
+	dir := t.TempDir()
+	blocker := filepath.Join(dir, "file")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		OutputFile: filepath.Join(dir, "results.csv"),
+		ExcelFile:  filepath.Join(blocker, "results.xlsx"),
+		JSONFile:   filepath.Join(dir, "results.json"),
+		HTMLFile:   filepath.Join(dir, "report.html"),
+	}
+	results := sampleResults()
+	stats := view.ComputeRunStats(results, len(results), false, nil, time.Minute)
+	if _, ok := writeOutputs(cfg, results, sampleMeta(), stats, nil, nil); ok {
+		t.Error("Excel文件写入失败时应返回失败")
+	}
+	if !strings.Contains(logs.String(), "无法保存Excel文件到 "+cfg.ExcelFile) {
+		t.Errorf("没有报告写入失败，日志为:\n%s", logs.String())
+	}
+	for _, filename := range []string{cfg.OutputFile, cfg.JSONFile} {
+		if got := loadedRows(t, filename); got != len(results) {
+			t.Errorf("%s 有 %d 行结果，应为 %d", filepath.Base(filename), got, len(results))
+		}
+	}
+	if got := htmlRows(t, cfg.HTMLFile); got != len(results) {
+		t.Errorf("HTML报告有 %d 个结果，应为 %d", got, len(results))
+	}
+}
+
+// 读回结果文件中的结果数
+func loadedRows(t *testing.T, filename string) int {
+	t.Helper()
+	results, err := view.LoadResults(filename, view.InputAuto)
+	if err != nil {
+		t.Fatalf("读取 %s 失败: %v", filename, err)
+	}
+	return len(results)
+}
+
+// HTML报告中的结果卡片数
+func htmlRows(t *testing.T, filename string) int {
+	t.Helper()
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(data), `<div class="domain-card `)
+}
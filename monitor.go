@@ -0,0 +1,205 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"subdomain-checker/checker"
+	"subdomain-checker/config"
+	"subdomain-checker/notify"
+	"subdomain-checker/utils"
+	"subdomain-checker/view"
+)
+
+// 监控模式下每一轮检测在子进程中运行，保证轮次之间内存完全释放、Chrome进程完全退出。
+// 父进程通过环境变量告诉子进程本轮的时间戳和结果文件
+const (
+	monitorCycleEnv    = "SQUIRREL_MONITOR_CYCLE"    // 本轮的时间戳，子进程据此为输出文件名加后缀
+	monitorResultsEnv  = "SQUIRREL_MONITOR_RESULTS"  // 子进程写入本轮完整结果(JSON)的文件，供父进程对比
+	monitorBaselineEnv = "SQUIRREL_MONITOR_BASELINE" // 上一轮的结果文件，子进程将其作为 -diff 基线
+)
+
+// 监控模式下输出文件名使用的时间戳格式
+const monitorStampFormat = "20060102-150405"
+
+// 当前进程是否为监控模式中的一轮检测
+func isMonitorCycle() bool {
+	return os.Getenv(monitorCycleEnv) != ""
+}
+
+// 在文件名的扩展名之前插入时间戳，如 results.csv.gz -> results-20060102-150405.csv.gz
+func stampPath(path, stamp string) string {
+	if path == "" {
+		return ""
+	}
+	dir, base := filepath.Split(path)
+	name, ext := base, ""
+	if i := strings.Index(base, "."); i > 0 {
+		name, ext = base[:i], base[i:]
+	}
+	return dir + name + "-" + stamp + ext
+}
+
+// 为一轮检测的所有输出文件名加上时间戳，避免覆盖之前的输出
+func stampOutputs(cfg *config.Config, stamp string) {
+	for _, path := range []*string{
+		&cfg.OutputFile, &cfg.ExcelFile, &cfg.JSONFile, &cfg.JSONLFile, &cfg.HTMLFile, &cfg.SimpleHTMLFile,
+		&cfg.OutputFailed, &cfg.StatsFile, &cfg.ExcludedOutput,
+	} {
+		*path = stampPath(*path, stamp)
+	}
+}
+
+// 监控模式：按 -interval 周期在子进程中重复检测，与上一轮对比，有变化时发送通知。
+// 收到 SIGTERM 或 Ctrl+C 时在当前一轮结束后停止并打印总结
+func runMonitor(cfg *config.Config) int {
+	if cfg.Input == "" || cfg.Input == "-" {
+		fmt.Println("错误: 监控模式需要指定目标文件或域名列表，不能从标准输入读取")
+		return exitUsage
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Printf("错误: 无法获取程序路径: %s\n", err)
+		return exitUsage
+	}
+	stateDir, err := os.MkdirTemp("", "squirrel-monitor-*")
+	if err != nil {
+		fmt.Printf("错误: 无法创建监控状态目录: %s\n", err)
+		return exitUsage
+	}
+	defer os.RemoveAll(stateDir)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, shutdownSignals...)
+	stopping := false
+
+	_, args := config.SplitCommand(os.Args[1:])
+	utils.Log().Infof("👀 监控模式: 每 %s 检测一次 %s，按 Ctrl+C 或发送 SIGTERM 在当前一轮结束后停止\n", cfg.Interval, cfg.Input)
+
+	var previous []checker.Result
+	var previousPath string
+	var previousTime time.Time
+	cycles, changedCycles := 0, 0
+	exitCode := exitOK
+	for !stopping {
+		cycles++
+		now := time.Now()
+		stamp := now.Format(monitorStampFormat)
+		resultsPath := filepath.Join(stateDir, fmt.Sprintf("cycle-%d.json", cycles))
+		utils.Log().Infof("🔁 第 %d 轮检测开始 (%s)\n", cycles, now.Format("2006-01-02 15:04:05"))
+
+		// 上一轮的结果作为本轮的 -diff 基线，变化会写入本轮的Excel和HTML报告
+		cmd := exec.Command(exe, append([]string{config.CommandScan}, args...)...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		cmd.Env = append(os.Environ(), monitorCycleEnv+"="+stamp, monitorResultsEnv+"="+resultsPath, monitorBaselineEnv+"="+previousPath)
+		if err := cmd.Start(); err != nil {
+			utils.Log().Errorf("启动第 %d 轮检测失败: %s\n", cycles, err)
+			exitCode = exitUsage
+			break
+		}
+		// 等待本轮结束，期间收到的停止信号只做记录
+		done := make(chan error, 1)
+		go func() { done <- cmd.Wait() }()
+		var waitErr error
+	wait:
+		for {
+			select {
+			case <-stop:
+				if !stopping {
+					utils.Log().Infof("🛑 收到停止信号，将在第 %d 轮检测结束后停止\n", cycles)
+				}
+				stopping = true
+			case waitErr = <-done:
+				break wait
+			}
+		}
+
+		var exitErr *exec.ExitError
+		if errors.As(waitErr, &exitErr) {
+			switch exitErr.ExitCode() {
+			case exitUsage:
+				utils.Log().Errorf("第 %d 轮检测出错，停止监控\n", cycles)
+				exitCode = exitUsage
+				stopping = true
+			case exitInterrupted:
+				utils.Log().Warnf("第 %d 轮检测被中断\n", cycles)
+				stopping = true
+			}
+		}
+
+		current, err := view.LoadResults(resultsPath, view.InputJSON)
+		if err != nil {
+			// 本轮没有完整结果（如被中断），保留上一轮作为基线
+			utils.Log().Warnf("第 %d 轮没有完整的结果，跳过对比: %s\n", cycles, err)
+		} else {
+			if previousPath == "" {
+				utils.Log().Infof("📌 第 %d 轮结果作为之后对比的基线\n", cycles)
+			} else {
+				diff := view.DiffResults(current, previous, "上一轮 ("+previousTime.Format("2006-01-02 15:04:05")+")")
+				if len(diff.Entries) == 0 {
+					utils.Log().Infof("✅ 第 %d 轮与上一轮相比没有变化\n", cycles)
+				} else {
+					changedCycles++
+					utils.Log().Infof("🔔 第 %d 轮发现变化: %s\n", cycles, diff.Summary())
+					sendNotifications(cfg, monitorSummary(cfg, current, diff, now))
+				}
+				os.Remove(previousPath)
+			}
+			previous, previousPath, previousTime = current, resultsPath, now
+		}
+
+		if stopping {
+			break
+		}
+		next := now.Add(cfg.Interval)
+		utils.Log().Infof("⏰ 下一轮检测时间: %s\n", next.Format("2006-01-02 15:04:05"))
+		select {
+		case <-time.After(time.Until(next)):
+		case <-stop:
+			stopping = true
+		}
+	}
+
+	fmt.Printf("\n监控结束: 共运行 %d 轮，其中 %d 轮发现变化\n", cycles, changedCycles)
+	if previous != nil {
+		alive := 0
+		for _, result := range previous {
+			if result.Alive {
+				alive++
+			}
+		}
+		fmt.Printf("最近一轮 (%s): %d 个目标, %d 个存活\n", previousTime.Format("2006-01-02 15:04:05"), len(previous), alive)
+	}
+	return exitCode
+}
+
+// 生成监控模式的变化通知
+func monitorSummary(cfg *config.Config, results []checker.Result, diff *view.Diff, start time.Time) notify.Summary {
+	stats := view.ComputeRunStats(results, len(results), cfg.ScreenshotAlive, nil, time.Since(start))
+	changes := make([]string, len(diff.Entries))
+	for i, entry := range diff.Entries {
+		changes[i] = fmt.Sprintf("[%s] %s: %s -> %s", entry.Kind, entry.Domain, entry.Before, entry.After)
+	}
+	return notify.Summary{
+		Name:         cfg.Input,
+		Time:         time.Now().Format("2006-01-02 15:04:05"),
+		Total:        stats.Total,
+		Checked:      stats.Checked,
+		Alive:        stats.Alive,
+		Protected:    stats.Protected,
+		Dead:         stats.Dead,
+		Screenshots:  stats.Screenshots,
+		LoginPages:   stats.PageTypes["登录页面"],
+		AdminPages:   stats.PageTypes["管理后台"],
+		Duration:     stats.Duration.Seconds(),
+		TopPageTypes: notify.TopPageTypes(stats.PageTypes, 5),
+		Changes:      diff.Summary(),
+		ChangeList:   changes,
+	}
+}
@@ -0,0 +1,174 @@
+package notify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 支持的即时通讯机器人类型
+var Providers = []string{"dingtalk", "feishu", "slack"}
+
+// 解析 -notify 参数，格式为 "类型:Webhook地址"
+func ParseTarget(spec string) (provider, webhookURL string, err error) {
+	provider, webhookURL, ok := strings.Cut(spec, ":")
+	if !ok || webhookURL == "" {
+		return "", "", fmt.Errorf("无效的通知目标: %s (格式应为 类型:地址，如 dingtalk:https://...)", spec)
+	}
+	provider = strings.ToLower(provider)
+	for _, p := range Providers {
+		if provider == p {
+			return provider, webhookURL, nil
+		}
+	}
+	return "", "", fmt.Errorf("不支持的通知类型: %s (可选: %s)", provider, strings.Join(Providers, ", "))
+}
+
+// 发送摘要卡片到指定的机器人，secret 仅用于钉钉加签
+func Send(spec, secret string, summary Summary) error {
+	provider, webhookURL, err := ParseTarget(spec)
+	if err != nil {
+		return err
+	}
+
+	var payload interface{}
+	switch provider {
+	case "dingtalk":
+		payload = DingTalkPayload(summary)
+		if secret != "" {
+			webhookURL = SignDingTalkURL(webhookURL, secret, time.Now())
+		}
+	case "feishu":
+		payload = FeishuPayload(summary)
+	case "slack":
+		payload = SlackPayload(summary)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("序列化通知内容失败: %v", err)
+	}
+	return post(webhookURL, nil, body)
+}
+
+// 通知卡片标题
+func cardTitle(summary Summary) string {
+	title := "松鼠子域名检测完成"
+	if summary.Interrupted {
+		title = "松鼠子域名检测已中断"
+	} else if summary.Changes != "" {
+		title = "松鼠子域名监控发现变化"
+	}
+	if summary.Name != "" {
+		title += ": " + summary.Name
+	}
+	return title
+}
+
+// 通知卡片中最多列出的变化明细条数
+const cardMaxChanges = 10
+
+// 通知卡片正文，每项一行
+func cardLines(summary Summary) []string {
+	var lines []string
+	if summary.Changes != "" {
+		lines = append(lines, "变化: "+summary.Changes)
+		for i, change := range summary.ChangeList {
+			if i == cardMaxChanges {
+				lines = append(lines, fmt.Sprintf("... 另有 %d 项变化", len(summary.ChangeList)-cardMaxChanges))
+				break
+			}
+			lines = append(lines, change)
+		}
+	}
+	lines = append(lines,
+		fmt.Sprintf("检测域名: %d/%d", summary.Checked, summary.Total),
+		fmt.Sprintf("存活: %d, 受保护: %d, 无法访问: %d", summary.Alive, summary.Protected, summary.Dead),
+		fmt.Sprintf("登录页面: %d, 管理后台: %d", summary.LoginPages, summary.AdminPages),
+		fmt.Sprintf("耗时: %.1f 秒", summary.Duration),
+	)
+	if summary.Screenshots > 0 {
+		lines = append(lines, fmt.Sprintf("截图: %d", summary.Screenshots))
+	}
+	if len(summary.Reports) > 0 {
+		lines = append(lines, "报告文件: "+strings.Join(summary.Reports, ", "))
+	}
+	return lines
+}
+
+// 钉钉 markdown 消息
+func DingTalkPayload(summary Summary) map[string]interface{} {
+	title := cardTitle(summary)
+	text := "### " + title + "\n\n- " + strings.Join(cardLines(summary), "\n- ")
+	return map[string]interface{}{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"title": title,
+			"text":  text,
+		},
+	}
+}
+
+// 为钉钉加签机器人的地址附加 timestamp 和 sign 参数
+func SignDingTalkURL(webhookURL, secret string, now time.Time) string {
+	timestamp := strconv.FormatInt(now.UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + secret))
+	sign := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	sep := "?"
+	if strings.Contains(webhookURL, "?") {
+		sep = "&"
+	}
+	return webhookURL + sep + "timestamp=" + timestamp + "&sign=" + url.QueryEscape(sign)
+}
+
+// 飞书消息卡片
+func FeishuPayload(summary Summary) map[string]interface{} {
+	template := "green"
+	if summary.Interrupted {
+		template = "orange"
+	}
+	return map[string]interface{}{
+		"msg_type": "interactive",
+		"card": map[string]interface{}{
+			"header": map[string]interface{}{
+				"title": map[string]string{
+					"tag":     "plain_text",
+					"content": cardTitle(summary),
+				},
+				"template": template,
+			},
+			"elements": []map[string]string{
+				{
+					"tag":     "markdown",
+					"content": strings.Join(cardLines(summary), "\n"),
+				},
+			},
+		},
+	}
+}
+
+// Slack Block Kit 消息
+func SlackPayload(summary Summary) map[string]interface{} {
+	title := cardTitle(summary)
+	return map[string]interface{}{
+		"text": title,
+		"blocks": []map[string]interface{}{
+			{
+				"type": "header",
+				"text": map[string]string{"type": "plain_text", "text": title},
+			},
+			{
+				"type": "section",
+				"text": map[string]string{"type": "mrkdwn", "text": "• " + strings.Join(cardLines(summary), "\n• ")},
+			},
+		},
+	}
+}
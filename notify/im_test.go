@@ -0,0 +1,154 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 按路径取出JSON中的值，数字下标表示数组元素
+func jsonPath(t *testing.T, data []byte, path ...string) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatalf("解析JSON失败: %v", err)
+	}
+	for _, key := range path {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			v = node[key]
+		case []interface{}:
+			i := int(key[0] - '0')
+			if i >= len(node) {
+				t.Fatalf("%v 中没有 %s", path, key)
+			}
+			v = node[i]
+		default:
+			t.Fatalf("%v 中没有 %s", path, key)
+		}
+	}
+	return v
+}
+
+// 每种机器人的消息格式：标题和正文位于各自要求的字段中
+func TestIMPayloads(t *testing.T) {
+	tests := []struct {
+		provider  string
+		typePath  []string
+		wantType  string
+		titlePath []string
+		textPath  []string
+		textStart string
+	}{
+		{"dingtalk", []string{"msgtype"}, "markdown", []string{"markdown", "title"}, []string{"markdown", "text"}, "### "},
+		{"feishu", []string{"msg_type"}, "interactive", []string{"card", "header", "title", "content"}, []string{"card", "elements", "0", "content"}, ""},
+		{"slack", []string{"blocks", "0", "type"}, "header", []string{"blocks", "0", "text", "text"}, []string{"blocks", "1", "text", "text"}, "• "},
+	}
+	summary := sampleSummary()
+	summary.Changes = "新增存活 2"
+	summary.ChangeList = []string{"+ a.example.com", "+ b.example.com"}
+	wantTitle := "松鼠子域名监控发现变化: nightly"
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			var body []byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ = io.ReadAll(r.Body)
+			}))
+			defer server.Close()
+
+			if err := Send(tt.provider+":"+server.URL, "", summary); err != nil {
+				t.Fatal(err)
+			}
+			if got := jsonPath(t, body, tt.typePath...); got != tt.wantType {
+				t.Errorf("消息类型为 %v，应为 %s", got, tt.wantType)
+			}
+			if got := jsonPath(t, body, tt.titlePath...); got != wantTitle {
+				t.Errorf("标题为 %v，应为 %s", got, wantTitle)
+			}
+			text, _ := jsonPath(t, body, tt.textPath...).(string)
+			if !strings.HasPrefix(text, tt.textStart) {
+				t.Errorf("正文 %q 应以 %q 开头", text, tt.textStart)
+			}
+			for _, want := range []string{"变化: 新增存活 2", "+ b.example.com", "检测域名: 100/120", "存活: 60, 受保护: 5, 无法访问: 35", "报告文件: results.csv, results.html"} {
+				if !strings.Contains(text, want) {
+					t.Errorf("正文中没有 %q:\n%s", want, text)
+				}
+			}
+		})
+	}
+}
+
+// 卡片标题：中断优先于变化，没有名称时不附加
+func TestCardTitle(t *testing.T) {
+	tests := []struct {
+		summary Summary
+		want    string
+	}{
+		{Summary{}, "松鼠子域名检测完成"},
+		{Summary{Name: "prod"}, "松鼠子域名检测完成: prod"},
+		{Summary{Interrupted: true, Changes: "x"}, "松鼠子域名检测已中断"},
+		{Summary{Changes: "x"}, "松鼠子域名监控发现变化"},
+	}
+	for _, tt := range tests {
+		if got := cardTitle(tt.summary); got != tt.want {
+			t.Errorf("%+v 的标题为 %q，应为 %q", tt.summary, got, tt.want)
+		}
+	}
+}
+
+// 变化明细超过上限时只列出前 cardMaxChanges 项
+func TestCardLinesTruncatesChanges(t *testing.T) {
+	summary := Summary{Changes: "新增存活 15"}
+	for i := 0; i < 15; i++ {
+		summary.ChangeList = append(summary.ChangeList, "+ host")
+	}
+	lines := cardLines(summary)
+	if got := lines[cardMaxChanges+1]; got != "... 另有 5 项变化" {
+		t.Errorf("截断提示为 %q", got)
+	}
+}
+
+// 钉钉加签：签名为 timestamp+"\n"+secret 的HMAC-SHA256，已有查询参数时以 & 连接
+func TestSignDingTalkURL(t *testing.T) {
+	now := time.UnixMilli(1700000000000)
+	signed := SignDingTalkURL("https://oapi.dingtalk.com/robot/send?access_token=abc", "SECabc", now)
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	if query.Get("access_token") != "abc" || query.Get("timestamp") != "1700000000000" {
+		t.Errorf("加签后的地址为 %s", signed)
+	}
+	if sign := query.Get("sign"); sign != "jcUpW0QmtKduN03n4JqQ0PBosVjqnM8gU7fIIvsDmCM=" {
+		t.Errorf("签名为 %s", sign)
+	}
+}
+
+// 通知目标的格式和类型
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		spec     string
+		provider string
+		url      string
+		wantErr  bool
+	}{
+		{"dingtalk:https://oapi.dingtalk.com/robot/send", "dingtalk", "https://oapi.dingtalk.com/robot/send", false},
+		{"Slack:https://hooks.slack.com/x", "slack", "https://hooks.slack.com/x", false},
+		{"teams:https://example.com", "", "", true},
+		{"feishu", "", "", true},
+		{"feishu:", "", "", true},
+	}
+	for _, tt := range tests {
+		provider, url, err := ParseTarget(tt.spec)
+		if (err != nil) != tt.wantErr || provider != tt.provider || url != tt.url {
+			t.Errorf("%q 解析为 %q %q %v", tt.spec, provider, url, err)
+		}
+	}
+}
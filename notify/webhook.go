@@ -0,0 +1,126 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 运行结束时发送的统计摘要
+type Summary struct {
+	Name         string          `json:"name"`
+	Time         string          `json:"time"`
+	Interrupted  bool            `json:"interrupted"`
+	Total        int             `json:"total"`
+	Checked      int             `json:"checked"`
+	Alive        int             `json:"alive"`
+	Protected    int             `json:"protected"` // 受保护（401/403/407），不计入 Alive 和 Dead
+	Dead         int             `json:"dead"`
+	Screenshots  int             `json:"screenshots"`
+	LoginPages   int             `json:"login_pages"`
+	AdminPages   int             `json:"admin_pages"`
+	Duration     float64         `json:"duration_seconds"`
+	Reports      []string        `json:"reports"`
+	TopPageTypes []PageTypeCount `json:"top_page_types"`
+	Changes      string          `json:"changes,omitempty"`     // 监控模式下与上一轮相比的变化概要
+	ChangeList   []string        `json:"change_list,omitempty"` // 监控模式下的变化明细
+}
+
+// 页面类型及数量
+type PageTypeCount struct {
+	Type  string `json:"type"`
+	Count int    `json:"count"`
+}
+
+// 按数量从高到低返回前 n 个页面类型
+func TopPageTypes(pageTypeCount map[string]int, n int) []PageTypeCount {
+	top := make([]PageTypeCount, 0, len(pageTypeCount))
+	for pageType, count := range pageTypeCount {
+		top = append(top, PageTypeCount{Type: pageType, Count: count})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Count != top[j].Count {
+			return top[i].Count > top[j].Count
+		}
+		return top[i].Type < top[j].Type
+	})
+	if len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
+
+// 发送通知的整体超时时间，避免无响应的接收端阻塞程序退出
+const webhookTotalTimeout = 15 * time.Second
+
+// 单次请求超时时间
+const webhookRequestTimeout = 5 * time.Second
+
+// 失败重试次数（仅对5xx和网络错误重试）
+const webhookMaxRetries = 2
+
+// 以JSON格式POST统计摘要到指定地址
+func SendWebhook(url string, headers []string, summary Summary) error {
+	body, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("序列化通知内容失败: %v", err)
+	}
+	return post(url, headers, body)
+}
+
+// 发送POST请求，5xx和网络错误时按指数退避重试
+func post(url string, headers []string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTotalTimeout)
+	defer cancel()
+
+	client := &http.Client{Timeout: webhookRequestTimeout}
+	backoff := 500 * time.Millisecond
+
+	var lastErr error
+	for attempt := 0; attempt <= webhookMaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+				backoff *= 2
+			case <-ctx.Done():
+				return fmt.Errorf("发送通知超时: %v", lastErr)
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("创建通知请求失败: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for _, header := range headers {
+			name, value, ok := strings.Cut(header, ":")
+			if !ok {
+				return fmt.Errorf("无效的请求头: %s (格式应为 \"Name: value\")", header)
+			}
+			req.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= 500 {
+			lastErr = fmt.Errorf("服务器返回 %d", resp.StatusCode)
+			continue
+		}
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("服务器返回 %d", resp.StatusCode)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("发送通知失败: %v", lastErr)
+}
@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+// 测试用的摘要
+func sampleSummary() Summary {
+	return Summary{
+		Name:         "nightly",
+		Time:         "2026-10-16 08:00:00",
+		Total:        120,
+		Checked:      100,
+		Alive:        60,
+		Protected:    5,
+		Dead:         35,
+		Screenshots:  60,
+		LoginPages:   3,
+		AdminPages:   1,
+		Duration:     42.5,
+		Reports:      []string{"results.csv", "results.html"},
+		TopPageTypes: []PageTypeCount{{Type: "登录页面", Count: 3}},
+	}
+}
+
+// POST的内容为摘要的JSON，带有 Content-Type 和 -webhook-header 指定的请求头
+func TestSendWebhook(t *testing.T) {
+	var received Summary
+	var contentType, token string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("请求方法为 %s", r.Method)
+		}
+		contentType, token = r.Header.Get("Content-Type"), r.Header.Get("X-Token")
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("解析请求内容失败: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	summary := sampleSummary()
+	if err := SendWebhook(server.URL, []string{"X-Token: secret value"}, summary); err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "application/json" || token != "secret value" {
+		t.Errorf("请求头为 Content-Type: %q, X-Token: %q", contentType, token)
+	}
+	if !reflect.DeepEqual(received, summary) {
+		t.Errorf("收到的摘要为 %+v，应为 %+v", received, summary)
+	}
+}
+
+// 5xx时重试，4xx和无效的请求头直接返回错误
+func TestSendWebhookErrors(t *testing.T) {
+	var attempts atomic.Int32
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer flaky.Close()
+	if err := SendWebhook(flaky.URL, nil, sampleSummary()); err != nil || attempts.Load() != 2 {
+		t.Errorf("错误为 %v，请求了 %d 次，应在第2次成功", err, attempts.Load())
+	}
+
+	var rejected atomic.Int32
+	forbidden := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rejected.Add(1)
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer forbidden.Close()
+	if err := SendWebhook(forbidden.URL, nil, sampleSummary()); err == nil || rejected.Load() != 1 {
+		t.Errorf("错误为 %v，请求了 %d 次，应不重试并返回错误", err, rejected.Load())
+	}
+
+	if err := SendWebhook(forbidden.URL, []string{"no-colon"}, sampleSummary()); err == nil {
+		t.Error("无效的请求头应返回错误")
+	}
+}
@@ -0,0 +1,6 @@
+package main
+
+// Windows没有 SIGUSR1/SIGUSR2，只能在交互式界面中按 p 暂停
+func notifyPauseSignals(pause, resume func()) (stop func()) {
+	return func() {}
+}
@@ -0,0 +1,220 @@
+package screenshot
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/chromedp"
+	"golang.org/x/net/proxy"
+)
+
+// Chrome使用的代理设置
+type proxySettings struct {
+	source   string // 传给 SetProxy 的代理地址，相同地址重复设置时沿用已有的设置
+	server   string // --proxy-server 的值
+	username string // HTTP(S)代理的认证信息，代理要求认证时通过DevTools协议提供
+	password string
+}
+
+// 当前的代理设置，为nil时截图不使用代理
+var chromeProxy atomic.Pointer[proxySettings]
+
+// 设置截图使用的代理，需在开始截图前调用；同一地址重复调用时沿用已有的设置（不会再启动中转）。
+// Chrome 的 --proxy-server 不接受地址中的认证信息：HTTP(S)代理在要求认证时通过DevTools协议提供用户名和密码；
+// Chrome 不支持需要认证的SOCKS5代理，此时在本机启动一个不需认证的SOCKS5中转，由中转带着认证信息连接上游代理
+func SetProxy(proxyURL *url.URL) error {
+	if current := chromeProxy.Load(); current != nil && current.source == proxyURL.String() {
+		return nil
+	}
+	scheme := proxyURL.Scheme
+	if scheme == "socks5h" {
+		scheme = "socks5" // Chrome 的SOCKS5代理总是由代理解析域名
+	}
+	settings := &proxySettings{source: proxyURL.String(), server: scheme + "://" + proxyURL.Host}
+	if proxyURL.User != nil && scheme == "socks5" {
+		addr, err := startSOCKSRelay(proxyURL)
+		if err != nil {
+			return fmt.Errorf("启动SOCKS5中转失败: %v", err)
+		}
+		settings.server = "socks5://" + addr
+	} else if proxyURL.User != nil {
+		settings.username = proxyURL.User.Username()
+		settings.password, _ = proxyURL.User.Password()
+	}
+	chromeProxy.Store(settings)
+	return nil
+}
+
+// 截图时Chrome的代理参数，未设置代理时返回nil。
+// Chrome 默认不代理本机地址，这里取消该例外，与HTTP请求走同一个出口
+func proxyOptions(settings *proxySettings) []chromedp.ExecAllocatorOption {
+	if settings == nil {
+		return nil
+	}
+	return []chromedp.ExecAllocatorOption{
+		chromedp.ProxyServer(settings.server),
+		chromedp.Flag("proxy-bypass-list", "<-loopback>"),
+	}
+}
+
+// HTTP(S)代理需要认证时，在导航前启用请求拦截并在代理要求认证时提供用户名和密码，
+// 同一个请求只提供一次，认证信息错误时取消而不是反复重试；不需要认证时返回nil
+func proxyAuthAction(ctx context.Context, settings *proxySettings) chromedp.Action {
+	if settings == nil || settings.username == "" {
+		return nil
+	}
+	var mu sync.Mutex
+	answered := make(map[fetch.RequestID]bool)
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		switch ev := ev.(type) {
+		case *fetch.EventRequestPaused:
+			go chromedp.Run(ctx, fetch.ContinueRequest(ev.RequestID))
+		case *fetch.EventAuthRequired:
+			response := &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseDefault}
+			if ev.AuthChallenge != nil && ev.AuthChallenge.Source == fetch.AuthChallengeSourceProxy {
+				mu.Lock()
+				retry := answered[ev.RequestID]
+				answered[ev.RequestID] = true
+				mu.Unlock()
+				response.Response = fetch.AuthChallengeResponseResponseCancelAuth
+				if !retry {
+					response.Response = fetch.AuthChallengeResponseResponseProvideCredentials
+					response.Username, response.Password = settings.username, settings.password
+				}
+			}
+			go chromedp.Run(ctx, fetch.ContinueWithAuth(ev.RequestID, response))
+		}
+	})
+	return fetch.Enable().WithHandleAuthRequests(true)
+}
+
+// 在本机随机端口启动不需认证的SOCKS5中转（只支持CONNECT），返回监听地址。中转在进程退出前一直运行
+func startSOCKSRelay(upstream *url.URL) (string, error) {
+	dialer, err := proxy.FromURL(upstream, proxy.Direct)
+	if err != nil {
+		return "", err
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go relaySOCKS(conn, dialer)
+		}
+	}()
+	return listener.Addr().String(), nil
+}
+
+// SOCKS5应答码
+const (
+	socksSucceeded      = 0x00
+	socksGeneralFailure = 0x01
+	socksNotSupported   = 0x07
+)
+
+// 处理一个SOCKS5连接：协商无认证方式，读取CONNECT目标后经上游代理连接，然后双向转发
+func relaySOCKS(conn net.Conn, dialer proxy.Dialer) {
+	defer conn.Close()
+	target, err := readSOCKSRequest(conn)
+	if err != nil {
+		if errors.Is(err, errSOCKSCommand) {
+			replySOCKS(conn, socksNotSupported)
+		}
+		return
+	}
+	remote, err := dialer.Dial("tcp", target)
+	if err != nil {
+		replySOCKS(conn, socksGeneralFailure)
+		return
+	}
+	defer remote.Close()
+	if replySOCKS(conn, socksSucceeded) != nil {
+		return
+	}
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(remote, conn)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(conn, remote)
+		done <- struct{}{}
+	}()
+	<-done // 任一方向结束后关闭两个连接，另一方向随之结束
+}
+
+var errSOCKSCommand = errors.New("只支持CONNECT命令")
+
+// 读取SOCKS5的方法协商和请求，返回CONNECT的目标地址（host:port）
+func readSOCKSRequest(conn net.Conn) (string, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return "", err
+	}
+	if header[0] != 0x05 {
+		return "", fmt.Errorf("不支持的SOCKS版本 %d", header[0])
+	}
+	if _, err := io.ReadFull(conn, make([]byte, header[1])); err != nil {
+		return "", err
+	}
+	if _, err := conn.Write([]byte{0x05, 0x00}); err != nil { // 无需认证
+		return "", err
+	}
+
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(conn, request); err != nil {
+		return "", err
+	}
+	var host string
+	switch request[3] {
+	case 0x01, 0x04: // IPv4、IPv6
+		ip := make(net.IP, 4)
+		if request[3] == 0x04 {
+			ip = make(net.IP, 16)
+		}
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return "", err
+		}
+		host = ip.String()
+	case 0x03: // 域名
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return "", err
+		}
+		name := make([]byte, length[0])
+		if _, err := io.ReadFull(conn, name); err != nil {
+			return "", err
+		}
+		host = string(name)
+	default:
+		return "", fmt.Errorf("不支持的地址类型 %d", request[3])
+	}
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return "", err
+	}
+	if request[1] != 0x01 {
+		return "", errSOCKSCommand
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))), nil
+}
+
+// 发送SOCKS5应答，绑定地址固定为 0.0.0.0:0
+func replySOCKS(conn net.Conn, code byte) error {
+	_, err := conn.Write([]byte{0x05, code, 0x00, 0x01, 0, 0, 0, 0, 0, 0})
+	return err
+}
@@ -14,80 +14,146 @@ import (
 	"sync/atomic"
 	"time"
 
+	"subdomain-checker/utils"
+
 	"github.com/chromedp/chromedp"
 	"github.com/fogleman/gg"
 )
 
+// 未实测前假定的每个Chrome实例的内存占用（字节），以及可用内存中分给Chrome的比例
+const (
+	AssumedChromeMemory = 150 << 20
+	ChromeMemoryShare   = 0.7
+)
+
+// 实测Chrome内存占用的截图次数，至少测得 chromeMemoryMinSamples 次后才调整有效并发
+const (
+	chromeMemorySamples    = 30
+	chromeMemoryMinSamples = 5
+)
+
+// 进行中的截图达到有效并发时，等待名额的检查间隔
+const startTaskInterval = 50 * time.Millisecond
+
 // 截图任务
 type ScreenshotTask struct {
-	URL      string
-	Filename string
-	Dir      string
-	Result   chan<- string // 返回截图路径或空字符串（失败时）
+	URL       string
+	Filename  string
+	Dir       string
+	UserAgent string        // Chrome使用的User-Agent，为空时使用Chrome的默认值
+	Result    chan<- string // 返回截图路径或空字符串（失败时）
 }
 
 // 截图工作池
 type ScreenshotPool struct {
-	tasks        chan ScreenshotTask
-	workers      int
-	wg           sync.WaitGroup
-	closed       bool
-	mutex        sync.RWMutex
-	successCount int64
-	failureCount int64
-	totalCount   int64
-}
-
-// 创建新的截图工作池
-func NewScreenshotPool(workers int) *ScreenshotPool {
+	tasks           chan ScreenshotTask
+	workers         int
+	wg              sync.WaitGroup
+	closed          bool
+	mutex           sync.RWMutex
+	successCount    int64
+	failureCount    int64
+	totalCount      int64
+	errorImageCount int64 // 网络错误时生成的错误图片，计入成功数
+	logger          *utils.Logger
+	monitor         ResourceMonitor
+	taskCounter     int64 // 已处理的任务数，用于大量域名处理时的资源管理
+	lastGCTime      time.Time
+	timeout         time.Duration // 单次截图的超时时间，根据工作者数量确定
+	resume          chan struct{} // 暂停时不为nil，恢复时关闭，由 mutex 保护
+	chromeMemory    chromeMemory
+}
+
+// 启动后前几十次截图中实测的Chrome实例内存占用
+type chromeMemory struct {
+	mu       sync.Mutex
+	budget   uint64 // 启动时可用内存中分给Chrome的部分，无法读取系统内存时为0（不调整并发）
+	samples  int
+	total    uint64
+	disabled bool // 当前系统不支持读取进程内存
+	reported bool
+}
+
+// 截图工作池的统计数据
+type Stats struct {
+	Total        int
+	Success      int
+	Failed       int
+	ErrorImages  int
+	ChromeMemory uint64 // 实测的每个Chrome实例的平均内存占用（字节），未测量时为0
+}
+
+// 创建新的截图工作池，logger 为nil时使用全局日志记录器
+func NewScreenshotPool(workers int, logger *utils.Logger) *ScreenshotPool {
+	if logger == nil {
+		logger = utils.Log()
+	}
 	return &ScreenshotPool{
-		tasks:   make(chan ScreenshotTask, workers*2), // 缓冲大小为工作者数量的2倍
-		workers: workers,
+		tasks:      make(chan ScreenshotTask, workers*2), // 缓冲大小为工作者数量的2倍
+		workers:    workers,
+		logger:     logger,
+		monitor:    ResourceMonitor{maxMemoryMB: 2048, maxConcurrency: workers, limit: int64(workers)},
+		lastGCTime: time.Now(),
+		timeout:    calculateTimeout(workers),
 	}
 }
 
 // 启动截图工作池 - 高并发优化版本，带重试机制
 func (p *ScreenshotPool) Start() {
-	fmt.Printf("🚀 启动 %d 个截图工作者 (高并发优化版本)\n", p.workers)
+	p.logger.Infof("🚀 启动 %d 个截图工作者 (高并发优化版本)\n", p.workers)
+	if mem := utils.SystemMemory(); !mem.Estimated {
+		p.chromeMemory.budget = uint64(float64(mem.Available) * ChromeMemoryShare)
+	}
 
 	// 启动指定数量的工作者
 	for i := 0; i < p.workers; i++ {
 		p.wg.Add(1)
 		go func(workerId int) {
 			defer p.wg.Done()
-			fmt.Printf("📸 截图工作者 %d 启动\n", workerId)
+			p.logger.Debugf("📸 截图工作者 %d 启动\n", workerId)
 
-			for task := range p.tasks {
+			for {
+				// 暂停期间不取新的任务，进行中的截图照常完成
+				if resume := p.pauseChan(); resume != nil {
+					<-resume
+				}
+				// 接近 -max-memory 的预算时等进行中的截图释放内存后再取新的任务
+				p.monitor.WaitForMemory()
+				task, ok := <-p.tasks
+				if !ok {
+					break
+				}
 				atomic.AddInt64(&p.totalCount, 1)
 				screenshotPath := filepath.Join(task.Dir, task.Filename)
 
 				// 轻量级资源监控 - 只在极端情况下限制
-				if !resourceMonitor.CanStartTask() {
-					fmt.Printf("⚠️  工作者 %d 系统资源极度不足，跳过任务: %s\n", workerId, task.URL)
+				if !p.monitor.CanStartTask() {
+					p.logger.Warnf("⚠️  工作者 %d 系统资源极度不足，跳过任务: %s\n", workerId, task.URL)
 					atomic.AddInt64(&p.failureCount, 1)
 					task.Result <- ""
 					continue
 				}
 
-				// 开始任务
-				resourceMonitor.StartTask()
-				defer resourceMonitor.EndTask()
+				// 开始任务，实测的Chrome内存占用降低了有效并发时等待进行中的截图结束
+				p.monitor.StartTask()
 
 				// 大量域名处理时的资源管理
-				taskCount := atomic.AddInt64(&globalTaskCounter, 1)
+				taskCount := atomic.AddInt64(&p.taskCounter, 1)
 
 				// 每处理1000个任务进行一次垃圾回收和资源清理
 				if taskCount%1000 == 0 {
-					if time.Since(lastGCTime) > 30*time.Second {
-						fmt.Printf("🧹 工作者 %d 执行资源清理 (已处理%d个任务)\n", workerId, taskCount)
+					p.mutex.Lock()
+					if time.Since(p.lastGCTime) > 30*time.Second {
+						p.logger.Infof("🧹 工作者 %d 执行资源清理 (已处理%d个任务)\n", workerId, taskCount)
 						runtime.GC()
-						lastGCTime = time.Now()
+						p.lastGCTime = time.Now()
 					}
+					p.mutex.Unlock()
 				}
 
 				// 每处理5000个任务暂停一下，让系统恢复
 				if taskCount%5000 == 0 {
-					fmt.Printf("⏸️  工作者 %d 短暂休息，让系统恢复 (已处理%d个任务)\n", workerId, taskCount)
+					p.logger.Infof("⏸️  工作者 %d 短暂休息，让系统恢复 (已处理%d个任务)\n", workerId, taskCount)
 					time.Sleep(2 * time.Second)
 				}
 
@@ -99,16 +165,16 @@ func (p *ScreenshotPool) Start() {
 					if retry > 0 {
 						// 重试前等待更长时间，给网络和系统更多恢复时间
 						waitTime := time.Duration(retry*500) * time.Millisecond
-						fmt.Printf("🔄 工作者 %d 重试截图 %s (第%d次，等待%v)\n", workerId, task.URL, retry+1, waitTime)
+						p.logger.Debugf("🔄 工作者 %d 重试截图 %s (第%d次，等待%v)\n", workerId, task.URL, retry+1, waitTime)
 						time.Sleep(waitTime)
 					} else {
-						fmt.Printf("🔄 工作者 %d 开始截图: %s\n", workerId, task.URL)
+						p.logger.Debugf("🔄 工作者 %d 开始截图: %s\n", workerId, task.URL)
 					}
 
 					// 尝试截图
-					if err := TakeScreenshotIndependent(task.URL, screenshotPath); err == nil {
+					if err := takeScreenshot(task.URL, screenshotPath, task.UserAgent, p.timeout, p.sampleChromeMemory); err == nil {
 						atomic.AddInt64(&p.successCount, 1)
-						fmt.Printf("✅ 工作者 %d 截图成功: %s\n", workerId, task.URL)
+						p.logger.Debugf("✅ 工作者 %d 截图成功: %s\n", workerId, task.URL)
 						task.Result <- screenshotPath
 						success = true
 					} else {
@@ -124,39 +190,52 @@ func (p *ScreenshotPool) Start() {
 							if isNetworkError {
 								// 网络错误仍然算作成功（生成了错误图片）
 								atomic.AddInt64(&p.successCount, 1)
-								fmt.Printf("🌐 工作者 %d 网络错误，已生成错误图片: %s - %v\n", workerId, task.URL, err)
+								atomic.AddInt64(&p.errorImageCount, 1)
+								p.logger.Debugf("🌐 工作者 %d 网络错误，已生成错误图片: %s - %v\n", workerId, task.URL, err)
 								task.Result <- screenshotPath
 								success = true
 							} else {
 								atomic.AddInt64(&p.failureCount, 1)
-								fmt.Printf("❌ 工作者 %d 截图最终失败: %s - %v\n", workerId, task.URL, err)
+								p.logger.Errorf("❌ 工作者 %d 截图最终失败: %s - %v\n", workerId, task.URL, err)
 								task.Result <- ""
 							}
 						} else {
 							if isNetworkError {
-								fmt.Printf("🌐 工作者 %d 网络错误，准备重试: %s - %v\n", workerId, task.URL, err)
+								p.logger.Debugf("🌐 工作者 %d 网络错误，准备重试: %s - %v\n", workerId, task.URL, err)
 							} else {
-								fmt.Printf("⚠️  工作者 %d 截图失败，准备重试: %s - %v\n", workerId, task.URL, err)
+								p.logger.Debugf("⚠️  工作者 %d 截图失败，准备重试: %s - %v\n", workerId, task.URL, err)
 							}
 						}
 					}
 				}
+				p.monitor.EndTask()
 			}
 
-			fmt.Printf("🏁 截图工作者 %d 结束\n", workerId)
+			p.logger.Debugf("🏁 截图工作者 %d 结束\n", workerId)
 		}(i)
 	}
 }
 
-// 提交截图任务 - 高并发优化版本，带队列管理
-func (p *ScreenshotPool) Submit(url, filename, dir string) <-chan string {
+// 提交截图任务 - 高并发优化版本，带队列管理；队列1秒内仍然是满的时跳过任务。userAgent 为空时使用Chrome的默认值
+func (p *ScreenshotPool) Submit(url, filename, dir, userAgent string) <-chan string {
+	return p.submit(url, filename, dir, userAgent, time.After(1*time.Second))
+}
+
+// 提交截图任务，队列已满时一直等到任务进入队列，只有工作池已关闭时才跳过。
+// 调用方需自行限制同时等待的任务数（如由单个goroutine依次提交）
+func (p *ScreenshotPool) SubmitWait(url, filename, dir, userAgent string) <-chan string {
+	return p.submit(url, filename, dir, userAgent, nil)
+}
+
+// 提交截图任务，timeout 为nil时不超时
+func (p *ScreenshotPool) submit(url, filename, dir, userAgent string, timeout <-chan time.Time) <-chan string {
 	result := make(chan string, 1)
 
 	// 检查工作池是否已关闭
 	p.mutex.RLock()
 	if p.closed {
 		p.mutex.RUnlock()
-		fmt.Printf("⚠️  截图工作池已关闭，跳过任务: %s\n", url)
+		p.logger.Warnf("⚠️  截图工作池已关闭，跳过任务: %s\n", url)
 		result <- ""
 		return result
 	}
@@ -166,33 +245,134 @@ func (p *ScreenshotPool) Submit(url, filename, dir string) <-chan string {
 	defer func() {
 		if r := recover(); r != nil {
 			// 如果发生panic（通常是向已关闭的channel发送数据），返回空结果
-			fmt.Printf("❌ 提交截图任务时发生panic: %s - %v\n", url, r)
+			p.logger.Errorf("❌ 提交截图任务时发生panic: %s - %v\n", url, r)
 			result <- ""
 		}
 	}()
 
 	// 创建任务
 	task := ScreenshotTask{
-		URL:      url,
-		Filename: filename,
-		Dir:      dir,
-		Result:   result,
+		URL:       url,
+		Filename:  filename,
+		Dir:       dir,
+		UserAgent: userAgent,
+		Result:    result,
+	}
+
+	// 暂停期间队列不会被消费，等待恢复后再提交，避免任务因队列已满被跳过
+	if resume := p.pauseChan(); resume != nil {
+		<-resume
 	}
 
 	// 使用带超时的select语句发送任务，避免长时间阻塞
 	select {
 	case p.tasks <- task:
 		// 成功发送任务
-		fmt.Printf("📋 任务已提交到队列: %s\n", url)
-	case <-time.After(1 * time.Second):
+		p.logger.Debugf("📋 任务已提交到队列: %s\n", url)
+	case <-timeout:
 		// 如果1秒内无法提交任务，说明队列可能已满
-		fmt.Printf("⚠️  截图任务队列繁忙，跳过任务: %s\n", url)
+		p.logger.Warnf("⚠️  截图任务队列繁忙，跳过任务: %s\n", url)
 		result <- ""
 	}
 
 	return result
 }
 
+// 设置内存预算的限流器，需在 Start 之前调用
+func (p *ScreenshotPool) SetMemoryGuard(guard *utils.MemoryGuard) {
+	p.monitor.memory = guard
+}
+
+// 暂停截图：工作者不再开始新的任务，Submit 阻塞到恢复为止
+func (p *ScreenshotPool) Pause() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.resume == nil && !p.closed {
+		p.resume = make(chan struct{})
+	}
+}
+
+// 恢复暂停的截图
+func (p *ScreenshotPool) Resume() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.resume != nil {
+		close(p.resume)
+		p.resume = nil
+	}
+}
+
+// 暂停时返回恢复时关闭的通道，未暂停时返回nil
+func (p *ScreenshotPool) pauseChan() <-chan struct{} {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.resume
+}
+
+// 等待中的截图任务数
+func (p *ScreenshotPool) QueueLen() int {
+	return len(p.tasks)
+}
+
+// 获取当前的截图统计，运行中调用时返回已完成部分的数据
+func (p *ScreenshotPool) Stats() Stats {
+	return Stats{
+		Total:        int(atomic.LoadInt64(&p.totalCount)),
+		Success:      int(atomic.LoadInt64(&p.successCount)),
+		Failed:       int(atomic.LoadInt64(&p.failureCount)),
+		ErrorImages:  int(atomic.LoadInt64(&p.errorImageCount)),
+		ChromeMemory: p.chromeMemoryAverage(),
+	}
+}
+
+// 实测的每个Chrome实例的平均内存占用，未测量时为0
+func (p *ScreenshotPool) chromeMemoryAverage() uint64 {
+	m := &p.chromeMemory
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.samples == 0 {
+		return 0
+	}
+	return m.total / uint64(m.samples)
+}
+
+// 页面加载完成、Chrome实例仍在运行时读取其进程树的内存占用，只在前 chromeMemorySamples 次截图中测量。
+// 测得足够次数后按平均值和启动时的内存预算重新计算有效并发，低于工作者数量时多出的工作者等待
+func (p *ScreenshotPool) sampleChromeMemory(pid int) {
+	m := &p.chromeMemory
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.disabled || m.samples >= chromeMemorySamples {
+		return
+	}
+	used, err := utils.ProcessTreeMemory(pid)
+	if err != nil {
+		m.disabled = true
+		p.logger.Debugf("无法读取Chrome进程的内存，按每个实例 %dMB 估算: %v\n", AssumedChromeMemory>>20, err)
+		return
+	}
+	m.samples++
+	m.total += used
+	if m.samples < chromeMemoryMinSamples {
+		return
+	}
+	average := m.total / uint64(m.samples)
+	if m.samples == chromeMemorySamples && !m.reported {
+		m.reported = true
+		p.logger.Infof("📏 实测每个Chrome实例平均占用 %dMB（%d次截图，假定值为 %dMB）\n", average>>20, m.samples, AssumedChromeMemory>>20)
+	}
+	if m.budget == 0 {
+		return
+	}
+	limit := int64(max(1, min(p.workers, int(m.budget/average))))
+	if previous := atomic.SwapInt64(&p.monitor.limit, limit); limit < previous {
+		p.logger.Warnf("⚠️  实测每个Chrome实例平均占用 %dMB，可用内存只够 %d 个实例，截图并发从 %d 降到 %d\n",
+			average>>20, limit, previous, limit)
+	} else if limit > previous {
+		p.logger.Debugf("实测每个Chrome实例平均占用 %dMB，截图并发调整为 %d\n", average>>20, limit)
+	}
+}
+
 // 关闭截图工作池
 func (p *ScreenshotPool) Stop() {
 	p.mutex.Lock()
@@ -200,6 +380,11 @@ func (p *ScreenshotPool) Stop() {
 		p.closed = true
 		close(p.tasks)
 	}
+	// 停止时自动恢复，让工作者处理完队列中剩余的任务后退出
+	if p.resume != nil {
+		close(p.resume)
+		p.resume = nil
+	}
 	p.mutex.Unlock()
 
 	p.wg.Wait()
@@ -209,63 +394,52 @@ func (p *ScreenshotPool) Stop() {
 	success := atomic.LoadInt64(&p.successCount)
 	failure := atomic.LoadInt64(&p.failureCount)
 
-	fmt.Printf("📸 截图工作池已停止\n")
+	p.logger.Infof("📸 截图工作池已停止\n")
 	if total > 0 {
 		successRate := float64(success) / float64(total) * 100
-		fmt.Printf("📊 截图统计: 总计%d个, 成功%d个, 失败%d个, 成功率%.1f%%\n",
+		p.logger.Infof("📊 截图统计: 总计%d个, 成功%d个, 失败%d个, 成功率%.1f%%\n",
 			total, success, failure, successRate)
+		if average := p.chromeMemoryAverage(); average > 0 {
+			p.logger.Infof("📏 每个Chrome实例平均占用 %dMB，可据此设置以后运行的 -screenshot-concurrency\n", average>>20)
+		}
 
 		// 根据成功率给出性能评估
 		if successRate >= 95 {
-			fmt.Printf("✅ 截图性能优秀: 成功率%.1f%% (≥95%%)\n", successRate)
+			p.logger.Infof("✅ 截图性能优秀: 成功率%.1f%% (≥95%%)\n", successRate)
 		} else if successRate >= 85 {
-			fmt.Printf("⚖️  截图性能良好: 成功率%.1f%% (85-95%%)\n", successRate)
+			p.logger.Infof("⚖️  截图性能良好: 成功率%.1f%% (85-95%%)\n", successRate)
 		} else if successRate >= 70 {
-			fmt.Printf("⚠️  截图性能一般: 成功率%.1f%% (70-85%%)\n", successRate)
+			p.logger.Warnf("⚠️  截图性能一般: 成功率%.1f%% (70-85%%)\n", successRate)
 		} else {
-			fmt.Printf("❌ 截图性能较差: 成功率%.1f%% (<70%%)\n", successRate)
+			p.logger.Warnf("❌ 截图性能较差: 成功率%.1f%% (<70%%)\n", successRate)
 		}
 
 		if failure > 0 {
-			fmt.Printf("⚠️  有%d个截图失败，可能原因：\n", failure)
-			fmt.Printf("   • 网络超时或连接失败\n")
-			fmt.Printf("   • 域名无法访问或DNS解析失败\n")
-			fmt.Printf("   • Chrome进程启动失败或崩溃\n")
-			fmt.Printf("   • 系统资源不足（内存/CPU）\n")
-			fmt.Printf("   • 并发数过高导致资源竞争\n")
+			p.logger.Warnf("⚠️  有%d个截图失败，可能原因：\n", failure)
+			p.logger.Infof("   • 网络超时或连接失败\n")
+			p.logger.Infof("   • 域名无法访问或DNS解析失败\n")
+			p.logger.Infof("   • Chrome进程启动失败或崩溃\n")
+			p.logger.Infof("   • 系统资源不足（内存/CPU）\n")
+			p.logger.Infof("   • 并发数过高导致资源竞争\n")
 		}
 	} else {
-		fmt.Printf("📊 没有处理任何截图任务\n")
+		p.logger.Infof("📊 没有处理任何截图任务\n")
 	}
 }
 
-// 全局变量存储当前并发数，用于动态调整超时
-var currentConcurrency int = 1
-
-// 全局计数器，用于大量域名处理时的资源管理
-var globalTaskCounter int64 = 0
-var lastGCTime time.Time = time.Now()
-
 // 资源监控结构
 type ResourceMonitor struct {
 	maxMemoryMB    int64
 	maxConcurrency int
+	limit          int64 // 有效并发，按实测的Chrome内存占用可能低于工作者数量
 	currentTasks   int64
 	mutex          sync.RWMutex
+	memory         *utils.MemoryGuard // -max-memory 的限流器，为nil时不限流
 }
 
-// 全局资源监控器
-var resourceMonitor = &ResourceMonitor{
-	maxMemoryMB:    2048, // 默认2GB内存限制
-	maxConcurrency: 50,   // 默认最大50并发
-}
-
-// 设置当前并发数
-func SetConcurrency(concurrency int) {
-	currentConcurrency = concurrency
-	resourceMonitor.mutex.Lock()
-	resourceMonitor.maxConcurrency = concurrency
-	resourceMonitor.mutex.Unlock()
+// 内存占用超过预算的90%时等待进行中的截图结束，没有进行中的截图时不等待
+func (rm *ResourceMonitor) WaitForMemory() {
+	rm.memory.Wait(0.9, func() bool { return atomic.LoadInt64(&rm.currentTasks) > 0 })
 }
 
 // 检查是否可以启动新任务 - 完全禁用限制
@@ -275,9 +449,15 @@ func (rm *ResourceMonitor) CanStartTask() bool {
 	return true
 }
 
-// 开始任务
+// 开始任务，进行中的任务达到有效并发时等待
 func (rm *ResourceMonitor) StartTask() {
-	atomic.AddInt64(&rm.currentTasks, 1)
+	for {
+		current := atomic.LoadInt64(&rm.currentTasks)
+		if current < atomic.LoadInt64(&rm.limit) && atomic.CompareAndSwapInt64(&rm.currentTasks, current, current+1) {
+			return
+		}
+		time.Sleep(startTaskInterval)
+	}
 }
 
 // 结束任务
@@ -309,8 +489,14 @@ func calculateTimeout(concurrency int) time.Duration {
 	}
 }
 
-// 完全独立的截图函数 - 动态超时优化
-func TakeScreenshotIndependent(url string, screenshotPath string) error {
+// 完全独立的截图函数，使用单个工作者时的超时时间，userAgent 为空时使用Chrome的默认值
+func TakeScreenshotIndependent(url string, screenshotPath string, userAgent string) error {
+	return takeScreenshot(url, screenshotPath, userAgent, calculateTimeout(1), nil)
+}
+
+// 启动独立的Chrome实例截图，timeout 由工作池根据并发数确定。
+// onLoaded 不为nil时在页面处理完成、Chrome实例关闭之前以浏览器进程的PID调用，用于测量内存占用
+func takeScreenshot(url string, screenshotPath string, userAgent string, timeout time.Duration, onLoaded func(pid int)) error {
 	// 检查URL是否包含协议前缀
 	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
 		url = "http://" + url
@@ -340,6 +526,11 @@ func TakeScreenshotIndependent(url string, screenshotPath string) error {
 		chromedp.Flag("max_old_space_size", "512"), // 进一步减少内存
 		chromedp.WindowSize(1280, 720),             // 减少窗口大小提高速度
 	)
+	if userAgent != "" {
+		opts = append(opts, chromedp.UserAgent(userAgent))
+	}
+	proxySettings := chromeProxy.Load()
+	opts = append(opts, proxyOptions(proxySettings)...)
 
 	allocCtx, allocCancel := chromedp.NewExecAllocator(context.Background(), opts...)
 	defer allocCancel()
@@ -347,13 +538,18 @@ func TakeScreenshotIndependent(url string, screenshotPath string) error {
 	taskCtx, taskCancel := chromedp.NewContext(allocCtx)
 	defer taskCancel()
 
-	// 根据并发数动态设置超时时间
-	timeout := calculateTimeout(currentConcurrency)
 	timeoutCtx, timeoutCancel := context.WithTimeout(taskCtx, timeout)
 	defer timeoutCancel()
 
 	var buf []byte
 
+	// 代理需要认证时先启用请求拦截，导航中由 proxyAuthAction 提供认证信息
+	if auth := proxyAuthAction(timeoutCtx, proxySettings); auth != nil {
+		if err := chromedp.Run(timeoutCtx, auth); err != nil {
+			return fmt.Errorf("截图失败: %w", err)
+		}
+	}
+
 	// 智能截图流程 - 处理网络错误和无效响应
 	err := chromedp.Run(timeoutCtx,
 		chromedp.Navigate(url),
@@ -379,6 +575,11 @@ func TakeScreenshotIndependent(url string, screenshotPath string) error {
 		}),
 		chromedp.FullScreenshot(&buf, 80), // 适中质量，平衡速度和清晰度
 	)
+	if c := chromedp.FromContext(taskCtx); onLoaded != nil && c != nil && c.Browser != nil {
+		if process := c.Browser.Process(); process != nil {
+			onLoaded(process.Pid)
+		}
+	}
 
 	if err != nil {
 		// 检查是否是网络相关错误
@@ -410,17 +611,17 @@ func TakeScreenshotIndependent(url string, screenshotPath string) error {
 
 // 快速截图模式 - 保持向后兼容
 func TakeScreenshotFast(ctx context.Context, url string, screenshotPath string) error {
-	return TakeScreenshotIndependent(url, screenshotPath)
+	return TakeScreenshotIndependent(url, screenshotPath, "")
 }
 
 // 稳定截图模式 - 保持向后兼容
 func TakeScreenshotStable(ctx context.Context, url string, screenshotPath string) error {
-	return TakeScreenshotIndependent(url, screenshotPath)
+	return TakeScreenshotIndependent(url, screenshotPath, "")
 }
 
 // 使用已有的context进行截图 - 兼容性函数
 func TakeScreenshotWithContext(ctx context.Context, url string, screenshotPath string) error {
-	return TakeScreenshotIndependent(url, screenshotPath)
+	return TakeScreenshotIndependent(url, screenshotPath, "")
 }
 
 // 宽松模式截图 - 用于处理404、403等错误页面
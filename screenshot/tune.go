@@ -0,0 +1,67 @@
+package screenshot
+
+// 自动计算截图并发数时的限制因素
+const (
+	LimitCPU       = "CPU"
+	LimitMemory    = "内存"
+	LimitTargets   = "目标数量"
+	LimitRequested = "指定值"
+)
+
+// 按目标数量限制的截图并发数：目标越多，过高的并发越容易导致网络错误增加
+func targetLimit(totalDomains int) int {
+	switch {
+	case totalDomains > 20000:
+		return 15
+	case totalDomains > 10000:
+		return 25
+	default:
+		return 50
+	}
+}
+
+// 截图并发数的推荐结果，由调用方决定输出多少信息
+type Recommendation struct {
+	CPUs           int
+	AvailableGB    float64
+	CPUBased       int    // CPU支持的Chrome实例数
+	MemoryBased    int    // 可用内存支持的Chrome实例数
+	TargetLimit    int    // 按目标数量的上限
+	Chosen         int    // 最终的截图并发数
+	LimitingFactor string // LimitCPU、LimitMemory、LimitTargets 或 LimitRequested
+}
+
+// 可用内存（GB）支持的Chrome实例数，按假定的每个实例的占用估算，截图工作池启动后会按实测值调整
+func MemoryLimit(availableGB float64) int {
+	return int(availableGB * ChromeMemoryShare / (float64(AssumedChromeMemory) / (1 << 30)))
+}
+
+// 根据CPU核心数、可用内存（GB）和目标数量推荐截图并发数。
+// requested 大于0且小于推荐值时使用 requested；结果至少为1，且不超过目标数量
+func Recommend(cpus int, availableGB float64, totalDomains, requested int) Recommendation {
+	r := Recommendation{CPUs: cpus, AvailableGB: availableGB}
+
+	// 4核以下每核1个Chrome实例，8核及以上每核2个
+	r.CPUBased = cpus
+	if cpus >= 8 {
+		r.CPUBased = cpus * 2
+	}
+	r.MemoryBased = MemoryLimit(availableGB)
+
+	r.Chosen, r.LimitingFactor = r.CPUBased, LimitCPU
+	if r.MemoryBased < r.Chosen {
+		r.Chosen, r.LimitingFactor = r.MemoryBased, LimitMemory
+	}
+	r.TargetLimit = targetLimit(totalDomains)
+	if r.Chosen > r.TargetLimit {
+		r.Chosen, r.LimitingFactor = r.TargetLimit, LimitTargets
+	}
+	if requested > 0 && requested < r.Chosen {
+		r.Chosen, r.LimitingFactor = requested, LimitRequested
+	}
+	if totalDomains > 0 && r.Chosen > totalDomains {
+		r.Chosen, r.LimitingFactor = totalDomains, LimitTargets
+	}
+	r.Chosen = max(1, r.Chosen)
+	return r
+}
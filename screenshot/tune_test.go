@@ -0,0 +1,59 @@
+package screenshot
+
+import "testing"
+
+// 每档的边界：CPU每核1个或2个实例、内存估算、目标数量上限、指定值，结果至少为1
+func TestRecommend(t *testing.T) {
+	tests := []struct {
+		name         string
+		cpus         int
+		availableGB  float64
+		totalDomains int
+		requested    int
+		chosen       int
+		factor       string
+	}{
+		{"4核", 4, 16, 1000, 0, 4, LimitCPU},
+		{"7核每核1个", 7, 16, 1000, 0, 7, LimitCPU},
+		{"8核每核2个", 8, 16, 1000, 0, 16, LimitCPU},
+		{"内存不足", 8, 2, 1000, 0, 9, LimitMemory},
+		{"内存与CPU相同时取CPU", 4, 1, 1000, 0, 4, LimitCPU},
+		{"内存只够不到1个", 8, 0.1, 1000, 0, 1, LimitMemory},
+		{"10000个目标", 32, 64, 10000, 0, 50, LimitTargets},
+		{"10001个目标", 32, 64, 10001, 0, 25, LimitTargets},
+		{"20000个目标", 32, 64, 20000, 0, 25, LimitTargets},
+		{"20001个目标", 32, 64, 20001, 0, 15, LimitTargets},
+		{"目标少于并发数", 16, 64, 3, 0, 3, LimitTargets},
+		{"目标数量未知", 32, 64, 0, 0, 50, LimitTargets},
+		{"指定值较小", 8, 16, 1000, 3, 3, LimitRequested},
+		{"指定值较大时不采用", 8, 16, 1000, 100, 16, LimitCPU},
+		{"指定值超过目标数量", 8, 16, 2, 5, 2, LimitTargets},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Recommend(tt.cpus, tt.availableGB, tt.totalDomains, tt.requested)
+			if r.Chosen != tt.chosen || r.LimitingFactor != tt.factor {
+				t.Errorf("推荐 %d（%s），应为 %d（%s）: %+v", r.Chosen, r.LimitingFactor, tt.chosen, tt.factor, r)
+			}
+		})
+	}
+}
+
+// 内存估算按每个实例 AssumedChromeMemory 和 ChromeMemoryShare 计算，向下取整
+func TestMemoryLimit(t *testing.T) {
+	tests := []struct {
+		gb   float64
+		want int
+	}{
+		{0, 0},
+		{0.5, 2},
+		{1, 4},
+		{2, 9},
+		{8, 38},
+	}
+	for _, tt := range tests {
+		if got := MemoryLimit(tt.gb); got != tt.want {
+			t.Errorf("%gGB 支持 %d 个实例，应为 %d", tt.gb, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// 触发优雅关闭的信号：Ctrl+C、kill 和终端关闭（SIGHUP）
+var shutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP}
+
+// 只有Windows需要单独处理控制台关闭事件，其他系统由 SIGHUP 覆盖
+func notifyConsoleClose(c chan<- os.Signal) {}
+
+// 关闭流程的宽限时间
+func shutdownGraceFor(sig os.Signal) time.Duration {
+	return shutdownGrace
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// 触发优雅关闭的信号。Ctrl+C 和 Ctrl+Break 都由 os/signal 转换为 os.Interrupt
+var shutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// 关闭控制台窗口时Windows只等待约5秒就强制结束进程，注销和关机同样有时限，关闭流程需在此之前完成
+const consoleCloseGrace = 4 * time.Second
+
+// 控制台关闭、注销或关机事件，通过信号通道触发与 Ctrl+C 相同的关闭流程
+type consoleCloseSignal uint32
+
+func (s consoleCloseSignal) String() string {
+	switch s {
+	case windows.CTRL_LOGOFF_EVENT:
+		return "注销"
+	case windows.CTRL_SHUTDOWN_EVENT:
+		return "关机"
+	}
+	return "关闭控制台窗口"
+}
+
+func (consoleCloseSignal) Signal() {}
+
+// 注册控制台事件处理函数（SetConsoleCtrlHandler）：关闭窗口、注销和关机时把事件发送到 c，
+// 并在宽限时间内阻塞，让关闭流程停止截图工作池和清理Chrome进程；关闭流程结束时会直接退出进程
+func notifyConsoleClose(c chan<- os.Signal) {
+	proc := windows.NewLazySystemDLL("kernel32.dll").NewProc("SetConsoleCtrlHandler")
+	handler := windows.NewCallback(func(event uint32) uintptr {
+		switch event {
+		case windows.CTRL_CLOSE_EVENT, windows.CTRL_LOGOFF_EVENT, windows.CTRL_SHUTDOWN_EVENT:
+			select {
+			case c <- consoleCloseSignal(event):
+			default:
+			}
+			time.Sleep(consoleCloseGrace)
+			return 1
+		}
+		// 其他事件（Ctrl+C、Ctrl+Break）交给Go运行时的处理函数
+		return 0
+	})
+	proc.Call(handler, 1)
+}
+
+// 关闭流程的宽限时间，控制台关闭、注销和关机时需在系统强制结束进程之前完成
+func shutdownGraceFor(sig os.Signal) time.Duration {
+	if _, ok := sig.(consoleCloseSignal); ok {
+		return consoleCloseGrace - time.Second
+	}
+	return shutdownGrace
+}
@@ -0,0 +1,160 @@
+package squirrel
+
+import (
+	"net/http"
+	"sync"
+
+	"subdomain-checker/checker"
+	"subdomain-checker/utils"
+)
+
+// 自适应并发（-adaptive）的参数
+const (
+	adaptiveWindow     = 200 // 统计失败率的最近结果数
+	adaptiveMinSamples = 100 // 两次调整之间至少需要的新结果数
+	adaptiveSlowDown   = 0.4 // 超时和连接被拒绝的比例超过该值时并发减半
+	adaptiveRecover    = 0.1 // 低于该值时逐步恢复并发
+)
+
+// 自适应并发的调整情况
+type AdaptiveStats struct {
+	SlowDowns      int      // 降低并发的次数
+	MinConcurrency int      // 运行中的最低并发数，未降低时为配置的并发数
+	Suspects       []string // 失败突发期间超时或连接被拒绝的目标（Input），可能是误判，应复查
+}
+
+// 根据最近结果中超时和连接被拒绝的比例调整同时检测的目标数。
+// 本机DNS解析器过载、连接跟踪表满或上游限速时，这类失败会突然集中出现，
+// 此时降低并发让本机恢复，失败率恢复正常后再逐步提高
+type adaptiveLimiter struct {
+	max int
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	limit  int
+	active int
+
+	window   []string // 最近结果的环形缓冲，失败的记录 Input，其余为空字符串
+	next     int
+	count    int
+	failures int
+	samples  int // 上次调整后的新结果数
+
+	stats    AdaptiveStats
+	suspects map[string]bool
+}
+
+func newAdaptiveLimiter(concurrency int) *adaptiveLimiter {
+	l := &adaptiveLimiter{
+		max:      concurrency,
+		limit:    concurrency,
+		window:   make([]string, adaptiveWindow),
+		stats:    AdaptiveStats{MinConcurrency: concurrency},
+		suspects: make(map[string]bool),
+	}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// 是否属于本机或网络过载引起的失败，目标返回429（请求过多、被限流）同样说明请求发得太快
+func burstFailure(result Result) bool {
+	if result.Status == http.StatusTooManyRequests {
+		return true
+	}
+	return !result.Alive && (result.ErrorClass == checker.ErrorTimeout || result.ErrorClass == checker.ErrorRefused)
+}
+
+// 等待可用的并发名额，未启用时直接返回
+func (l *adaptiveLimiter) acquire() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	for l.active >= l.limit {
+		l.cond.Wait()
+	}
+	l.active++
+	l.mu.Unlock()
+}
+
+func (l *adaptiveLimiter) release() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	l.active--
+	l.mu.Unlock()
+	l.cond.Signal()
+}
+
+// 记录一条结果，失败率超过阈值时并发减半，恢复正常后每次提高四分之一
+func (l *adaptiveLimiter) record(result Result) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	failed := ""
+	if burstFailure(result) {
+		failed = result.Input
+		// 已经降低并发时的失败同样可能是过载引起的
+		if l.limit < l.max {
+			l.suspects[failed] = true
+		}
+	}
+	if l.window[l.next] != "" {
+		l.failures--
+	}
+	l.window[l.next] = failed
+	if failed != "" {
+		l.failures++
+	}
+	l.next = (l.next + 1) % len(l.window)
+	l.count = min(l.count+1, len(l.window))
+	l.samples++
+	if l.samples < adaptiveMinSamples {
+		return
+	}
+
+	rate := float64(l.failures) / float64(l.count)
+	switch {
+	case rate > adaptiveSlowDown && l.limit > 1:
+		previous := l.limit
+		l.limit = max(1, l.limit/2)
+		l.samples = 0
+		l.stats.SlowDowns++
+		l.stats.MinConcurrency = min(l.stats.MinConcurrency, l.limit)
+		for _, input := range l.window {
+			if input != "" {
+				l.suspects[input] = true
+			}
+		}
+		utils.Log().Warnf("⚠️  最近 %d 个请求中 %.0f%% 超时、连接被拒绝或被限流(429)，可能是本机或网络过载，并发从 %d 降到 %d (-adaptive)\n",
+			l.count, rate*100, previous, l.limit)
+	case rate < adaptiveRecover && l.limit < l.max:
+		l.limit = min(l.max, l.limit+max(1, l.max/4))
+		l.samples = 0
+		l.cond.Broadcast()
+		if l.limit == l.max {
+			utils.Log().Infof("✅ 失败率已恢复正常，并发恢复到 %d (-adaptive)\n", l.limit)
+		} else {
+			utils.Log().Debugf("失败率 %.0f%%，并发提高到 %d\n", rate*100, l.limit)
+		}
+	}
+}
+
+// 目前为止的调整情况，未启用时返回零值
+func (l *adaptiveLimiter) snapshot() AdaptiveStats {
+	if l == nil {
+		return AdaptiveStats{}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stats := l.stats
+	stats.Suspects = make([]string, 0, len(l.suspects))
+	for input := range l.suspects {
+		stats.Suspects = append(stats.Suspects, input)
+	}
+	return stats
+}
@@ -0,0 +1,283 @@
+package squirrel
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"subdomain-checker/checker"
+	"subdomain-checker/checkpoint"
+	"subdomain-checker/utils"
+)
+
+// 汇总器的选项
+type AggregatorOptions struct {
+	// 内存中最多保留的结果数，超过后把已完成的结果转存到临时文件，0 表示不转存
+	SpillThreshold int
+	// 每凑满多少条已完成（不再等待截图）的结果调用一次 OnBatch，0 表示每条都调用
+	BatchSize int
+	// 交出一批已完成的结果，如写入检查点、推送到Web界面。在汇总器的锁内按完成顺序调用
+	OnBatch func([]Result)
+	// 结果钩子，在汇总器的锁外逐条调用，运行结束时由 Complete 通知
+	Hooks []ResultHook
+}
+
+// 汇总器的统计：通过 Add 添加的结果数量和分类，不含 NewAggregator 传入的之前的结果
+type AggregatorStats struct {
+	Processed int
+	Alive     int
+	Protected int // 受保护（401/403/407），不计入 Alive 和 Dead
+	Dead      int
+}
+
+// 汇总检测结果：计数、保存全部结果、把之后完成的截图补到结果中，并分批交出已完成的结果。
+// 结果超过 SpillThreshold 条后转存到临时文件，内存中只保留计数、添加顺序和等待截图的结果，
+// Close 时再全部读回并恢复添加的顺序。所有方法都可以在多个goroutine中同时调用
+type Aggregator struct {
+	opts AggregatorOptions
+
+	processed, alive, protected, dead int32
+
+	mu      sync.Mutex
+	results []Result
+	pending map[string]int // 等待截图的结果在 results 中的下标
+	batch   []Result
+	spool   *checkpoint.Spool
+	closed  bool
+
+	// 转存时结果写入临时文件的顺序与添加的顺序不同（等待截图的结果留在内存中，截图补上后才转存），
+	// 记录每条结果是第几个添加的，读回时按它恢复顺序。seqs 与 results 一一对应，spooled 与临时文件中的结果一一对应
+	seqs    []int
+	spooled []int
+
+	completeOnce sync.Once
+}
+
+// 创建汇总器，previous 为之前已有的结果（如从检查点恢复），不计入计数也不交给 OnBatch
+func NewAggregator(previous []Result, opts AggregatorOptions) *Aggregator {
+	seqs := make([]int, len(previous))
+	for i := range seqs {
+		seqs[i] = i
+	}
+	return &Aggregator{
+		opts:    opts,
+		results: append([]Result(nil), previous...),
+		pending: make(map[string]int),
+		seqs:    seqs,
+	}
+}
+
+// 添加一条检测结果。ScreenshotPending 为true的结果等 AddScreenshot 补上截图后才交给 OnBatch，
+// 同一 Input 有多条等待截图的结果时截图补到最后添加的一条上
+func (a *Aggregator) Add(result Result) {
+	atomic.AddInt32(&a.processed, 1)
+	switch {
+	case result.Alive:
+		atomic.AddInt32(&a.alive, 1)
+	case checker.IsProtected(result):
+		atomic.AddInt32(&a.protected, 1)
+	default:
+		atomic.AddInt32(&a.dead, 1)
+	}
+
+	a.mu.Lock()
+	var superseded *Result
+	if result.ScreenshotPending {
+		// 同一目标再次添加（如重复的输入）时截图补到新的结果上，旧的结果不再等待截图
+		if i, ok := a.pending[result.Input]; ok {
+			a.results[i].ScreenshotPending = false
+			old := a.results[i]
+			a.addBatch(old)
+			superseded = &old
+		}
+		a.pending[result.Input] = len(a.results)
+	} else {
+		a.addBatch(result)
+	}
+	if !a.closed {
+		a.seqs = append(a.seqs, len(a.spooled)+len(a.results))
+	}
+	a.results = append(a.results, result)
+	if a.opts.SpillThreshold > 0 && len(a.results) >= a.opts.SpillThreshold {
+		a.spill()
+	}
+	a.mu.Unlock()
+	if superseded != nil {
+		a.RunHooks(*superseded)
+	}
+	if !result.ScreenshotPending {
+		a.RunHooks(result)
+	}
+}
+
+// 把完成的截图补到对应的结果中，返回补上截图后的结果；没有等待该截图的结果时返回false
+func (a *Aggregator) AddScreenshot(shot Screenshot) (Result, bool) {
+	a.mu.Lock()
+	i, ok := a.pending[shot.Input]
+	if !ok {
+		a.mu.Unlock()
+		return Result{}, false
+	}
+	delete(a.pending, shot.Input)
+	a.results[i].Screenshot, a.results[i].ScreenshotPending = shot.Path, false
+	result := a.results[i]
+	a.addBatch(result)
+	a.mu.Unlock()
+	a.RunHooks(result)
+	return result, true
+}
+
+// 运行结束或中断时把摘要交给结果钩子，等待钩子处理完成；只有第一次调用有效
+func (a *Aggregator) Complete(summary Summary) {
+	a.completeOnce.Do(func() {
+		for _, hook := range a.opts.Hooks {
+			callHook(hook, func() { hook.OnComplete(summary) })
+		}
+	})
+}
+
+// 把最终结果交给结果钩子。Add 和 AddScreenshot 会自动调用，不经过 Add 的结果（如复查后恢复存活的结果）由调用方调用
+func (a *Aggregator) RunHooks(result Result) {
+	for _, hook := range a.opts.Hooks {
+		callHook(hook, func() { hook.OnResult(result) })
+	}
+}
+
+// 检测、存活、受保护和无法访问的计数，可直接交给进度条读取，调用方不能修改
+func (a *Aggregator) Counters() (processed, alive, protected, dead *int32) {
+	return &a.processed, &a.alive, &a.protected, &a.dead
+}
+
+// 目前为止的统计
+func (a *Aggregator) Stats() AggregatorStats {
+	return AggregatorStats{
+		Processed: int(atomic.LoadInt32(&a.processed)),
+		Alive:     int(atomic.LoadInt32(&a.alive)),
+		Protected: int(atomic.LoadInt32(&a.protected)),
+		Dead:      int(atomic.LoadInt32(&a.dead)),
+	}
+}
+
+// 交出未凑满一批的结果；已经开始转存时，把内存中已完成的结果也转存到临时文件（如分块处理每块结束时）
+func (a *Aggregator) Flush() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.flushBatch()
+	if a.spool != nil {
+		a.spill()
+	}
+}
+
+// 目前为止的全部结果（包括已转存的结果）的副本，用于中断时写入部分结果
+func (a *Aggregator) Snapshot() []Result {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.collect()
+}
+
+// 用新的结果（如复查后的结果）替换全部结果，只能在 Close 之后调用，之后 results 归汇总器所有
+func (a *Aggregator) Replace(results []Result) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.results = results
+}
+
+// 在汇总器的锁内修改全部结果（如排序），只能在 Close 之后调用
+func (a *Aggregator) Update(update func([]Result)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	update(a.results)
+}
+
+// 交出剩余的结果，从临时文件读回全部结果并删除临时文件，返回全部结果和统计。
+// 返回的切片仍由汇总器使用（Snapshot 会读取），调用方只能读取，修改需通过 Update 或在副本上修改后 Replace。
+// 可以多次调用，之后添加的结果只保留在内存中
+func (a *Aggregator) Close() ([]Result, AggregatorStats) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.flushBatch()
+	if a.spool != nil {
+		a.results = a.collect()
+		a.spool.Close()
+		a.spool = nil
+	}
+	a.closed = true
+	a.seqs, a.spooled = nil, nil
+	return a.results, a.Stats()
+}
+
+// 调用方需持有 a.mu
+func (a *Aggregator) addBatch(result Result) {
+	a.batch = append(a.batch, result)
+	if len(a.batch) >= a.opts.BatchSize {
+		a.flushBatch()
+	}
+}
+
+// 调用方需持有 a.mu
+func (a *Aggregator) flushBatch() {
+	if len(a.batch) == 0 {
+		return
+	}
+	if a.opts.OnBatch != nil {
+		a.opts.OnBatch(a.batch)
+	}
+	a.batch = nil
+}
+
+// 把已完成的结果转存到临时文件，等待截图的结果留在内存中等截图补上。
+// 临时文件在第一次转存时创建，结果较少时不会写磁盘。调用方需持有 a.mu
+func (a *Aggregator) spill() {
+	if a.closed {
+		return
+	}
+	if a.spool == nil {
+		spool, err := checkpoint.NewSpool()
+		if err != nil {
+			utils.Log().Warnf("⚠️  %s，结果保留在内存中\n", err)
+			a.opts.SpillThreshold = 0
+			return
+		}
+		a.spool = spool
+	}
+	done := make([]Result, 0, len(a.results))
+	doneSeqs := make([]int, 0, len(a.results))
+	var kept []Result
+	var keptSeqs []int
+	pending := make(map[string]int, len(a.pending))
+	for i, result := range a.results {
+		if result.ScreenshotPending {
+			pending[result.Input] = len(kept)
+			kept = append(kept, result)
+			keptSeqs = append(keptSeqs, a.seqs[i])
+		} else {
+			done = append(done, result)
+			doneSeqs = append(doneSeqs, a.seqs[i])
+		}
+	}
+	if err := a.spool.Add(done); err != nil {
+		utils.Log().Errorf("%s\n", err)
+		return
+	}
+	a.results, a.pending, a.seqs = kept, pending, keptSeqs
+	a.spooled = append(a.spooled, doneSeqs...)
+}
+
+// 已转存的结果加上内存中的结果，按添加的顺序排列；临时文件读取失败时只返回内存中的结果。调用方需持有 a.mu
+func (a *Aggregator) collect() []Result {
+	if a.spool == nil {
+		return append([]Result(nil), a.results...)
+	}
+	spooled, err := a.spool.ReadAll()
+	if err != nil {
+		utils.Log().Errorf("%s\n", err)
+		return append([]Result(nil), a.results...)
+	}
+	results := make([]Result, len(spooled)+len(a.results))
+	for i, result := range spooled {
+		results[a.spooled[i]] = result
+	}
+	for i, result := range a.results {
+		results[a.seqs[i]] = result
+	}
+	return results
+}
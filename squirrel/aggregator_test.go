@@ -0,0 +1,277 @@
+package squirrel
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// 按序号构造结果：每3个中依次为存活、受保护（403）和无法访问
+func testResult(i int) Result {
+	result := Result{Domain: fmt.Sprintf("host%d.example.com", i), Input: fmt.Sprintf("host%d.example.com", i)}
+	switch i % 3 {
+	case 0:
+		result.Status, result.Alive = 200, true
+	case 1:
+		result.Status = 403
+	}
+	return result
+}
+
+// 多个goroutine同时 Add，所有结果都应交给 OnBatch 且只交一次，计数与结果一致
+func TestAggregatorConcurrentAdd(t *testing.T) {
+	for _, spill := range []int{0, 500} {
+		t.Run(fmt.Sprintf("spill=%d", spill), func(t *testing.T) {
+			const workers, perWorker = 16, 500
+			var mu sync.Mutex
+			batched := make(map[string]int)
+			agg := NewAggregator(nil, AggregatorOptions{
+				SpillThreshold: spill,
+				BatchSize:      64,
+				OnBatch: func(batch []Result) {
+					mu.Lock()
+					defer mu.Unlock()
+					for _, result := range batch {
+						batched[result.Input]++
+					}
+				},
+			})
+
+			var wg sync.WaitGroup
+			for w := 0; w < workers; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					for i := 0; i < perWorker; i++ {
+						agg.Add(testResult(w*perWorker + i))
+						if w == 0 && i%100 == 0 {
+							agg.Snapshot()
+						}
+					}
+				}(w)
+			}
+			wg.Wait()
+
+			results, stats := agg.Close()
+			const total = workers * perWorker
+			if len(results) != total {
+				t.Fatalf("Close 返回 %d 条结果，应为 %d", len(results), total)
+			}
+			want := AggregatorStats{Processed: total, Alive: (total + 2) / 3, Protected: (total + 1) / 3, Dead: total / 3}
+			if stats != want {
+				t.Errorf("统计为 %+v，应为 %+v", stats, want)
+			}
+			if len(batched) != total {
+				t.Errorf("OnBatch 收到 %d 个不同的结果，应为 %d", len(batched), total)
+			}
+			for input, n := range batched {
+				if n != 1 {
+					t.Errorf("%s 交给 OnBatch %d 次", input, n)
+				}
+			}
+		})
+	}
+}
+
+// 最后一批未凑满 BatchSize 的结果在 Close 时交出，不会丢失
+func TestAggregatorLastBatchFlush(t *testing.T) {
+	var batches [][]Result
+	agg := NewAggregator(nil, AggregatorOptions{
+		BatchSize: 10,
+		OnBatch:   func(batch []Result) { batches = append(batches, append([]Result(nil), batch...)) },
+	})
+	for i := 0; i < 25; i++ {
+		agg.Add(testResult(i))
+	}
+	if len(batches) != 2 {
+		t.Fatalf("Close 之前交出 %d 批，应为 2 批", len(batches))
+	}
+
+	results, stats := agg.Close()
+	if len(batches) != 3 || len(batches[2]) != 5 {
+		t.Fatalf("Close 后应交出最后5条结果，实际批次: %d", len(batches))
+	}
+	if len(results) != 25 || stats.Processed != 25 {
+		t.Errorf("Close 返回 %d 条结果，统计 %d 条，应为 25", len(results), stats.Processed)
+	}
+	if got := batches[2][4].Input; got != "host24.example.com" {
+		t.Errorf("最后一条结果为 %s", got)
+	}
+
+	// 再次 Close 不会重复交出
+	agg.Close()
+	if len(batches) != 3 {
+		t.Errorf("再次 Close 后批次为 %d", len(batches))
+	}
+}
+
+// 等待截图的结果在截图补上后才交给 OnBatch，截图与添加同时进行时不丢失结果
+func TestAggregatorPendingScreenshots(t *testing.T) {
+	const total = 2000
+	var mu sync.Mutex
+	batched := make(map[string]string)
+	agg := NewAggregator(nil, AggregatorOptions{
+		BatchSize: 16,
+		OnBatch: func(batch []Result) {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, result := range batch {
+				if result.ScreenshotPending {
+					t.Errorf("%s 在截图完成前交给了 OnBatch", result.Input)
+				}
+				batched[result.Input] = result.Screenshot
+			}
+		},
+	})
+
+	shots := make(chan Screenshot, total)
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < total; i += 8 {
+				result := testResult(i)
+				result.ScreenshotPending = true
+				agg.Add(result)
+				shots <- Screenshot{Input: result.Input, Path: "screenshots/" + result.Input + ".png"}
+			}
+		}(w)
+	}
+	var shotWG sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		shotWG.Add(1)
+		go func() {
+			defer shotWG.Done()
+			for shot := range shots {
+				if _, ok := agg.AddScreenshot(shot); !ok {
+					t.Errorf("没有等待 %s 截图的结果", shot.Input)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(shots)
+	shotWG.Wait()
+
+	results, _ := agg.Close()
+	if len(batched) != total {
+		t.Fatalf("OnBatch 收到 %d 条结果，应为 %d", len(batched), total)
+	}
+	for _, result := range results {
+		if result.ScreenshotPending || result.Screenshot != batched[result.Input] {
+			t.Errorf("%s 的截图为 %q，应为 %q", result.Input, result.Screenshot, batched[result.Input])
+		}
+	}
+}
+
+// 转存后等待截图的结果仍能补上截图，Close 返回的结果按添加的顺序排列
+func TestAggregatorSpillPendingScreenshots(t *testing.T) {
+	const total = 1000
+	agg := NewAggregator([]Result{testResult(-1)}, AggregatorOptions{SpillThreshold: 100, BatchSize: 10})
+	var shots []Screenshot
+	for i := 0; i < total; i++ {
+		result := testResult(i)
+		if i%7 == 0 {
+			result.ScreenshotPending = true
+			shots = append(shots, Screenshot{Input: result.Input, Path: "screenshots/" + result.Input + ".png"})
+		}
+		agg.Add(result)
+		// 部分截图在之后几次转存之间完成，其余的在全部添加后才完成
+		if i%7 == 3 && len(shots) > 1 {
+			shot := shots[0]
+			shots = shots[1:]
+			if _, ok := agg.AddScreenshot(shot); !ok {
+				t.Fatalf("转存后没有等待 %s 截图的结果", shot.Input)
+			}
+		}
+	}
+	for _, shot := range shots {
+		if _, ok := agg.AddScreenshot(shot); !ok {
+			t.Fatalf("转存后没有等待 %s 截图的结果", shot.Input)
+		}
+	}
+
+	results, _ := agg.Close()
+	if len(results) != total+1 {
+		t.Fatalf("Close 返回 %d 条结果，应为 %d", len(results), total+1)
+	}
+	for i, result := range results {
+		if want := testResult(i - 1).Input; result.Input != want {
+			t.Fatalf("第 %d 条结果为 %s，应为 %s", i, result.Input, want)
+		}
+		wantShot := ""
+		if i > 0 && (i-1)%7 == 0 {
+			wantShot = "screenshots/" + result.Input + ".png"
+		}
+		if result.ScreenshotPending || result.Screenshot != wantShot {
+			t.Errorf("%s 的截图为 %q，应为 %q", result.Input, result.Screenshot, wantShot)
+		}
+	}
+}
+
+// 10万条结果、每1万条转存一次时的内存分配
+func BenchmarkAggregatorSpill(b *testing.B) {
+	const total = 100000
+	results := make([]Result, total)
+	for i := range results {
+		results[i] = testResult(i)
+		results[i].Title = "Example Domain"
+	}
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		agg := NewAggregator(nil, AggregatorOptions{SpillThreshold: 10000, BatchSize: 500, OnBatch: func([]Result) {}})
+		for _, result := range results {
+			agg.Add(result)
+		}
+		if all, _ := agg.Close(); len(all) != total {
+			b.Fatalf("Close 返回 %d 条结果", len(all))
+		}
+	}
+}
+
+// 同一目标添加两次时截图补到后添加的结果上，先添加的结果不再等待截图；补上截图后保留指纹等其他字段
+func TestAggregatorDuplicatePending(t *testing.T) {
+	for _, spill := range []int{0, 2} {
+		t.Run(fmt.Sprintf("spill=%d", spill), func(t *testing.T) {
+			var batched []Result
+			agg := NewAggregator(nil, AggregatorOptions{
+				SpillThreshold: spill,
+				BatchSize:      1,
+				OnBatch:        func(batch []Result) { batched = append(batched, batch...) },
+			})
+			older := Result{Domain: "https://a.example.com", Input: "a.example.com", Status: 503, ScreenshotPending: true}
+			newer := Result{
+				Domain: "https://a.example.com", Input: "a.example.com", Status: 200, Alive: true, ScreenshotPending: true,
+				Fingerprints: []string{"Nginx", "WordPress"}, FaviconHash: "-1234", Title: "Blog",
+			}
+			agg.Add(older)
+			agg.Add(newer)
+			agg.Add(testResult(3))
+			if len(batched) != 2 || batched[0].Status != 503 || batched[0].ScreenshotPending {
+				t.Fatalf("先添加的结果应不等待截图直接交出，已交出 %+v", batched)
+			}
+
+			merged, ok := agg.AddScreenshot(Screenshot{Input: "a.example.com", Path: "screenshots/a.png"})
+			if !ok || merged.Status != 200 || merged.Screenshot != "screenshots/a.png" {
+				t.Fatalf("截图补到了 %+v", merged)
+			}
+			if _, ok := agg.AddScreenshot(Screenshot{Input: "a.example.com", Path: "screenshots/a2.png"}); ok {
+				t.Error("同一目标的第二张截图不应再有等待的结果")
+			}
+
+			results, _ := agg.Close()
+			if len(results) != 3 {
+				t.Fatalf("Close 返回 %d 条结果，应为3", len(results))
+			}
+			got := results[1]
+			if got.ScreenshotPending || got.Screenshot != "screenshots/a.png" || got.Title != "Blog" || got.FaviconHash != "-1234" ||
+				len(got.Fingerprints) != 2 || got.Fingerprints[1] != "WordPress" {
+				t.Errorf("补上截图后的结果为 %+v", got)
+			}
+			if results[0].ScreenshotPending || results[0].Screenshot != "" {
+				t.Errorf("先添加的结果为 %+v", results[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package squirrel
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"subdomain-checker/checker"
+	"subdomain-checker/utils"
+)
+
+// 自动并发（-auto-concurrency）的参数
+const (
+	aimdStart         = 10              // 初始并发数（不超过 -concurrency）
+	aimdInterval      = 2 * time.Second // 控制器的调整间隔
+	aimdMinResults    = 50              // 每次调整至少需要的新结果数，不足时累积到之后的间隔
+	aimdBackOff       = 0.2             // 超时、连接被拒绝或被重置的比例比正常水平高出该值时并发减半
+	aimdSmoothing     = 0.2             // 更新正常失败率时新数据的权重
+	aimdLatencyFactor = 2.0             // 平均响应时间超过基线的该倍数时不再提高并发
+	aimdStepDivisor   = 20              // 每次提高 -concurrency 的二十分之一（至少1个）
+)
+
+// 自动并发的运行情况
+type AutoConcurrencyStats struct {
+	Min     int // 运行中的最低并发数
+	Max     int // 运行中的最高并发数
+	Typical int // 有检测进行时各调整间隔并发数的中位数，即本机和网络通常能承受的并发
+}
+
+// 加性增、乘性减（AIMD）的并发控制器：从较低的并发开始，失败率和响应时间正常且并发已用满时
+// 每个间隔增加一点，超时、连接被拒绝或被重置突增时减半，上限为 -concurrency。
+// 工作者数量固定为上限，由信号量控制同时进行的检测数
+type aimdController struct {
+	max  int
+	step int
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	limit   int
+	active  int
+	blocked bool // 上次调整后有工作者因达到并发数而等待
+
+	results  int // 上次调整后的结果数
+	failures int
+	answered int           // 有响应的结果数
+	latency  time.Duration // 有响应的结果的响应时间之和
+	baseline time.Duration // 目前为止每次调整时平均响应时间的最小值
+
+	// 正常的失败率：目标本身不可达（端口关闭、主机不存在）造成的失败，与并发无关。
+	// 只用没有突增的数据更新，已降到1个并发时的数据也视为正常
+	failureRate    float64
+	hasFailureRate bool
+
+	minLimit, maxLimit int
+	observed           map[int]int // 各并发数出现的间隔数
+}
+
+func newAIMDController(concurrency int) *aimdController {
+	start := min(concurrency, aimdStart)
+	c := &aimdController{
+		max:      concurrency,
+		step:     max(1, concurrency/aimdStepDivisor),
+		limit:    start,
+		minLimit: start,
+		maxLimit: start,
+		observed: make(map[int]int),
+	}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// 是否属于需要退让的失败：超时、连接被拒绝或被重置，或目标返回429（请求过多、被限流）
+func backOffFailure(result Result) bool {
+	if result.Alive {
+		return false
+	}
+	if result.Status == http.StatusTooManyRequests {
+		return true
+	}
+	switch result.ErrorClass {
+	case checker.ErrorTimeout, checker.ErrorRefused, checker.ErrorReset:
+		return true
+	}
+	return false
+}
+
+// 按间隔调整并发，直到 stop 关闭
+func (c *aimdController) run(stop <-chan struct{}) {
+	if c == nil {
+		return
+	}
+	ticker := time.NewTicker(aimdInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.adjust()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// 等待可用的并发名额，未启用时直接返回
+func (c *aimdController) acquire() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	for c.active >= c.limit {
+		c.blocked = true
+		c.cond.Wait()
+	}
+	c.active++
+	c.mu.Unlock()
+}
+
+func (c *aimdController) release() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.active--
+	c.mu.Unlock()
+	c.cond.Signal()
+}
+
+// 记录一条结果，供下一次调整使用
+func (c *aimdController) record(result Result) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.results++
+	if backOffFailure(result) {
+		c.failures++
+	}
+	if result.Status != 0 {
+		c.answered++
+		c.latency += result.ResponseTime
+	}
+}
+
+// 根据上次调整后的结果调整并发数
+func (c *aimdController) adjust() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// 没有检测在进行（如等待截图）的间隔不计入统计
+	if c.active > 0 || c.results > 0 {
+		c.observed[c.limit]++
+	}
+	// 结果不足时继续累积到下一个间隔，响应较慢时多个间隔才调整一次
+	if c.results < aimdMinResults {
+		return
+	}
+	results, failures, answered, latency, blocked := c.results, c.failures, c.answered, c.latency, c.blocked
+	c.results, c.failures, c.answered, c.latency, c.blocked = 0, 0, 0, 0, false
+
+	var average time.Duration
+	if answered > 0 {
+		average = latency / time.Duration(answered)
+		if c.baseline == 0 || average < c.baseline {
+			c.baseline = average
+		}
+	}
+	rate := float64(failures) / float64(results)
+	spike := c.hasFailureRate && rate > c.failureRate+aimdBackOff
+	switch {
+	case !c.hasFailureRate:
+		c.failureRate, c.hasFailureRate = rate, true
+	case !spike || c.limit == 1:
+		c.failureRate = c.failureRate*(1-aimdSmoothing) + rate*aimdSmoothing
+	}
+	switch {
+	case spike:
+		if c.limit > 1 {
+			previous := c.limit
+			c.limit = max(1, c.limit/2)
+			utils.Log().Debugf("自动并发: %d 个结果中 %.0f%% 超时、连接被拒绝或被重置（正常 %.0f%%），并发从 %d 降到 %d\n",
+				results, rate*100, c.failureRate*100, previous, c.limit)
+		}
+	case average > 0 && float64(average) > float64(c.baseline)*aimdLatencyFactor:
+		utils.Log().Debugf("自动并发: 平均响应时间 %dms 超过基线 %dms 的 %.0f 倍，保持并发 %d\n",
+			average.Milliseconds(), c.baseline.Milliseconds(), aimdLatencyFactor, c.limit)
+	case blocked && c.limit < c.max:
+		previous := c.limit
+		c.limit = min(c.max, c.limit+c.step)
+		c.cond.Broadcast()
+		utils.Log().Debugf("自动并发: 失败率 %.0f%%，平均响应时间 %dms，并发从 %d 提高到 %d\n",
+			rate*100, average.Milliseconds(), previous, c.limit)
+	}
+	c.minLimit = min(c.minLimit, c.limit)
+	c.maxLimit = max(c.maxLimit, c.limit)
+}
+
+// 目前为止的运行情况，未启用时返回零值
+func (c *aimdController) snapshot() AutoConcurrencyStats {
+	if c == nil {
+		return AutoConcurrencyStats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := AutoConcurrencyStats{Min: c.minLimit, Max: c.maxLimit, Typical: c.limit}
+
+	// 按间隔数取中位数
+	limits := make([]int, 0, len(c.observed))
+	total := 0
+	for limit, count := range c.observed {
+		limits = append(limits, limit)
+		total += count
+	}
+	sort.Ints(limits)
+	seen := 0
+	for _, limit := range limits {
+		seen += c.observed[limit]
+		if seen*2 >= total {
+			stats.Typical = limit
+			break
+		}
+	}
+	return stats
+}
@@ -0,0 +1,179 @@
+package squirrel
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"subdomain-checker/notify"
+	"subdomain-checker/utils"
+)
+
+// 运行结束（或中断）时的统计摘要，与发送通知的内容相同
+type Summary = notify.Summary
+
+// 结果钩子，注册到汇总器（AggregatorOptions.Hooks）后依次收到每条最终结果和运行结束时的摘要。
+// 需要截图的结果在补上截图后才交给 OnResult；Add 在多个goroutine中调用时 OnResult 也可能同时被调用。
+// 钩子中的panic会被捕获并记录到日志，不影响检测和其他钩子
+type ResultHook interface {
+	OnResult(Result)
+	OnComplete(Summary)
+}
+
+// 调用钩子，捕获并记录panic
+func callHook(hook ResultHook, call func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			utils.Log().Errorf("⚠️  结果钩子 %s 出错: %v\n", hookName(hook), r)
+		}
+	}()
+	call()
+}
+
+// 钩子在日志中显示的名称
+func hookName(hook ResultHook) string {
+	if named, ok := hook.(fmt.Stringer); ok {
+		return named.String()
+	}
+	return fmt.Sprintf("%T", hook)
+}
+
+// 对每个存活的结果执行一条命令（-exec）。命令按空白分割为程序和参数（可用单引号或双引号包含空白），
+// 不经过shell，结果中的内容只会替换到参数中，不会被当作命令执行。
+// 支持的占位符: {domain} {url} {status} {title} {screenshot}
+type ExecHook struct {
+	args    []string
+	timeout time.Duration
+	slots   chan struct{}
+	wg      sync.WaitGroup
+}
+
+// 创建命令钩子，最多同时执行 concurrency 条命令，每条命令超过 timeout 后被终止
+func NewExecHook(command string, concurrency int, timeout time.Duration) (*ExecHook, error) {
+	args, err := splitCommand(command)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("命令为空")
+	}
+	if concurrency <= 0 {
+		return nil, fmt.Errorf("并发数必须大于0，当前为 %d", concurrency)
+	}
+	if timeout <= 0 {
+		return nil, fmt.Errorf("超时必须大于0，当前为 %s", timeout)
+	}
+	return &ExecHook{args: args, timeout: timeout, slots: make(chan struct{}, concurrency)}, nil
+}
+
+func (h *ExecHook) String() string {
+	return "-exec " + h.args[0]
+}
+
+// 存活的结果在后台执行命令，同时执行的命令达到并发数时等待
+func (h *ExecHook) OnResult(result Result) {
+	if !result.Alive {
+		return
+	}
+	replacer := strings.NewReplacer(
+		"{domain}", hookDomain(result),
+		"{url}", hookURL(result),
+		"{status}", strconv.Itoa(result.Status),
+		"{title}", strings.TrimSpace(result.Title),
+		"{screenshot}", result.Screenshot,
+	)
+	args := make([]string, len(h.args))
+	for i, arg := range h.args {
+		args[i] = replacer.Replace(arg)
+	}
+
+	h.slots <- struct{}{}
+	h.wg.Add(1)
+	go func() {
+		defer func() {
+			<-h.slots
+			h.wg.Done()
+		}()
+		h.run(args)
+	}()
+}
+
+// 等待正在执行的命令结束
+func (h *ExecHook) OnComplete(Summary) {
+	h.wg.Wait()
+}
+
+// 执行一条命令，输出和错误记录到日志
+func (h *ExecHook) run(args []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, args[0], args[1:]...).CombinedOutput()
+	command := strings.Join(args, " ")
+	if text := strings.TrimSpace(string(output)); text != "" {
+		utils.Log().Infof("🪝 %s: %s\n", command, text)
+	}
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		utils.Log().Warnf("⚠️  命令超时（%s）已终止: %s\n", h.timeout, command)
+	case err != nil:
+		utils.Log().Warnf("⚠️  命令执行失败: %s: %v\n", command, err)
+	default:
+		utils.Log().Debugf("命令执行完成: %s\n", command)
+	}
+}
+
+// {domain} 为输入中的目标（不含协议），没有时取结果的域名
+func hookDomain(result Result) string {
+	if result.Input != "" {
+		return result.Input
+	}
+	return result.Domain
+}
+
+// {url} 为实际得到响应的地址
+func hookURL(result Result) string {
+	if result.FinalURL != "" {
+		return result.FinalURL
+	}
+	return result.Domain
+}
+
+// 按空白分割命令，单引号或双引号中的空白不分割，引号本身去掉
+func splitCommand(command string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	var quote rune
+	inArg := false
+	for _, r := range command {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("命令中的引号没有闭合: %s", command)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,384 @@
+// Package squirrel 提供可嵌入其他Go程序的检测接口，命令行程序同样基于它实现。
+//
+// 库代码不直接打印到标准输出，也不会退出进程：结果和进度通过回调返回，
+// 运行日志写入 utils.Log()，可以用 utils.SetLogger 替换。
+package squirrel
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"sync/atomic"
+
+	"subdomain-checker/checker"
+	"subdomain-checker/config"
+	"subdomain-checker/screenshot"
+	"subdomain-checker/utils"
+)
+
+// 检测参数，与命令行参数相同；库只使用其中与检测有关的字段（超时、并发、截图、重定向等）
+type Config = config.Config
+
+// 单个目标的检测结果
+type Result = checker.Result
+
+// 检测进度
+type Progress struct {
+	Total     int // 本次 Run 的目标数
+	Processed int
+	Alive     int
+	Protected int // 受保护（401/403/407）的数量，不计入 Alive 和 Dead
+	Dead      int
+
+	Screenshots     int // 本次 Run 中需要截图的结果数
+	ScreenshotsDone int // 其中已完成（含失败）的截图数
+}
+
+// 检测结果之后完成的截图
+type Screenshot struct {
+	Seq   int    // 对应的结果在本次 Run 中交给 OnResult 的序号，从0开始
+	Input string // 对应结果的 Input
+	Path  string // 截图的相对路径，截图失败时为空
+}
+
+// 创建检测器的选项
+type Options struct {
+	// 共享的截图工作池，为nil且启用截图时由检测器创建，并在 Close 时停止
+	ScreenshotPool *screenshot.ScreenshotPool
+	// 自动创建截图工作池时的工作者数量，0 表示与并发数相同
+	ScreenshotWorkers int
+	// 截图工作池的日志记录器，为nil时使用全局日志记录器
+	Logger *utils.Logger
+	// 按目标轮换的代理（Config.ProxyRotation 指定轮换方式），为空时使用 Config.Proxy
+	Proxies []*url.URL
+	// 内存预算的限流器，为nil时不限流。自动创建的截图工作池在占用达到预算的90%时暂缓截图，
+	// 达到预算时检测器也暂缓派发新的目标，直到进行中的检测释放内存
+	MemoryGuard *utils.MemoryGuard
+
+	// 每得到一条结果时调用，设置后 Run 不再保留结果（适合大量目标的流式处理）。
+	// 未设置 OnScreenshot 时，需要截图的结果等截图完成后才交给 OnResult
+	OnResult func(Result)
+	// 设置后需要截图的结果在检测完成时立即交给 OnResult（ScreenshotPending 为true、Screenshot 为空），
+	// 截图完成后再调用 OnScreenshot，由调用方补到之前的结果中
+	OnScreenshot func(Screenshot)
+	// 每得到一条结果或完成一张截图后调用，传入本次 Run 的进度
+	OnProgress func(Progress)
+}
+
+// 检测器，可以多次调用 Run（如分块处理），但同一时间只能运行一个 Run。
+// 同一个检测器的各次 Run 共用一个检测会话（-rate-per-host 的限速、代理轮换和 -paths 选定的协议），
+// 不同的检测器互不影响；需要重新开始时创建新的检测器。
+// HTTP检测不等待截图：需要截图的结果排队交给截图工作池，检测工作者继续检测下一个目标，
+// Run 在全部截图完成后才返回。
+// OnResult、OnScreenshot 和 OnProgress 在同一个goroutine中依次调用，回调中不需要加锁，但应尽快返回
+type Runner struct {
+	cfg     Config
+	opts    Options
+	session *checker.Session
+	pool    *screenshot.ScreenshotPool
+	ownPool bool
+
+	adaptive        *adaptiveLimiter // 启用 Adaptive 时根据失败率调整并发，否则为nil
+	auto            *aimdController  // 启用 AutoConcurrency 时以AIMD方式调整并发，否则为nil
+	inFlight        int32            // 正在检测的目标数
+	screenshots     int32            // 所有 Run 中需要截图的结果数
+	screenshotsDone int32            // 其中已完成的截图数
+
+	mu      sync.Mutex
+	resume  chan struct{} // 暂停时不为nil，恢复时关闭
+	pausing chan struct{} // 暂停时通知正在等待派发目标的goroutine重新检查暂停状态
+}
+
+// 创建检测器，启用截图且未提供截图工作池时启动新的工作池，使用完毕后需调用 Close
+func NewRunner(cfg Config, opts Options) (*Runner, error) {
+	if cfg.Timeout <= 0 {
+		return nil, fmt.Errorf("Timeout 必须大于0，当前为 %d", cfg.Timeout)
+	}
+	if cfg.Concurrency <= 0 {
+		return nil, fmt.Errorf("Concurrency 必须大于0，当前为 %d", cfg.Concurrency)
+	}
+	if cfg.ScreenshotDir == "" {
+		cfg.ScreenshotDir = "screenshots"
+	}
+
+	r := &Runner{cfg: cfg, opts: opts, session: checker.NewSession(cfg, opts.Proxies), pool: opts.ScreenshotPool, pausing: make(chan struct{}, 1)}
+	if cfg.Adaptive {
+		r.adaptive = newAdaptiveLimiter(cfg.Concurrency)
+	}
+	if cfg.AutoConcurrency {
+		r.auto = newAIMDController(cfg.Concurrency)
+	}
+	if r.pool == nil && (cfg.Screenshot || cfg.ScreenshotAlive) {
+		workers := opts.ScreenshotWorkers
+		if workers <= 0 {
+			workers = cfg.Concurrency
+		}
+		if cfg.Proxy != "" {
+			proxyURL, err := config.ParseProxy(cfg.Proxy)
+			if err == nil {
+				err = screenshot.SetProxy(proxyURL)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		r.pool = screenshot.NewScreenshotPool(workers, opts.Logger)
+		r.pool.SetMemoryGuard(opts.MemoryGuard)
+		r.pool.Start()
+		r.ownPool = true
+	}
+	return r, nil
+}
+
+// 检测器使用的截图工作池，未启用截图时为nil
+func (r *Runner) ScreenshotPool() *screenshot.ScreenshotPool {
+	return r.pool
+}
+
+// 停止检测器创建的截图工作池，共享的工作池由调用方负责停止
+func (r *Runner) Close() {
+	if r.ownPool {
+		r.pool.Stop()
+		r.ownPool = false
+	}
+}
+
+// 暂停检测：不再开始新的目标，截图工作池也不再开始新的截图，进行中的检测照常完成
+func (r *Runner) Pause() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.resume == nil {
+		r.resume = make(chan struct{})
+		if r.pool != nil {
+			r.pool.Pause()
+		}
+		select {
+		case r.pausing <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// 恢复暂停的检测
+func (r *Runner) Resume() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.resume != nil {
+		close(r.resume)
+		r.resume = nil
+		if r.pool != nil {
+			r.pool.Resume()
+		}
+	}
+}
+
+// 是否处于暂停状态
+func (r *Runner) Paused() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.resume != nil
+}
+
+// 暂停时返回恢复时关闭的通道，未暂停时返回nil
+func (r *Runner) pauseChan() <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.resume
+}
+
+// 正在检测的目标数，可在 Run 期间从其他goroutine调用
+func (r *Runner) InFlight() int {
+	return int(atomic.LoadInt32(&r.inFlight))
+}
+
+// 自适应并发（Config.Adaptive）的调整情况，未启用时返回零值
+func (r *Runner) AdaptiveStats() AdaptiveStats {
+	return r.adaptive.snapshot()
+}
+
+// 自动并发（AutoConcurrency）的运行情况，未启用时返回零值
+func (r *Runner) AutoConcurrencyStats() AutoConcurrencyStats {
+	return r.auto.snapshot()
+}
+
+// -rate-per-host 检测到的主域名分组数量，未启用时为0
+func (r *Runner) RateLimitGroups() int {
+	return r.session.RateLimitGroups()
+}
+
+// 所有 Run 中已完成的截图数和需要截图的结果数，可在 Run 期间从其他goroutine调用
+func (r *Runner) ScreenshotProgress() (done, total int) {
+	return int(atomic.LoadInt32(&r.screenshotsDone)), int(atomic.LoadInt32(&r.screenshots))
+}
+
+// 等待截图的结果
+type pendingScreenshot struct {
+	seq    int
+	result Result
+}
+
+// 完成的截图
+type screenshotOutcome struct {
+	pendingScreenshot
+	path string
+}
+
+// 依次把等待截图的结果提交到截图工作池，队列满时阻塞，同时等待结果的goroutine不超过工作池的容量
+func (r *Runner) feedScreenshots(feed <-chan pendingScreenshot, done chan<- screenshotOutcome) {
+	for shot := range feed {
+		ch := checker.SubmitScreenshot(r.pool, shot.result, r.cfg.ScreenshotDir)
+		go func(shot pendingScreenshot) {
+			done <- screenshotOutcome{shot, checker.ScreenshotRelPath(<-ch)}
+		}(shot)
+	}
+}
+
+// 以配置的并发数检测目标，返回按完成顺序排列的结果（设置了 OnResult 时返回nil）。
+// ctx 取消后不再开始新的目标和尚未开始的截图，等待进行中的检测和截图结束后返回已得到的结果和 ctx.Err()
+func (r *Runner) Run(ctx context.Context, targets []string) ([]Result, error) {
+	targetChan := make(chan string)
+	resultChan := make(chan Result, r.cfg.Concurrency)
+
+	stopAuto := make(chan struct{})
+	defer close(stopAuto)
+	go r.auto.run(stopAuto)
+
+	var wg sync.WaitGroup
+	for i := 0; i < min(r.cfg.Concurrency, len(targets)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for target := range targetChan {
+				r.adaptive.acquire()
+				r.auto.acquire()
+				atomic.AddInt32(&r.inFlight, 1)
+				r.session.CheckDomain(target, r.cfg, resultChan)
+				atomic.AddInt32(&r.inFlight, -1)
+				r.auto.release()
+				r.adaptive.release()
+			}
+		}()
+	}
+	go func() {
+		defer close(targetChan)
+		for i := 0; i < len(targets); {
+			if resume := r.pauseChan(); resume != nil {
+				select {
+				case <-resume:
+				case <-ctx.Done():
+					return
+				}
+			}
+			// 最后手段：内存占用达到预算时等进行中的检测结束再派发
+			r.opts.MemoryGuard.Wait(1, func() bool { return r.InFlight() > 0 })
+			// 等待空闲的工作者时被暂停，目标留到恢复后再派发
+			select {
+			case targetChan <- targets[i]:
+				i++
+			case <-r.pausing:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(resultChan)
+	}()
+
+	// 截图在后台进行：等待截图的结果先排队，由 feedScreenshots 依次提交，完成后回到这个循环
+	feed := make(chan pendingScreenshot)
+	shotDone := make(chan screenshotOutcome)
+	defer close(feed)
+	if r.pool != nil {
+		go r.feedScreenshots(feed, shotDone)
+	}
+
+	var results []Result
+	var queued []pendingScreenshot // 尚未提交到截图工作池
+	held := make(map[int]Result)   // 未设置 OnScreenshot 时等待截图完成的结果
+	submitted := 0                 // 已提交、尚未完成的截图数
+	seq := 0
+	progress := Progress{Total: len(targets)}
+
+	// 截图完成（或被取消）时补到结果中
+	finishScreenshot := func(shot pendingScreenshot, path string) {
+		progress.ScreenshotsDone++
+		atomic.AddInt32(&r.screenshotsDone, 1)
+		switch {
+		case r.opts.OnResult == nil:
+			results[shot.seq].Screenshot = path
+			results[shot.seq].ScreenshotPending = false
+		case r.opts.OnScreenshot != nil:
+			r.opts.OnScreenshot(Screenshot{Seq: shot.seq, Input: shot.result.Input, Path: path})
+		default:
+			result := held[shot.seq]
+			delete(held, shot.seq)
+			result.Screenshot, result.ScreenshotPending = path, false
+			r.opts.OnResult(result)
+		}
+	}
+
+	incoming := (<-chan Result)(resultChan)
+	for incoming != nil || len(queued) > 0 || submitted > 0 {
+		var next pendingScreenshot
+		var feedChan chan<- pendingScreenshot
+		var cancelled <-chan struct{}
+		if len(queued) > 0 {
+			next, feedChan, cancelled = queued[0], feed, ctx.Done()
+		}
+
+		select {
+		case result, ok := <-incoming:
+			if !ok {
+				incoming = nil
+				continue
+			}
+			r.adaptive.record(result)
+			r.auto.record(result)
+			progress.Processed++
+			switch {
+			case result.Alive:
+				progress.Alive++
+			case checker.IsProtected(result):
+				progress.Protected++
+			default:
+				progress.Dead++
+			}
+			if result.ScreenshotPending && r.pool == nil {
+				result.ScreenshotPending = false
+			}
+			if result.ScreenshotPending {
+				queued = append(queued, pendingScreenshot{seq: seq, result: result})
+				progress.Screenshots++
+				atomic.AddInt32(&r.screenshots, 1)
+			}
+			switch {
+			case r.opts.OnResult == nil:
+				results = append(results, result)
+			case result.ScreenshotPending && r.opts.OnScreenshot == nil:
+				held[seq] = result
+			default:
+				r.opts.OnResult(result)
+			}
+			seq++
+		case feedChan <- next:
+			queued = queued[1:]
+			submitted++
+		case shot := <-shotDone:
+			submitted--
+			finishScreenshot(shot.pendingScreenshot, shot.path)
+		case <-cancelled:
+			// 取消后不再开始排队中的截图
+			for _, shot := range queued {
+				finishScreenshot(shot, "")
+			}
+			queued = nil
+		}
+		if r.opts.OnProgress != nil {
+			r.opts.OnProgress(progress)
+		}
+	}
+	return results, ctx.Err()
+}
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// 不加 -allow-large-cidr 时允许展开的最大地址数量（相当于IPv4的 /16）
+const LargeCIDRSize = 1 << 16
+
+// 单个CIDR范围最多展开的地址数量（相当于IPv4的 /8），超过时无论如何都拒绝
+const MaxCIDRSize = 1 << 24
+
+// 判断输入是否为CIDR表示的地址范围，如 10.10.0.0/24
+func IsCIDR(s string) bool {
+	_, err := netip.ParsePrefix(s)
+	return err == nil
+}
+
+// 将CIDR范围展开为单个IP地址，IPv6地址加上方括号以便直接拼接端口和URL。
+// 前缀长度小于 /31 的IPv4范围会跳过网络地址和广播地址；
+// 地址数量超过 LargeCIDRSize 时需要 allowLarge，超过 MaxCIDRSize 时总是返回错误
+func ExpandCIDR(s string, allowLarge bool) ([]string, error) {
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return nil, fmt.Errorf("无效的CIDR: %s", s)
+	}
+	prefix = prefix.Masked()
+
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	if hostBits > 24 {
+		return nil, fmt.Errorf("CIDR范围 %s 过大（超过 %d 个地址）", s, MaxCIDRSize)
+	}
+	size := 1 << hostBits
+	if size > LargeCIDRSize && !allowLarge {
+		return nil, fmt.Errorf("CIDR范围 %s 包含 %d 个地址，超过 %d 个时需要指定 -allow-large-cidr", s, size, LargeCIDRSize)
+	}
+
+	skipEdges := prefix.Addr().Is4() && hostBits >= 2
+	ips := make([]string, 0, size)
+	addr := prefix.Addr()
+	for i := 0; i < size; i++ {
+		if !skipEdges || (i != 0 && i != size-1) {
+			if addr.Is6() {
+				ips = append(ips, "["+addr.String()+"]")
+			} else {
+				ips = append(ips, addr.String())
+			}
+		}
+		addr = addr.Next()
+	}
+	return ips, nil
+}
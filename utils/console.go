@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// 终端输出协调：底部状态行（进度条）与普通日志共用标准输出
+var console struct {
+	sync.Mutex
+	status  string
+	capture func(string) // 全屏界面运行期间接管输出
+	held    []string     // 接管期间打印的内容，结束后按原顺序输出
+}
+
+// 判断文件是否为终端
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// 由 -no-color、-no-emoji 关闭的输出样式
+var outputStyle struct {
+	noColor bool
+	noEmoji bool
+}
+
+// 设置输出样式，需在开始输出之前调用
+func SetOutputStyle(noColor, noEmoji bool) {
+	outputStyle.noColor = noColor
+	outputStyle.noEmoji = noEmoji
+}
+
+// 是否使用颜色：标准输出是终端，且没有指定 -no-color 或 NO_COLOR 环境变量。
+// 每次调用时重新判断，静默模式把标准输出换成标准错误后同样适用
+func ColorEnabled() bool {
+	return !outputStyle.noColor && os.Getenv("NO_COLOR") == "" && IsTerminal(os.Stdout)
+}
+
+// 是否在日志中保留表情符号：标准输出是终端且没有指定 -no-emoji
+func EmojiEnabled() bool {
+	return !outputStyle.noEmoji && IsTerminal(os.Stdout)
+}
+
+// 用ANSI颜色代码（如 "32" 为绿色）包裹文本，不使用颜色时原样返回
+func Color(code, text string) string {
+	if !ColorEnabled() {
+		return text
+	}
+	return "\033[" + code + "m" + text + "\033[0m"
+}
+
+// ANSI转义序列（颜色、清除行等）
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+// 按输出样式去掉文本中的ANSI转义序列和表情符号
+func applyOutputStyle(text string) string {
+	if !ColorEnabled() {
+		text = ansiPattern.ReplaceAllString(text, "")
+	}
+	if !EmojiEnabled() {
+		text = StripEmoji(text)
+	}
+	return text
+}
+
+// 去掉表情符号；位于行首或空白之后的表情符号连同后面的空格一起去掉，避免留下多余的缩进
+func StripEmoji(text string) string {
+	var sb strings.Builder
+	var last rune = '\n'
+	skipSpace := false
+	for _, r := range text {
+		if isEmoji(r) {
+			skipSpace = skipSpace || last == '\n' || last == ' '
+			continue
+		}
+		if skipSpace && r == ' ' {
+			continue
+		}
+		skipSpace = false
+		sb.WriteRune(r)
+		last = r
+	}
+	return sb.String()
+}
+
+// 判断是否为表情符号（含变体选择符和零宽连接符），保留 ✓ ✗ 这类普通符号
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF, r >= 0x2600 && r <= 0x26FF, r >= 0x23E9 && r <= 0x23FA:
+		return true
+	case r >= 0x2700 && r <= 0x27BF:
+		return r != '✓' && r != '✗'
+	case r == 0xFE0F, r == 0x200D:
+		return true
+	}
+	return false
+}
+
+// 打印信息，存在状态行时先清除状态行，打印后再重绘，避免输出交错。
+// 输出不是终端或指定了 -no-color、-no-emoji 时去掉颜色和表情符号
+func Printf(format string, a ...interface{}) {
+	console.Lock()
+	defer console.Unlock()
+	text := fmt.Sprintf(format, a...)
+	if console.capture != nil {
+		console.held = append(console.held, text)
+		console.capture(text)
+		return
+	}
+	if console.status != "" {
+		fmt.Fprint(os.Stdout, "\r\033[K")
+	}
+	fmt.Fprint(os.Stdout, applyOutputStyle(text))
+	if console.status != "" {
+		fmt.Fprint(os.Stdout, console.status)
+	}
+}
+
+// 更新底部状态行（仅在终端中使用）
+func SetStatus(line string) {
+	console.Lock()
+	defer console.Unlock()
+	console.status = line
+	fmt.Fprint(os.Stdout, "\r\033[K"+line)
+}
+
+// 清除底部状态行
+func ClearStatus() {
+	console.Lock()
+	defer console.Unlock()
+	if console.status != "" {
+		fmt.Fprint(os.Stdout, "\r\033[K")
+		console.status = ""
+	}
+}
+
+// 全屏界面运行期间接管终端输出：打印的内容交给 fn（如显示在界面底部）并暂存，
+// 调用返回的函数后恢复正常输出，并按原顺序打印暂存的内容
+func CaptureConsole(fn func(string)) (release func()) {
+	console.Lock()
+	console.capture = fn
+	console.Unlock()
+	return func() {
+		console.Lock()
+		defer console.Unlock()
+		console.capture = nil
+		for _, text := range console.held {
+			fmt.Fprint(os.Stdout, applyOutputStyle(text))
+		}
+		console.held = nil
+	}
+}
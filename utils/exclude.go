@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"path"
+	"strings"
+)
+
+// 排除规则：精确的主机名（可带端口）、通配符（如 *.prod.example.com）和CIDR范围
+type Excluder struct {
+	exact    map[string]bool
+	patterns []string
+	prefixes []netip.Prefix
+}
+
+// 根据规则列表创建排除器，空行和以#开头的行会被忽略
+func NewExcluder(rules []string) (*Excluder, error) {
+	e := &Excluder{exact: make(map[string]bool)}
+	for _, rule := range rules {
+		rule = strings.ToLower(strings.TrimSpace(rule))
+		if rule == "" || strings.HasPrefix(rule, "#") {
+			continue
+		}
+		switch {
+		case IsCIDR(rule):
+			prefix, _ := netip.ParsePrefix(rule)
+			e.prefixes = append(e.prefixes, prefix.Masked())
+		case strings.ContainsAny(rule, "*?["):
+			if _, err := path.Match(rule, ""); err != nil {
+				return nil, fmt.Errorf("无效的排除规则: %s", rule)
+			}
+			e.patterns = append(e.patterns, rule)
+		default:
+			e.exact[rule] = true
+		}
+	}
+	return e, nil
+}
+
+// 规则数量
+func (e *Excluder) Len() int {
+	return len(e.exact) + len(e.patterns) + len(e.prefixes)
+}
+
+// 判断归一化后的目标（host 或 host:port）是否应被排除
+func (e *Excluder) Match(target string) bool {
+	target = strings.ToLower(target)
+	host := TargetHost(target)
+
+	if e.exact[target] || e.exact[host] {
+		return true
+	}
+	for _, pattern := range e.patterns {
+		if ok, _ := path.Match(pattern, host); ok {
+			return true
+		}
+	}
+	if len(e.prefixes) > 0 {
+		if addr, err := netip.ParseAddr(host); err == nil {
+			for _, prefix := range e.prefixes {
+				if prefix.Contains(addr) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// 获取归一化后的目标（host 或 host:port）中的主机名，去掉端口和IPv6地址的方括号
+func TargetHost(target string) string {
+	host := target
+	if h, _, err := net.SplitHostPort(target); err == nil {
+		host = h
+	}
+	return strings.Trim(host, "[]")
+}
@@ -0,0 +1,6 @@
+package utils
+
+// 错误是否由文件被其他程序占用引起（如报告在Excel中打开），关闭该程序后重试即可成功
+func IsFileLocked(err error) bool {
+	return isFileLocked(err)
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package utils
+
+// 其他系统打开的文件不阻止替换
+func isFileLocked(err error) bool {
+	return false
+}
@@ -0,0 +1,16 @@
+//go:build windows
+
+package utils
+
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
+// Windows 上被打开的文件不能覆盖或替换，重命名时返回共享冲突或拒绝访问
+func isFileLocked(err error) bool {
+	return errors.Is(err, windows.ERROR_SHARING_VIOLATION) ||
+		errors.Is(err, windows.ERROR_LOCK_VIOLATION) ||
+		errors.Is(err, windows.ERROR_ACCESS_DENIED)
+}
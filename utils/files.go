@@ -0,0 +1,13 @@
+package utils
+
+import "os"
+
+// 当前进程打开的文件数（含网络连接），通过列出 /dev/fd 估算；不支持的系统（如Windows）返回 -1
+func OpenFileCount() int {
+	entries, err := os.ReadDir("/dev/fd")
+	if err != nil {
+		return -1
+	}
+	// 减去列目录本身打开的一个
+	return len(entries) - 1
+}
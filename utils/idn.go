@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// 是否只包含ASCII字符
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// 将目标（host 或 host:port）中的Unicode主机名转换为punycode，纯ASCII的目标原样返回
+func ToASCIITarget(target string) (string, error) {
+	host, port, err := net.SplitHostPort(target)
+	if err != nil {
+		host, port = target, ""
+	}
+	if isASCII(host) {
+		return target, nil
+	}
+	ascii, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		return "", fmt.Errorf("无效的国际化域名 %s: %v", host, err)
+	}
+	if port != "" {
+		return net.JoinHostPort(ascii, port), nil
+	}
+	return ascii, nil
+}
+
+// 将URL或目标中的punycode主机名转换为Unicode形式用于显示，不含punycode时返回空字符串
+func ToUnicodeTarget(target string) string {
+	host := target
+	if u, err := url.Parse(target); err == nil && u.Host != "" {
+		host = u.Hostname()
+	} else if h, _, err := net.SplitHostPort(target); err == nil {
+		host = h
+	}
+	if !strings.Contains(strings.ToLower(host), "xn--") {
+		return ""
+	}
+	unicode, err := idna.Display.ToUnicode(host)
+	if err != nil || unicode == host {
+		return ""
+	}
+	return strings.Replace(target, host, unicode, 1)
+}
@@ -0,0 +1,178 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// 日志级别
+type Level = slog.Level
+
+const (
+	LevelDebug = slog.LevelDebug
+	LevelInfo  = slog.LevelInfo
+	LevelWarn  = slog.LevelWarn
+	LevelError = slog.LevelError
+)
+
+// 解析日志级别名称: debug|info|warn|error
+func ParseLevel(s string) (Level, error) {
+	var level Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return LevelInfo, fmt.Errorf("无效的日志级别: %s (可选: debug, info, warn, error)", s)
+	}
+	return level, nil
+}
+
+// 分级日志：终端保留原有的提示格式，日志文件（可选）记录带时间和级别的结构化日志
+type Logger struct {
+	slog *slog.Logger
+}
+
+// 创建只输出到终端的日志记录器
+func NewLogger(level Level) *Logger {
+	return &Logger{slog: slog.New(&consoleHandler{level: level})}
+}
+
+// 创建同时输出到终端和日志文件的日志记录器
+func NewFileLogger(consoleLevel Level, w io.Writer, fileLevel Level, jsonFormat bool) *Logger {
+	opts := &slog.HandlerOptions{Level: fileLevel}
+	var fileHandler slog.Handler = slog.NewTextHandler(w, opts)
+	if jsonFormat {
+		fileHandler = slog.NewJSONHandler(w, opts)
+	}
+	return &Logger{slog: slog.New(multiHandler{&consoleHandler{level: consoleLevel}, fileHandler})}
+}
+
+// 全局日志记录器，由 main 根据命令行参数设置
+var defaultLogger = NewLogger(LevelInfo)
+
+// 设置全局日志记录器
+func SetLogger(l *Logger) {
+	defaultLogger = l
+}
+
+// 获取全局日志记录器
+func Log() *Logger {
+	return defaultLogger
+}
+
+// 判断指定级别的消息是否会被输出
+func (l *Logger) Enabled(level Level) bool {
+	return l != nil && l.slog.Enabled(context.Background(), level)
+}
+
+func (l *Logger) logf(level Level, format string, a ...interface{}) {
+	if l.Enabled(level) {
+		l.slog.Log(context.Background(), level, fmt.Sprintf(format, a...))
+	}
+}
+
+// 调试信息（如每个任务的进度），只在详细模式下输出到终端
+func (l *Logger) Debugf(format string, a ...interface{}) {
+	l.logf(LevelDebug, format, a...)
+}
+
+func (l *Logger) Infof(format string, a ...interface{}) {
+	l.logf(LevelInfo, format, a...)
+}
+
+func (l *Logger) Warnf(format string, a ...interface{}) {
+	l.logf(LevelWarn, format, a...)
+}
+
+func (l *Logger) Errorf(format string, a ...interface{}) {
+	l.logf(LevelError, format, a...)
+}
+
+// 记录带键值对属性的结构化日志，如 Record(LevelDebug, "检测失败", "domain", d, "error", err)
+func (l *Logger) Record(level Level, msg string, args ...interface{}) {
+	if l.Enabled(level) {
+		l.slog.Log(context.Background(), level, msg, args...)
+	}
+}
+
+// 终端输出：原样打印消息（保留表情符号提示），附加属性以 key=value 形式追加
+type consoleHandler struct {
+	level Level
+	attrs []slog.Attr
+}
+
+func (h *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *consoleHandler) Handle(_ context.Context, r slog.Record) error {
+	var sb strings.Builder
+	sb.WriteString(strings.TrimRight(r.Message, "\n"))
+	appendAttr := func(a slog.Attr) bool {
+		fmt.Fprintf(&sb, " %s=%v", a.Key, a.Value)
+		return true
+	}
+	for _, a := range h.attrs {
+		appendAttr(a)
+	}
+	r.Attrs(appendAttr)
+	Printf("%s\n", sb.String())
+	return nil
+}
+
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &consoleHandler{level: h.level, attrs: append(append([]slog.Attr{}, h.attrs...), attrs...)}
+}
+
+func (h *consoleHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+// 同时分发到多个处理器
+type multiHandler []slog.Handler
+
+func (m multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range m {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	for _, h := range m {
+		if !h.Enabled(ctx, r.Level) {
+			continue
+		}
+		rec := r
+		if _, ok := h.(*consoleHandler); !ok {
+			// 日志文件中去掉首尾的换行和空白
+			rec = slog.NewRecord(r.Time, r.Level, strings.TrimSpace(r.Message), r.PC)
+			r.Attrs(func(a slog.Attr) bool {
+				rec.AddAttrs(a)
+				return true
+			})
+		}
+		if err := h.Handle(ctx, rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(multiHandler, len(m))
+	for i, h := range m {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (m multiHandler) WithGroup(name string) slog.Handler {
+	handlers := make(multiHandler, len(m))
+	for i, h := range m {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
@@ -0,0 +1,146 @@
+package utils
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 采样系统内存和等待内存回落时的轮询间隔
+const memoryGuardInterval = time.Second
+
+// 限流开始和结束的日志最多每隔这么久输出一次
+const throttleLogInterval = time.Minute
+
+// 内存预算（-max-memory）的限流器：本次运行（本程序和它启动的Chrome）占用的内存接近预算时，
+// 暂缓开始新的截图和检测，等进行中的任务释放内存。nil 表示不限流，可在多个goroutine中使用
+type MemoryGuard struct {
+	limit    uint64
+	baseline uint64 // 创建时系统已用内存中不属于本进程的部分，之后的增长都算作本次运行占用
+
+	mu        sync.Mutex
+	sampledAt time.Time
+	used      uint64
+	waiters   int       // 正在等待内存回落的调用数
+	since     time.Time // 本次限流开始的时间
+	throttled time.Duration
+	lastLog   time.Time
+}
+
+// 创建预算为 limit 字节的限流器，无法读取系统内存时返回错误
+func NewMemoryGuard(limit uint64) (*MemoryGuard, error) {
+	info := SystemMemory()
+	if info.Estimated {
+		return nil, fmt.Errorf("无法读取系统内存")
+	}
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	used := info.Total - info.Available
+	return &MemoryGuard{limit: limit, baseline: used - min(used, ms.Sys)}, nil
+}
+
+// 本次运行占用的内存估计值，每秒最多采样一次，调用方需持有 g.mu
+func (g *MemoryGuard) usage() uint64 {
+	if time.Since(g.sampledAt) >= memoryGuardInterval {
+		info := SystemMemory()
+		used := info.Total - info.Available
+		g.used = used - min(used, g.baseline)
+		g.sampledAt = time.Now()
+	}
+	return g.used
+}
+
+// 占用超过预算的 fraction 且 busy 返回true时阻塞，直到内存回落。
+// busy 为false（没有进行中的任务可以释放内存）时直接返回，避免永远等待
+func (g *MemoryGuard) Wait(fraction float64, busy func() bool) {
+	if g == nil {
+		return
+	}
+	threshold := uint64(float64(g.limit) * fraction)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	waiting := false
+	for g.usage() > threshold && busy() {
+		if !waiting {
+			waiting = true
+			g.enter()
+		}
+		g.mu.Unlock()
+		time.Sleep(memoryGuardInterval)
+		g.mu.Lock()
+	}
+	if waiting {
+		g.leave()
+	}
+}
+
+// 开始等待，第一个等待者开始计时，调用方需持有 g.mu
+func (g *MemoryGuard) enter() {
+	g.waiters++
+	if g.waiters > 1 {
+		return
+	}
+	g.since = time.Now()
+	if time.Since(g.lastLog) >= throttleLogInterval {
+		Log().Warnf("⚠️  内存占用 %.1fGB 接近上限 %.1fGB (-max-memory)，暂缓开始新的截图和检测\n",
+			BytesToGB(g.used), BytesToGB(g.limit))
+		g.lastLog = time.Now()
+	}
+}
+
+// 结束等待，最后一个等待者结束时累计限流时间，调用方需持有 g.mu
+func (g *MemoryGuard) leave() {
+	g.waiters--
+	if g.waiters > 0 {
+		return
+	}
+	g.throttled += time.Since(g.since)
+	if time.Since(g.lastLog) >= throttleLogInterval {
+		Log().Infof("内存占用回落到 %.1fGB，恢复正常速度（累计限流 %s）\n",
+			BytesToGB(g.used), g.throttled.Round(time.Second))
+		g.lastLog = time.Now()
+	}
+}
+
+// 累计的限流时间（多个任务同时等待只计一次），包括正在进行的限流
+func (g *MemoryGuard) ThrottledTime() time.Duration {
+	if g == nil {
+		return 0
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.waiters > 0 {
+		return g.throttled + time.Since(g.since)
+	}
+	return g.throttled
+}
+
+// 内存大小的单位，不区分大小写，按1024进位
+var sizeUnits = []struct {
+	suffix string
+	bytes  float64
+}{
+	{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+	{"T", 1 << 40}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1},
+}
+
+// 解析内存大小，如 8GB、512MB、1.5G，不带单位时按字节
+func ParseSize(s string) (uint64, error) {
+	text := strings.ToUpper(strings.TrimSpace(s))
+	multiplier := 1.0
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(text, unit.suffix) {
+			text = strings.TrimSpace(strings.TrimSuffix(text, unit.suffix))
+			multiplier = unit.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(text, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("无效的内存大小: %q（如 8GB、512MB）", s)
+	}
+	return uint64(n * multiplier), nil
+}
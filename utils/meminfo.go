@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// 解析 /proc/meminfo 格式的内容，旧内核没有 MemAvailable 时用 MemFree+Buffers+Cached 近似
+func parseMeminfo(r io.Reader) (MemoryInfo, error) {
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		// 格式: "MemTotal:       16318480 kB"
+		name, rest, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		if kb, err := strconv.ParseUint(fields[0], 10, 64); err == nil {
+			values[name] = kb * 1024
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return MemoryInfo{}, err
+	}
+	if values["MemTotal"] == 0 {
+		return MemoryInfo{}, fmt.Errorf("/proc/meminfo 中没有 MemTotal")
+	}
+
+	available, ok := values["MemAvailable"]
+	if !ok {
+		available = values["MemFree"] + values["Buffers"] + values["Cached"]
+	}
+	return MemoryInfo{Total: values["MemTotal"], Available: available}, nil
+}
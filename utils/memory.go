@@ -0,0 +1,41 @@
+package utils
+
+// 系统内存（字节）
+type MemoryInfo struct {
+	Total     uint64
+	Available uint64 // 可分配给新进程的内存（含可回收的缓存），无法单独获取时与 Total 相同
+	Estimated bool   // 无法读取系统内存，Total 和 Available 为估计值
+}
+
+// 无法读取系统内存时使用的估计值，取较保守的 8GB
+const estimatedMemory = 8 << 30
+
+// 读取系统内存的方式，各系统的实现为 readSystemMemory，测试中可以替换
+var readMemory = readSystemMemory
+
+// 读取系统的总内存和可用内存，不启动外部进程；读取失败时返回标记为估计值的结果
+func SystemMemory() MemoryInfo {
+	info, err := readMemory()
+	if err != nil || info.Total == 0 {
+		Log().Debugf("读取系统内存失败: %v\n", err)
+		return MemoryInfo{Total: estimatedMemory, Available: estimatedMemory, Estimated: true}
+	}
+	if info.Available == 0 || info.Available > info.Total {
+		info.Available = info.Total
+	}
+	return info
+}
+
+// 按内存压力的百分比（macOS的 kern.memorystatus_level，1 到 100）估算可用内存，百分比超出范围时返回false。
+// 结果等于 total*level/100（分成整百和余数两部分计算以免溢出），不会因为先除以100而丢掉总内存中不足100字节的部分
+func availableFromPressure(total uint64, level uint32) (uint64, bool) {
+	if level < 1 || level > 100 {
+		return 0, false
+	}
+	return total/100*uint64(level) + total%100*uint64(level)/100, true
+}
+
+// 字节数转换为GB
+func BytesToGB(n uint64) float64 {
+	return float64(n) / (1 << 30)
+}
@@ -0,0 +1,19 @@
+package utils
+
+import "golang.org/x/sys/unix"
+
+// 通过 sysctl 读取物理内存。macOS会把空闲内存用作缓存，空闲页数远小于实际可用的内存，
+// 可用内存按内存压力的百分比（kern.memorystatus_level，活动监视器中的"内存压力"）估算，读不到时按总内存计算
+func readSystemMemory() (MemoryInfo, error) {
+	total, err := unix.SysctlUint64("hw.memsize")
+	if err != nil {
+		return MemoryInfo{}, err
+	}
+	info := MemoryInfo{Total: total, Available: total}
+	if level, err := unix.SysctlUint32("kern.memorystatus_level"); err == nil {
+		if available, ok := availableFromPressure(total, level); ok {
+			info.Available = available
+		}
+	}
+	return info, nil
+}
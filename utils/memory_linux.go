@@ -0,0 +1,13 @@
+package utils
+
+import "os"
+
+// 从 /proc/meminfo 读取内存
+func readSystemMemory() (MemoryInfo, error) {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return MemoryInfo{}, err
+	}
+	defer file.Close()
+	return parseMeminfo(file)
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !windows && !darwin
+
+package utils
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// 其他系统不支持读取系统内存，由 SystemMemory 使用估计值
+func readSystemMemory() (MemoryInfo, error) {
+	return MemoryInfo{}, fmt.Errorf("不支持在 %s 上读取系统内存", runtime.GOOS)
+}
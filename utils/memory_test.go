@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// 解析 /proc/meminfo：有 MemAvailable 时直接使用，旧内核没有时用 MemFree+Buffers+Cached 近似
+func TestParseMeminfo(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    MemoryInfo
+		wantErr bool
+	}{
+		{
+			"MemAvailable",
+			"MemTotal:       16318480 kB\nMemFree:         1024000 kB\nMemAvailable:    8159240 kB\nBuffers:          204800 kB\nCached:          4096000 kB\n",
+			MemoryInfo{Total: 16318480 << 10, Available: 8159240 << 10},
+			false,
+		},
+		{
+			"旧内核",
+			"MemTotal:        4046060 kB\nMemFree:          512000 kB\nBuffers:          102400 kB\nCached:           921600 kB\nSwapCached:            0 kB\n",
+			MemoryInfo{Total: 4046060 << 10, Available: (512000 + 102400 + 921600) << 10},
+			false,
+		},
+		{"没有MemTotal", "MemFree:          512000 kB\n", MemoryInfo{}, true},
+		{"空内容", "", MemoryInfo{}, true},
+		{"格式错误的行", "garbage\nMemTotal:\nMemTotal: 2048 kB\n", MemoryInfo{Total: 2 << 20}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMeminfo(strings.NewReader(tt.content))
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("得到 %+v, %v，应为 %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+// 读取失败或总内存为0时使用估计值；可用内存为0或超过总内存时按总内存计算
+func TestSystemMemoryFallback(t *testing.T) {
+	defer func(read func() (MemoryInfo, error)) { readMemory = read }(readMemory)
+	estimated := MemoryInfo{Total: estimatedMemory, Available: estimatedMemory, Estimated: true}
+	tests := []struct {
+		name string
+		info MemoryInfo
+		err  error
+		want MemoryInfo
+	}{
+		{"读取失败", MemoryInfo{}, errors.New("不支持"), estimated},
+		{"总内存为0", MemoryInfo{Available: 1 << 30}, nil, estimated},
+		{"正常", MemoryInfo{Total: 16 << 30, Available: 6 << 30}, nil, MemoryInfo{Total: 16 << 30, Available: 6 << 30}},
+		{"可用内存为0", MemoryInfo{Total: 16 << 30}, nil, MemoryInfo{Total: 16 << 30, Available: 16 << 30}},
+		{"可用内存超过总内存", MemoryInfo{Total: 4 << 30, Available: 8 << 30}, nil, MemoryInfo{Total: 4 << 30, Available: 4 << 30}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readMemory = func() (MemoryInfo, error) { return tt.info, tt.err }
+			if got := SystemMemory(); got != tt.want {
+				t.Errorf("得到 %+v，应为 %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 按内存压力百分比估算的可用内存：百分比限定在 1 到 100，结果与 total*level/100 相同
+func TestAvailableFromPressure(t *testing.T) {
+	tests := []struct {
+		total  uint64
+		level  uint32
+		want   uint64
+		wantOK bool
+	}{
+		{16 << 30, 50, 8 << 30, true},
+		{16 << 30, 100, 16 << 30, true},
+		{16 << 30, 1, (16 << 30) / 100, true},
+		{199, 50, 99, true},
+		{99, 100, 99, true},
+		{99, 50, 49, true},
+		{16 << 30, 0, 0, false},
+		{16 << 30, 101, 0, false},
+		{1<<64 - 1, 100, 1<<64 - 1, true},
+	}
+	for _, tt := range tests {
+		got, ok := availableFromPressure(tt.total, tt.level)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("availableFromPressure(%d, %d) = %d, %v，应为 %d, %v", tt.total, tt.level, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+// 在本机上实际读取系统内存（不替换 readMemory），结果应在合理范围内；使用 memory_other.go 的系统跳过
+func TestReadSystemMemoryHost(t *testing.T) {
+	switch runtime.GOOS {
+	case "linux", "windows", "darwin":
+	default:
+		t.Skipf("不支持在 %s 上读取系统内存", runtime.GOOS)
+	}
+	info, err := readSystemMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Total <= 256<<20 || info.Total >= 64<<40 {
+		t.Errorf("总内存为 %d 字节，不在 256MiB 到 64TiB 之间", info.Total)
+	}
+	if info.Available == 0 || info.Available > info.Total {
+		t.Errorf("可用内存为 %d 字节，总内存为 %d 字节", info.Available, info.Total)
+	}
+	if info.Estimated {
+		t.Error("实际读取的结果不应标记为估计值")
+	}
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// GlobalMemoryStatusEx 使用的 MEMORYSTATUSEX 结构
+type memoryStatusEx struct {
+	length               uint32
+	memoryLoad           uint32
+	totalPhys            uint64
+	availPhys            uint64
+	totalPageFile        uint64
+	availPageFile        uint64
+	totalVirtual         uint64
+	availVirtual         uint64
+	availExtendedVirtual uint64
+}
+
+var procGlobalMemoryStatusEx = windows.NewLazySystemDLL("kernel32.dll").NewProc("GlobalMemoryStatusEx")
+
+// 通过 GlobalMemoryStatusEx 读取物理内存（不依赖已被移除的 wmic 或可能被策略禁用的 PowerShell）
+func readSystemMemory() (MemoryInfo, error) {
+	status := memoryStatusEx{}
+	status.length = uint32(unsafe.Sizeof(status))
+	if ok, _, err := procGlobalMemoryStatusEx.Call(uintptr(unsafe.Pointer(&status))); ok == 0 {
+		return MemoryInfo{}, err
+	}
+	return MemoryInfo{Total: status.totalPhys, Available: status.availPhys}, nil
+}
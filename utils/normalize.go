@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"net"
+	"net/url"
+	"strings"
+)
+
+// 各协议的默认端口
+var defaultPorts = map[string]string{"http": "80", "https": "443"}
+
+// 归一化目标用于去重，Example.COM、example.com.、example.com:443 和 https://example.com/ 都得到 example.com：
+// 去掉协议（URL中的路径一并去掉），主机名转为小写并去掉末尾的点，去掉默认端口
+// （URL按协议判断，不带协议时 80 和 443 都视为默认端口），以及末尾的斜杠
+func NormalizeTarget(target string) string {
+	target = strings.TrimSpace(target)
+	scheme := ""
+	if lower := strings.ToLower(target); strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") {
+		u, err := url.Parse(target)
+		if err != nil || u.Host == "" {
+			return target
+		}
+		scheme, target = strings.ToLower(u.Scheme), u.Host
+	}
+
+	// 不带协议的目标可以带路径，路径保持原样，只去掉末尾的斜杠
+	hostPort, path, _ := strings.Cut(target, "/")
+	path = strings.TrimRight(path, "/")
+
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		host, port = hostPort, ""
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if scheme != "" && port == defaultPorts[scheme] || scheme == "" && (port == "80" || port == "443") {
+		port = ""
+	}
+
+	normalized := host
+	if port != "" {
+		normalized = net.JoinHostPort(host, port)
+	} else if err == nil && strings.Contains(host, ":") {
+		// 去掉默认端口的IPv6地址保留方括号
+		normalized = "[" + host + "]"
+	}
+	if path != "" {
+		normalized += "/" + path
+	}
+	return normalized
+}
+
+// 为不带端口的目标主机（域名、IPv4地址或IPv6地址，IPv6地址可以带方括号）加上端口
+func WithPort(host, port string) string {
+	return net.JoinHostPort(strings.Trim(host, "[]"), port)
+}
@@ -0,0 +1,51 @@
+package utils
+
+import "testing"
+
+// 主机加端口：IPv6地址不论是否带方括号都只加一层方括号
+func TestWithPort(t *testing.T) {
+	tests := []struct{ host, port, want string }{
+		{"example.com", "8080", "example.com:8080"},
+		{"192.0.2.1", "80", "192.0.2.1:80"},
+		{"[::1]", "18443", "[::1]:18443"},
+		{"::1", "18443", "[::1]:18443"},
+		{"[2001:db8::1]", "80", "[2001:db8::1]:80"},
+	}
+	for _, tt := range tests {
+		if got := WithPort(tt.host, tt.port); got != tt.want {
+			t.Errorf("WithPort(%q, %q) = %q，应为 %q", tt.host, tt.port, got, tt.want)
+		}
+	}
+}
+
+// 归一化用于去重：大小写、末尾的点、默认端口和末尾的斜杠不同的写法得到同一个目标
+func TestNormalizeTarget(t *testing.T) {
+	tests := []struct{ target, want string }{
+		{"example.com", "example.com"},
+		{"  Example.COM  ", "example.com"},
+		{"example.com.", "example.com"},
+		{"example.com/", "example.com"},
+		{"example.com:443", "example.com"},
+		{"example.com:80", "example.com"},
+		{"example.com:8080", "example.com:8080"},
+		{"EXAMPLE.com.:8443/", "example.com:8443"},
+		{"https://example.com/", "example.com"},
+		{"HTTPS://Example.com:443", "example.com"},
+		{"http://example.com:80/", "example.com"},
+		{"https://example.com:80", "example.com:80"},
+		{"http://example.com:443", "example.com:443"},
+		{"https://example.com/login?next=/", "example.com"},
+		{"example.com/Admin/", "example.com/Admin"},
+		{"example.com:443/api//", "example.com/api"},
+		{"192.0.2.1:80", "192.0.2.1"},
+		{"[::1]:443", "[::1]"},
+		{"[2001:DB8::1]:8443", "[2001:db8::1]:8443"},
+		{"https://[::1]:443/", "[::1]"},
+		{"https://", "https://"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeTarget(tt.target); got != tt.want {
+			t.Errorf("NormalizeTarget(%q) = %q，应为 %q", tt.target, got, tt.want)
+		}
+	}
+}
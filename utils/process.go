@@ -0,0 +1,7 @@
+package utils
+
+// 进程及其全部子进程的常驻内存之和（字节），用于统计Chrome实例（浏览器进程加渲染、GPU等子进程）的实际占用。
+// 共享的内存页在每个进程中都会计入，结果偏大，适合作为保守的估计
+func ProcessTreeMemory(pid int) (uint64, error) {
+	return readProcessTreeMemory(pid)
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 从 /proc 读取进程的父子关系，累加进程树中每个进程 statm 里的常驻页数
+func readProcessTreeMemory(pid int) (uint64, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0, err
+	}
+	children := make(map[int][]int)
+	for _, entry := range entries {
+		child, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile("/proc/" + entry.Name() + "/stat")
+		if err != nil {
+			continue // 进程已退出
+		}
+		// 格式: "pid (comm) state ppid ..."，comm 中可能包含空格和括号
+		i := strings.LastIndexByte(string(data), ')')
+		if i < 0 {
+			continue
+		}
+		fields := strings.Fields(string(data[i+1:]))
+		if len(fields) < 2 {
+			continue
+		}
+		if parent, err := strconv.Atoi(fields[1]); err == nil {
+			children[parent] = append(children[parent], child)
+		}
+	}
+
+	var total uint64
+	found := false
+	pageSize := uint64(os.Getpagesize())
+	for queue := []int{pid}; len(queue) > 0; queue = queue[1:] {
+		current := queue[0]
+		queue = append(queue, children[current]...)
+		data, err := os.ReadFile(fmt.Sprintf("/proc/%d/statm", current))
+		if err != nil {
+			continue
+		}
+		// 格式: "size resident shared text lib data dt"，单位为页
+		fields := strings.Fields(string(data))
+		if len(fields) < 2 {
+			continue
+		}
+		if pages, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			total += pages * pageSize
+			found = true
+		}
+	}
+	if !found {
+		return 0, fmt.Errorf("进程 %d 不存在", pid)
+	}
+	return total, nil
+}
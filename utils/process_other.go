@@ -0,0 +1,13 @@
+//go:build !linux && !windows
+
+package utils
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// 其他系统不支持读取进程树的内存，调用方继续使用假定值
+func readProcessTreeMemory(pid int) (uint64, error) {
+	return 0, fmt.Errorf("不支持在 %s 上读取进程内存", runtime.GOOS)
+}
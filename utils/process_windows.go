@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// K32GetProcessMemoryInfo 使用的 PROCESS_MEMORY_COUNTERS 结构
+type processMemoryCounters struct {
+	cb                         uint32
+	pageFaultCount             uint32
+	peakWorkingSetSize         uintptr
+	workingSetSize             uintptr
+	quotaPeakPagedPoolUsage    uintptr
+	quotaPagedPoolUsage        uintptr
+	quotaPeakNonPagedPoolUsage uintptr
+	quotaNonPagedPoolUsage     uintptr
+	pagefileUsage              uintptr
+	peakPagefileUsage          uintptr
+}
+
+var procGetProcessMemoryInfo = windows.NewLazySystemDLL("kernel32.dll").NewProc("K32GetProcessMemoryInfo")
+
+// 通过进程快照得到父子关系，累加进程树中每个进程的工作集
+func readProcessTreeMemory(pid int) (uint64, error) {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer windows.CloseHandle(snapshot)
+
+	children := make(map[uint32][]uint32)
+	entry := windows.ProcessEntry32{Size: uint32(unsafe.Sizeof(windows.ProcessEntry32{}))}
+	for err = windows.Process32First(snapshot, &entry); err == nil; err = windows.Process32Next(snapshot, &entry) {
+		children[entry.ParentProcessID] = append(children[entry.ParentProcessID], entry.ProcessID)
+	}
+
+	var total uint64
+	found := false
+	for queue := []uint32{uint32(pid)}; len(queue) > 0; queue = queue[1:] {
+		current := queue[0]
+		queue = append(queue, children[current]...)
+		handle, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, current)
+		if err != nil {
+			continue
+		}
+		counters := processMemoryCounters{}
+		counters.cb = uint32(unsafe.Sizeof(counters))
+		ok, _, _ := procGetProcessMemoryInfo.Call(uintptr(handle), uintptr(unsafe.Pointer(&counters)), uintptr(counters.cb))
+		windows.CloseHandle(handle)
+		if ok != 0 {
+			total += uint64(counters.workingSetSize)
+			found = true
+		}
+	}
+	if !found {
+		return 0, fmt.Errorf("无法读取进程 %d 的内存", pid)
+	}
+	return total, nil
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// 按比例随机抽取目标，保持原有顺序；相同的种子得到相同的结果。至少保留一个目标
+func SampleTargets(targets []string, fraction float64, seed int64) []string {
+	n := int(math.Round(fraction * float64(len(targets))))
+	n = max(1, min(n, len(targets)))
+	if n == len(targets) {
+		return targets
+	}
+	picked := rand.New(rand.NewSource(seed)).Perm(len(targets))[:n]
+	sort.Ints(picked)
+	sampled := make([]string, n)
+	for i, idx := range picked {
+		sampled[i] = targets[idx]
+	}
+	return sampled
+}
+
+// 按目标的哈希把目标分为 count 片，返回第 index 片（从0开始），保持原有顺序。
+// 只取决于目标本身，相同的目标列表和分片数在任何机器上得到相同的划分
+func ShardTargets(targets []string, index, count int) []string {
+	var shard []string
+	for _, target := range targets {
+		if ShardOf(target, count) == index {
+			shard = append(shard, target)
+		}
+	}
+	return shard
+}
+
+// 目标所属的分片（从0开始）
+func ShardOf(target string, count int) int {
+	h := fnv.New64a()
+	h.Write([]byte(target))
+	return int(h.Sum64() % uint64(count))
+}
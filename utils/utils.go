@@ -2,38 +2,79 @@ package utils
 
 import (
 	"bufio"
+	"io"
 	"os"
 	"strings"
 )
 
 // 从文件中读取域名
 func ReadDomainsFromFile(filename string) ([]string, error) {
+	domains, _, err := ReadAnnotatedDomainsFromFile(filename)
+	return domains, err
+}
+
+// 按行读取域名，忽略空行、以#开头的注释行和行尾的备注
+func ReadDomains(r io.Reader) ([]string, error) {
+	domains, _, err := ReadAnnotatedDomains(r)
+	return domains, err
+}
+
+// 从文件读取域名和行尾备注
+func ReadAnnotatedDomainsFromFile(filename string) ([]string, map[string]string, error) {
 	file, err := os.Open(filename)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer file.Close()
+	return ReadAnnotatedDomains(file)
+}
 
+// 按行读取域名，忽略空行和以#开头的注释行。行尾的备注（空白后的 # 注释，或制表符分隔的第二列）
+// 以目标为键返回，没有备注的目标不在 notes 中；同一目标出现多次时保留第一条备注
+func ReadAnnotatedDomains(r io.Reader) ([]string, map[string]string, error) {
 	var domains []string
-	scanner := bufio.NewScanner(file)
+	notes := make(map[string]string)
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
-		domain := strings.TrimSpace(scanner.Text())
-		if domain != "" && !strings.HasPrefix(domain, "#") {
-			domains = append(domains, domain)
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domain, note := splitAnnotation(line)
+		if domain == "" {
+			continue
+		}
+		domains = append(domains, domain)
+		if _, ok := notes[domain]; note != "" && !ok {
+			notes[domain] = note
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return domains, nil
+	return domains, notes, nil
 }
 
-// 截断字符串到指定长度
+// 拆分一行中的目标和备注。URL 中的 #（如 https://host/#/login）前面没有空白，不会被当作备注
+func splitAnnotation(line string) (string, string) {
+	if target, note, ok := strings.Cut(line, "\t"); ok {
+		return strings.TrimSpace(target), strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(note), "#"))
+	}
+	for i := 1; i < len(line); i++ {
+		if line[i] == '#' && (line[i-1] == ' ' || line[i-1] == '\t') {
+			return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		}
+	}
+	return line, ""
+}
+
+// 截断字符串到指定长度（按字符计算，避免截断多字节字符）
 func Truncate(s string, maxLen int) string {
-	if len(s) <= maxLen {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
 		return s
 	}
-	return s[:maxLen-3] + "..."
-}
\ No newline at end of file
+	return string(runes[:maxLen-3]) + "..."
+}
@@ -0,0 +1,289 @@
+package view
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"subdomain-checker/checker"
+	"subdomain-checker/config"
+	"subdomain-checker/utils"
+)
+
+// 读取上一次运行的结果作为基线，支持本工具输出的JSON、CSV、Excel（CSV和JSON可为 .gz 压缩）以及每行一个域名的纯文本列表。
+// 纯文本列表中的域名全部视为存活；旧版本输出中缺少的列保持零值
+func LoadBaseline(filename string) ([]checker.Result, error) {
+	return LoadResults(filename, InputAuto)
+}
+
+// 按指定格式读取结果文件，InputAuto 时根据扩展名和内容自动判断
+func LoadResults(filename, format string) ([]checker.Result, error) {
+	results, _, err := LoadReport(filename, format)
+	return results, err
+}
+
+// 读取结果文件和其中的运行元数据，只有JSON输出带有可读取的元数据，其余格式返回的元数据为nil
+func LoadReport(filename, format string) ([]checker.Result, *RunMeta, error) {
+	if format == InputExcel || (format == InputAuto && strings.EqualFold(filepath.Ext(filename), ".xlsx")) {
+		results, err := parseExcelResults(filename)
+		return results, nil, err
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if strings.HasSuffix(filename, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("解压文件 %s 失败: %v", filename, err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})
+
+	// 跳过CSV开头的运行元数据注释行
+	trimmed := bytes.TrimSpace(data)
+	for bytes.HasPrefix(trimmed, []byte("#")) {
+		end := bytes.IndexByte(trimmed, '\n')
+		if end < 0 {
+			return nil, nil, nil
+		}
+		trimmed = bytes.TrimSpace(trimmed[end+1:])
+	}
+	if len(trimmed) == 0 {
+		return nil, nil, nil
+	}
+	if format == InputAuto {
+		switch {
+		case hasJSONExt(filename), looksLikeJSON(trimmed):
+			format = InputJSON
+		case bytes.HasPrefix(trimmed, []byte("域名,")):
+			format = InputCSV
+		default:
+			format = InputText
+		}
+	}
+	switch format {
+	case InputJSON:
+		return parseJSONBaseline(trimmed)
+	case InputCSV:
+		results, err := parseCSVBaseline(trimmed)
+		return results, nil, err
+	default:
+		return parseListBaseline(trimmed), nil, nil
+	}
+}
+
+// 文件扩展名（去掉 .gz 后）是否为 .json 或 .jsonl
+func hasJSONExt(filename string) bool {
+	ext := strings.ToLower(filepath.Ext(strings.TrimSuffix(filename, ".gz")))
+	return ext == ".json" || ext == ".jsonl"
+}
+
+// 内容是否为JSON文档或JSONL结果。以 [ 开头的纯文本列表（如第一行是 [::1] 这样的IPv6地址）不是JSON
+func looksLikeJSON(data []byte) bool {
+	if !bytes.HasPrefix(data, []byte("[")) && !bytes.HasPrefix(data, []byte("{")) {
+		return false
+	}
+	return isJSONLines(data) || json.Valid(data)
+}
+
+// 解析JSON格式的基线，支持带运行元数据的对象、旧版本输出的结果数组和 -jsonl 的逐行结果
+func parseJSONBaseline(data []byte) ([]checker.Result, *RunMeta, error) {
+	var items []JSONResult
+	var meta *RunMeta
+	if isJSONLines(data) {
+		items = parseJSONLines(data)
+	} else if data[0] == '{' {
+		var report JSONReport
+		if err := json.Unmarshal(data, &report); err != nil {
+			return nil, nil, fmt.Errorf("解析JSON基线失败: %v", err)
+		}
+		items, meta = report.Results, report.Meta
+	} else if err := json.Unmarshal(data, &items); err != nil {
+		return nil, nil, fmt.Errorf("解析JSON基线失败: %v", err)
+	}
+	results := make([]checker.Result, 0, len(items))
+	for _, item := range items {
+		result := checker.Result{
+			Domain:       item.Domain,
+			FinalURL:     item.FinalURL,
+			Alive:        item.Alive,
+			Status:       item.Status,
+			StatusText:   item.StatusText,
+			ResponseTime: time.Duration(item.ResponseTimeMs) * time.Millisecond,
+			Title:        item.Title,
+			Message:      item.Message,
+			Screenshot:   item.Screenshot,
+			IDN:          item.IDN,
+			ErrorClass:   item.ErrorClass,
+			Note:         item.Note,
+			Override:     item.Override,
+			Response:     item.Response,
+			IPs:          item.IPs,
+			UserAgent:    item.UserAgent,
+			Proxy:        item.Proxy,
+			Method:       item.Method,
+		}
+		result.CapturedHeaders = item.Headers
+		result.Fingerprints = item.Fingerprints
+		result.FaviconHash = item.FaviconHash
+		result.RedirectChain, result.RedirectCrossSite = item.RedirectChain, item.CrossSite
+		result.BodySize, result.BodySizeApprox = -1, false
+		if item.BodySize != nil {
+			result.BodySize, result.BodySizeApprox = *item.BodySize, item.BodySizeApprox
+		}
+		if item.PageType != "" {
+			result.PageInfo = &checker.PageType{Type: item.PageType}
+		}
+		results = append(results, result)
+	}
+	return results, meta, nil
+}
+
+// 解析CSV格式的基线，按表头名称取列，是否存活由状态码判断
+func parseCSVBaseline(data []byte) ([]checker.Result, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("解析CSV失败: %v", err)
+	}
+	return parseResultRows(rows)
+}
+
+// 解析表格形式的结果（CSV或Excel），第一行为表头
+func parseResultRows(rows [][]string) ([]checker.Result, error) {
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	column := make(map[string]int)
+	for i, name := range rows[0] {
+		column[name] = i
+	}
+	if _, ok := column["域名"]; !ok {
+		return nil, fmt.Errorf("表头中没有\"域名\"列")
+	}
+	field := func(row []string, name string) string {
+		if i, ok := column[name]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+
+	results := make([]checker.Result, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		result := checker.Result{
+			Domain:     field(row, "域名"),
+			StatusText: field(row, "状态"),
+			Title:      field(row, "页面标题"),
+			Message:    field(row, "消息"),
+			FinalURL:   field(row, "最终URL"),
+			Screenshot: field(row, "截图"),
+			ErrorClass: field(row, "失败类别"),
+			Input:      field(row, "输入"),
+			IDN:        field(row, "国际化域名"),
+			Note:       field(row, "备注"),
+			Override:   field(row, "覆盖规则"),
+			Response:   field(row, "原始响应"),
+			IPs:        strings.Fields(field(row, "IP地址")),
+			UserAgent:  field(row, "User-Agent"),
+			Proxy:      field(row, "代理"),
+			Method:     field(row, "请求方法"),
+		}
+		if result.Domain == "" {
+			continue
+		}
+		result.Status, _ = strconv.Atoi(field(row, "状态码"))
+		result.FaviconHash = field(row, "图标哈希")
+		result.BodySize, result.BodySizeApprox = parseBodySize(field(row, "响应大小(字节)"))
+		if chain := field(row, "重定向链"); chain != "" {
+			result.RedirectChain = strings.Split(chain, redirectSeparator)
+			result.RedirectCrossSite = checker.CrossSiteChain(result.RedirectChain)
+		}
+		result.Alive = result.Status != 0 && result.Status < 400
+		if ms, err := strconv.ParseFloat(field(row, "响应时间(毫秒)"), 64); err == nil {
+			result.ResponseTime = time.Duration(ms * float64(time.Millisecond))
+		}
+		if pageType := field(row, "页面类型"); pageType != "" {
+			result.PageInfo = &checker.PageType{Type: pageType}
+		}
+		// 技术指纹以逗号分隔，早期版本的CSV中以分号分隔
+		for _, fingerprint := range strings.FieldsFunc(field(row, "技术指纹"), func(r rune) bool { return r == ',' || r == ';' }) {
+			result.Fingerprints = append(result.Fingerprints, strings.TrimSpace(fingerprint))
+		}
+		for _, name := range config.DefaultCapturedHeaders {
+			if value := field(row, name); value != "" {
+				if result.CapturedHeaders == nil {
+					result.CapturedHeaders = make(map[string]string)
+				}
+				result.CapturedHeaders[name] = value
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// 解析CSV和Excel中的响应大小，"~"开头的为取自 Content-Length 的近似值，为空或无法解析时为-1
+func parseBodySize(s string) (int64, bool) {
+	approx := strings.HasPrefix(s, "~")
+	size, err := strconv.ParseInt(strings.TrimPrefix(s, "~"), 10, 64)
+	if err != nil {
+		return -1, false
+	}
+	return size, approx
+}
+
+// 解析每行一个域名的纯文本基线，忽略空行和 # 开头的注释，行尾的备注保存在 Note 中
+func parseListBaseline(data []byte) []checker.Result {
+	domains, notes, _ := utils.ReadAnnotatedDomains(bytes.NewReader(data))
+	results := make([]checker.Result, 0, len(domains))
+	for _, domain := range domains {
+		results = append(results, checker.Result{Domain: domain, Alive: true, Note: notes[domain]})
+	}
+	return results
+}
+
+// 基线匹配使用的键：去掉协议、末尾的斜杠并转为小写，保留端口和路径
+func BaselineKey(domain string) string {
+	key := strings.ToLower(strings.TrimSpace(domain))
+	key = strings.TrimPrefix(key, "https://")
+	key = strings.TrimPrefix(key, "http://")
+	return strings.TrimSuffix(key, "/")
+}
+
+// 获取本次存活但在基线中不存在或未存活的主机
+func NewlyAlive(results, baseline []checker.Result) []checker.Result {
+	aliveBefore := make(map[string]bool, len(baseline))
+	for _, result := range baseline {
+		if result.Alive {
+			aliveBefore[BaselineKey(result.Domain)] = true
+		}
+	}
+	var newly []checker.Result
+	for _, result := range results {
+		if result.Alive && !aliveBefore[BaselineKey(result.Domain)] {
+			newly = append(newly, result)
+		}
+	}
+	return newly
+}
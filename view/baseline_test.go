@@ -0,0 +1,162 @@
+package view
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"subdomain-checker/checker"
+	"subdomain-checker/config"
+)
+
+// 测试用的结果：存活、受保护、无法访问、带端口和路径、国际化域名和带备注的目标
+func sampleResults() []checker.Result {
+	return []checker.Result{
+		{Domain: "https://www.example.com", Input: "www.example.com", Status: 200, Alive: true, StatusText: "存活", Title: "Home, sweet \"home\"", BodySize: 1024},
+		{Domain: "https://admin.example.com", Input: "admin.example.com", Status: 403, StatusText: "受保护", BodySize: -1, Note: "VPN only"},
+		{Domain: "dead.example.com", Input: "dead.example.com", StatusText: "无法访问", ErrorClass: checker.ErrorTimeout, BodySize: -1},
+		{Domain: "http://app.example.com:8080/login", Input: "app.example.com:8080/login", Status: 200, Alive: true, StatusText: "存活", BodySize: 10},
+		{Domain: "https://xn--fiqs8s.example.com", Input: "xn--fiqs8s.example.com", IDN: "https://中国.example.com", Status: 200, Alive: true, StatusText: "存活", BodySize: -1},
+	}
+}
+
+// 每种输出格式写出后再读取，得到的目标集合（归一化后）与写出的相同
+func TestResultFileRoundTrip(t *testing.T) {
+	results := sampleResults()
+	want := make([]string, len(results))
+	for i, result := range results {
+		want[i] = BaselineKey(result.Domain)
+	}
+	slices.Sort(want)
+
+	dir := t.TempDir()
+	cfg := &config.Config{}
+	stats := ComputeRunStats(results, len(results), false, nil, 0)
+	writers := map[string]func(string) error{
+		"out.csv": func(name string) error {
+			return SaveResultsToFile(results, name, ExportFilter{}, &RunMeta{Version: "test"}, nil)
+		},
+		"out.csv.gz": func(name string) error { return SaveResultsToFile(results, name, ExportFilter{}, nil, nil) },
+		"out.json": func(name string) error {
+			return SaveResultsToJSON(results, name, ExportFilter{}, &RunMeta{Version: "test"})
+		},
+		"out.json.gz": func(name string) error { return SaveResultsToJSON(results, name, ExportFilter{}, nil) },
+		"out.xlsx":    func(name string) error { return SaveResultsToExcel(results, name, cfg, &RunMeta{}, stats, nil) },
+		"out.jsonl": func(name string) error {
+			w, err := CreateJSONL(name)
+			if err != nil {
+				return err
+			}
+			if err := w.Write(results); err != nil {
+				return err
+			}
+			return w.Close()
+		},
+	}
+	for name, write := range writers {
+		t.Run(name, func(t *testing.T) {
+			filename := filepath.Join(dir, name)
+			if err := write(filename); err != nil {
+				t.Fatalf("写入失败: %v", err)
+			}
+			targets, notes, err := LoadTargets(filename, InputAuto, false)
+			if err != nil {
+				t.Fatalf("读取失败: %v", err)
+			}
+			got := make([]string, len(targets))
+			for i, target := range targets {
+				got[i] = BaselineKey(target)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, want) {
+				t.Errorf("读取到的目标为 %q，应为 %q", got, want)
+			}
+			if name != "out.xlsx" && notes["https://admin.example.com"] != "VPN only" {
+				t.Errorf("备注为 %q", notes)
+			}
+
+			// 只读取存活的目标
+			alive, _, err := LoadTargets(filename, InputAuto, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(alive) != 3 {
+				t.Errorf("存活的目标为 %q，应为3个", alive)
+			}
+		})
+	}
+}
+
+// 自动判断格式：以 [ 开头但不是JSON的纯文本列表（IPv6地址）按域名列表读取
+func TestLoadResultsDetectsFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+		wantErr bool
+	}{
+		{"ipv6.txt", "[::1]\n[2001:db8::1]:8443\nexample.com\n", []string{"[::1]", "[2001:db8::1]:8443", "example.com"}, false},
+		{"list.txt", "# 注释\nexample.com  # 备注\n", []string{"example.com"}, false},
+		{"array.txt", `[{"domain":"a.example.com","alive":true}]`, []string{"a.example.com"}, false},
+		{"lines.txt", "{\"domain\":\"a.example.com\"}\n{\"domain\":\"b.example.com\"}\n", []string{"a.example.com", "b.example.com"}, false},
+		{"broken.json", `[{"domain":`, nil, true},
+		{"csv.txt", "域名,状态,状态码\nhttps://a.example.com,存活,200\n", []string{"https://a.example.com"}, false},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(dir, tt.name)
+			if err := os.WriteFile(filename, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			results, err := LoadResults(filename, InputAuto)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("错误为 %v", err)
+			}
+			var got []string
+			for _, result := range results {
+				got = append(got, result.Domain)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("读取到 %q，应为 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// 以前版本的结果文件中 401、407 的状态文本为"受保护"，429 为"无法访问"，读取后仍按状态码归类
+func TestLoadResultsProtected(t *testing.T) {
+	files := map[string]string{
+		"old.csv": "域名,状态,状态码\nhttps://a.example.com,受保护,401\nhttps://b.example.com,受保护,403\n" +
+			"https://c.example.com,受保护,407\nhttps://d.example.com,无法访问,429\nhttps://e.example.com,存活,200\n",
+		"old.json": `[{"domain":"https://a.example.com","alive":false,"status":401,"status_text":"受保护"},` +
+			`{"domain":"https://b.example.com","alive":false,"status":403,"status_text":"受保护"},` +
+			`{"domain":"https://c.example.com","alive":false,"status":407,"status_text":"受保护"},` +
+			`{"domain":"https://d.example.com","alive":false,"status":429,"status_text":"无法访问"},` +
+			`{"domain":"https://e.example.com","alive":true,"status":200,"status_text":"存活"}]`,
+	}
+	want := []string{"https://a.example.com", "https://b.example.com", "https://c.example.com"}
+	dir := t.TempDir()
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			filename := filepath.Join(dir, name)
+			if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			results, err := LoadResults(filename, InputAuto)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, result := range results {
+				if checker.IsProtected(result) {
+					got = append(got, result.Domain)
+				}
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("受保护的主机为 %q，应为 %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package view
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+
+	"subdomain-checker/checker"
+	"subdomain-checker/utils"
+)
+
+// 将内容相同（响应内容哈希一致）的结果折叠为一组：保留首次出现的结果作为代表，
+// 其余结果放入代表的 Duplicates 中。代表的选择只取决于结果顺序，相同排序下结果稳定
+func clusterDuplicates(results []TemplateResult) []TemplateResult {
+	clustered := make([]TemplateResult, 0, len(results))
+	index := make(map[string]int)
+	for _, result := range results {
+		if result.contentKey == "" {
+			clustered = append(clustered, result)
+			continue
+		}
+		if i, ok := index[result.contentKey]; ok {
+			clustered[i].Duplicates = append(clustered[i].Duplicates, result)
+			continue
+		}
+		index[result.contentKey] = len(clustered)
+		clustered = append(clustered, result)
+	}
+	return clustered
+}
+
+// 统计每个结果有多少个其他主机的内容与之相同，并为内容相同的主机分配分组编号
+// （从1开始，按主机数从多到少，数量相同时按首次出现的顺序）。返回每组只保留一个主机时隐藏的主机数
+func countSameContent(results []TemplateResult) int {
+	counts := make(map[string]int)
+	var keys []string
+	for _, result := range results {
+		if result.contentKey != "" {
+			if counts[result.contentKey] == 0 {
+				keys = append(keys, result.contentKey)
+			}
+			counts[result.contentKey]++
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return counts[keys[i]] > counts[keys[j]]
+	})
+	groups := make(map[string]int)
+	hidden := 0
+	for _, key := range keys {
+		if counts[key] > 1 {
+			groups[key] = len(groups) + 1
+			hidden += counts[key] - 1
+		}
+	}
+	for i := range results {
+		if key := results[i].contentKey; key != "" {
+			results[i].SameContent = counts[key] - 1
+			results[i].DupGroup = groups[key]
+		}
+	}
+	return hidden
+}
+
+const (
+	clusterSheet    = "重复分组" // 重复分组工作表名称
+	clusterPrintMax = 5      // 终端总结中最多列出的分组数
+)
+
+// 标题和响应内容相同的一组主机，通常是默认页面（噪音）或同一个共享的系统
+type ContentCluster struct {
+	Title    string   // 页面标题（已解码）
+	BodyHash string   // 响应内容的哈希，组内内容不一定相同时（只按标题分组）为空
+	Domains  []string // 组内的主机，第一个为代表（结果中首次出现的主机）
+}
+
+// 按标题和响应内容哈希对结果分组，没有哈希时只按标题分组；标题和哈希都为空的结果不参与分组。
+// 只返回至少有两个主机的分组，按主机数从多到少排列，数量相同时按首次出现的顺序
+func ClusterResults(results []checker.Result) []ContentCluster {
+	var clusters []ContentCluster
+	index := make(map[[2]string]int)
+	for _, result := range results {
+		title := strings.TrimSpace(decodeTitle(result.Title))
+		if title == "" && result.BodyHash == "" {
+			continue
+		}
+		key := [2]string{title, result.BodyHash}
+		i, ok := index[key]
+		if !ok {
+			i = len(clusters)
+			index[key] = i
+			clusters = append(clusters, ContentCluster{Title: title, BodyHash: result.BodyHash})
+		}
+		clusters[i].Domains = append(clusters[i].Domains, result.Domain)
+	}
+
+	shared := clusters[:0]
+	for _, cluster := range clusters {
+		if len(cluster.Domains) > 1 {
+			shared = append(shared, cluster)
+		}
+	}
+	sort.SliceStable(shared, func(i, j int) bool {
+		return len(shared[i].Domains) > len(shared[j].Domains)
+	})
+	return shared
+}
+
+// 分组在终端和Excel中的说明，如 "标题 'Welcome to nginx!'，内容相同"
+func (c ContentCluster) Describe() string {
+	title := "无标题"
+	if c.Title != "" {
+		title = fmt.Sprintf("标题 '%s'", utils.Truncate(c.Title, resultTitleMaxLen))
+	}
+	if c.BodyHash == "" {
+		return title
+	}
+	return title + "，内容相同"
+}
+
+// 写入重复分组工作表：每个主机一行，同一分组的行带有相同的分组编号、代表主机和主机数，便于筛选
+func writeClusterSheet(f *excelize.File, clusters []ContentCluster, headerStyle int) error {
+	if _, err := f.NewSheet(clusterSheet); err != nil {
+		return err
+	}
+	headers := []string{"分组", "代表主机", "主机数", "标题", "内容哈希", "主机"}
+	for i, header := range headers {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+		f.SetCellValue(clusterSheet, cell, header)
+	}
+	f.SetCellStyle(clusterSheet, "A1", "F1", headerStyle)
+	row := 2
+	for n, cluster := range clusters {
+		for _, domain := range cluster.Domains {
+			f.SetCellValue(clusterSheet, fmt.Sprintf("A%d", row), n+1)
+			f.SetCellValue(clusterSheet, fmt.Sprintf("B%d", row), cluster.Domains[0])
+			f.SetCellValue(clusterSheet, fmt.Sprintf("C%d", row), len(cluster.Domains))
+			f.SetCellValue(clusterSheet, fmt.Sprintf("D%d", row), cluster.Title)
+			f.SetCellValue(clusterSheet, fmt.Sprintf("E%d", row), cluster.BodyHash)
+			f.SetCellValue(clusterSheet, fmt.Sprintf("F%d", row), domain)
+			row++
+		}
+	}
+
+	f.SetColWidth(clusterSheet, "A", "A", 8)
+	f.SetColWidth(clusterSheet, "B", "B", 40)
+	f.SetColWidth(clusterSheet, "C", "C", 10)
+	f.SetColWidth(clusterSheet, "D", "D", 40)
+	f.SetColWidth(clusterSheet, "E", "E", 20)
+	f.SetColWidth(clusterSheet, "F", "F", 40)
+	return f.AutoFilter(clusterSheet, fmt.Sprintf("A1:F%d", row-1), nil)
+}
+
+// 图标哈希相同的一组主机，通常是同一种应用或同一套系统
+type FaviconGroup struct {
+	Hash    string   // 图标哈希（与 Shodan 相同的mmh3）
+	Domains []string // 组内的主机，按结果中出现的顺序
+}
+
+// 按图标哈希（-favicon）对结果分组，只返回至少有两个主机的分组，按主机数从多到少排列，数量相同时按首次出现的顺序
+func GroupByFavicon(results []checker.Result) []FaviconGroup {
+	var groups []FaviconGroup
+	index := make(map[string]int)
+	for _, result := range results {
+		if result.FaviconHash == "" {
+			continue
+		}
+		i, ok := index[result.FaviconHash]
+		if !ok {
+			i = len(groups)
+			index[result.FaviconHash] = i
+			groups = append(groups, FaviconGroup{Hash: result.FaviconHash})
+		}
+		groups[i].Domains = append(groups[i].Domains, result.Domain)
+	}
+
+	shared := groups[:0]
+	for _, group := range groups {
+		if len(group.Domains) > 1 {
+			shared = append(shared, group)
+		}
+	}
+	sort.SliceStable(shared, func(i, j int) bool {
+		return len(shared[i].Domains) > len(shared[j].Domains)
+	})
+	return shared
+}
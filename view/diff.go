@@ -0,0 +1,164 @@
+package view
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"subdomain-checker/checker"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// 变化类型
+const (
+	DiffNewAlive = "新存活"
+	DiffDied     = "不再存活"
+	DiffStatus   = "状态码变化"
+	DiffTitle    = "标题变化"
+	DiffPageType = "新页面类型"
+)
+
+const (
+	diffSheet     = "变化" // 变化工作表名称
+	diffPrintMax  = 50   // 终端中最多列出的变化条数
+	diffNoneValue = "-"  // 基线中没有对应值时的显示
+)
+
+// 与基线相比的一项变化
+type DiffEntry struct {
+	Kind   string
+	Domain string
+	Before string
+	After  string
+}
+
+// 与基线相比的全部变化，按变化类型的顺序排列
+type Diff struct {
+	Baseline string // 基线文件名
+	Entries  []DiffEntry
+}
+
+// 各类变化的数量
+func (d *Diff) Count(kind string) int {
+	n := 0
+	for _, entry := range d.Entries {
+		if entry.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// 变化概要，如"新存活 3 个, 不再存活 1 个, ..."
+func (d *Diff) Summary() string {
+	var parts []string
+	for _, kind := range []string{DiffNewAlive, DiffDied, DiffStatus, DiffTitle, DiffPageType} {
+		parts = append(parts, fmt.Sprintf("%s %d 个", kind, d.Count(kind)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// 对比本次结果与基线。只在基线中出现的主机不计为变化（本次可能没有检测）
+func DiffResults(results, baseline []checker.Result, baselineName string) *Diff {
+	before := make(map[string]checker.Result, len(baseline))
+	for _, result := range baseline {
+		before[BaselineKey(result.Domain)] = result
+	}
+
+	var newAlive, died, status, title, pageType []DiffEntry
+	for _, result := range results {
+		old, ok := before[BaselineKey(result.Domain)]
+		if !ok {
+			if result.Alive {
+				newAlive = append(newAlive, DiffEntry{DiffNewAlive, result.Domain, diffNoneValue, diffStatusLabel(result)})
+			}
+			continue
+		}
+
+		switch {
+		case result.Alive && !old.Alive:
+			newAlive = append(newAlive, DiffEntry{DiffNewAlive, result.Domain, diffStatusLabel(old), diffStatusLabel(result)})
+		case !result.Alive && old.Alive:
+			died = append(died, DiffEntry{DiffDied, result.Domain, diffStatusLabel(old), diffStatusLabel(result)})
+		case result.Status != old.Status:
+			status = append(status, DiffEntry{DiffStatus, result.Domain, diffStatusLabel(old), diffStatusLabel(result)})
+		}
+
+		// 标题和页面类型只在两次都有值时比较，避免旧版本输出缺列造成误报
+		oldTitle := strings.TrimSpace(decodeTitle(old.Title))
+		newTitle := strings.TrimSpace(decodeTitle(result.Title))
+		if oldTitle != "" && newTitle != "" && oldTitle != newTitle {
+			title = append(title, DiffEntry{DiffTitle, result.Domain, oldTitle, newTitle})
+		}
+		if newType := pageTypeOf(&result); newType != "" && newType != pageTypeOf(&old) {
+			oldType := pageTypeOf(&old)
+			if oldType == "" {
+				oldType = diffNoneValue
+			}
+			pageType = append(pageType, DiffEntry{DiffPageType, result.Domain, oldType, newType})
+		}
+	}
+
+	diff := &Diff{Baseline: baselineName}
+	for _, entries := range [][]DiffEntry{newAlive, died, status, title, pageType} {
+		diff.Entries = append(diff.Entries, entries...)
+	}
+	return diff
+}
+
+// 变化中显示的状态：状态码，请求失败时为状态文本
+func diffStatusLabel(result checker.Result) string {
+	if result.Status != 0 {
+		return strconv.Itoa(result.Status)
+	}
+	if result.StatusText != "" {
+		return result.StatusText
+	}
+	return diffNoneValue
+}
+
+// 在终端打印与基线相比的变化
+func PrintDiff(diff *Diff) {
+	fmt.Printf("\n与基线相比的变化 (%s):\n", diff.Baseline)
+	fmt.Println("----------------------------------------")
+	fmt.Println(diff.Summary())
+	for i, entry := range diff.Entries {
+		if i == diffPrintMax {
+			fmt.Printf("  ... 另有 %d 项变化，完整列表见Excel或HTML报告\n", len(diff.Entries)-diffPrintMax)
+			break
+		}
+		fmt.Printf("  [%s] %s: %s -> %s\n", entry.Kind, entry.Domain, entry.Before, entry.After)
+	}
+}
+
+// 写入变化工作表
+func writeDiffSheet(f *excelize.File, diff *Diff, headerStyle int) error {
+	if _, err := f.NewSheet(diffSheet); err != nil {
+		return err
+	}
+	f.SetCellValue(diffSheet, "A1", "基线: "+diff.Baseline)
+	f.SetCellValue(diffSheet, "A2", diff.Summary())
+
+	headers := []string{"变化类型", "域名", "之前", "现在"}
+	for i, header := range headers {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 4)
+		f.SetCellValue(diffSheet, cell, header)
+	}
+	f.SetCellStyle(diffSheet, "A4", "D4", headerStyle)
+	for i, entry := range diff.Entries {
+		row := i + 5
+		f.SetCellValue(diffSheet, fmt.Sprintf("A%d", row), entry.Kind)
+		f.SetCellValue(diffSheet, fmt.Sprintf("B%d", row), entry.Domain)
+		f.SetCellValue(diffSheet, fmt.Sprintf("C%d", row), entry.Before)
+		f.SetCellValue(diffSheet, fmt.Sprintf("D%d", row), entry.After)
+	}
+
+	f.SetColWidth(diffSheet, "A", "A", 14)
+	f.SetColWidth(diffSheet, "B", "B", 40)
+	f.SetColWidth(diffSheet, "C", "D", 40)
+	if len(diff.Entries) > 0 {
+		return f.AutoFilter(diffSheet, fmt.Sprintf("A4:D%d", len(diff.Entries)+4), nil)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package view
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"subdomain-checker/checker"
+)
+
+// 保存未存活的目标（包括DNS解析失败和超时），便于重新检测。
+// 文件名以 .csv（或 .csv.gz）结尾时输出带失败原因的CSV，否则每行一个原始输入形式的目标
+func SaveFailedTargets(results []checker.Result, filename string) (err error) {
+	file, err := createOutput(filename)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	if !strings.HasSuffix(strings.TrimSuffix(filename, ".gz"), ".csv") {
+		for _, result := range results {
+			if !result.Alive {
+				if _, err := fmt.Fprintln(file, failedTarget(result)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"目标", "URL", "状态", "状态码", "失败类别", "原因"})
+	for _, result := range results {
+		if result.Alive {
+			continue
+		}
+		writer.Write([]string{
+			failedTarget(result),
+			result.Domain,
+			result.StatusText,
+			strconv.Itoa(result.Status),
+			StatusKey(result),
+			result.Message,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// 失败目标的原始输入形式，旧结果中没有记录时使用检测的URL
+func failedTarget(result checker.Result) string {
+	if result.Input != "" {
+		return result.Input
+	}
+	return result.Domain
+}
@@ -0,0 +1,120 @@
+package view
+
+import (
+	"fmt"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"subdomain-checker/checker"
+)
+
+// 可选择的输出字段（CSV和静默模式），新增字段只需在 fieldRegistry 中注册
+type Field struct {
+	Name   string                         // 字段名，用于 -fields
+	Header string                         // CSV表头
+	Value  func(r *checker.Result) string // 取值函数
+}
+
+// 字段注册表，顺序即帮助信息中的顺序
+var fieldRegistry = []Field{
+	{Name: "domain", Header: "域名", Value: func(r *checker.Result) string { return r.Domain }},
+	{Name: "url", Header: "URL", Value: func(r *checker.Result) string { return domainLink(r.Domain) }},
+	{Name: "status_text", Header: "状态", Value: func(r *checker.Result) string { return r.StatusText }},
+	{Name: "status", Header: "状态码", Value: func(r *checker.Result) string { return strconv.Itoa(r.Status) }},
+	{Name: "response_time", Header: "响应时间(毫秒)", Value: func(r *checker.Result) string {
+		return strconv.FormatInt(r.ResponseTime.Milliseconds(), 10)
+	}},
+	{Name: "body_size", Header: "响应大小(字节)", Value: func(r *checker.Result) string { return bodySizeOf(r) }},
+	{Name: "page_type", Header: "页面类型", Value: func(r *checker.Result) string { return pageTypeOf(r) }},
+	{Name: "title", Header: "页面标题", Value: func(r *checker.Result) string { return decodeTitle(r.Title) }},
+	{Name: "message", Header: "消息", Value: func(r *checker.Result) string { return r.Message }},
+	{Name: "final_url", Header: "最终URL", Value: func(r *checker.Result) string { return r.FinalURL }},
+	{Name: "screenshot", Header: "截图", Value: func(r *checker.Result) string { return r.Screenshot }},
+	{Name: "apex", Header: "主域名", Value: func(r *checker.Result) string { return ApexOf(r.Domain) }},
+	{Name: "error_class", Header: "失败类别", Value: func(r *checker.Result) string { return r.ErrorClass }},
+	{Name: "input", Header: "输入", Value: func(r *checker.Result) string { return r.Input }},
+	{Name: "idn", Header: "国际化域名", Value: func(r *checker.Result) string { return r.IDN }},
+	{Name: "original", Header: "原始输入", Value: func(r *checker.Result) string { return r.Original }},
+	{Name: "note", Header: "备注", Value: func(r *checker.Result) string { return r.Note }},
+	{Name: "override", Header: "覆盖规则", Value: func(r *checker.Result) string { return r.Override }},
+	{Name: "response", Header: "原始响应", Value: func(r *checker.Result) string { return r.Response }},
+	{Name: "ip", Header: "IP地址", Value: func(r *checker.Result) string { return ipsOf(r) }},
+	{Name: "user_agent", Header: "User-Agent", Value: func(r *checker.Result) string { return r.UserAgent }},
+	{Name: "proxy", Header: "代理", Value: func(r *checker.Result) string { return r.Proxy }},
+	{Name: "method", Header: "请求方法", Value: func(r *checker.Result) string { return r.Method }},
+	{Name: "fingerprint", Header: "技术指纹", Value: func(r *checker.Result) string { return fingerprintsOf(r) }},
+	{Name: "redirect_chain", Header: "重定向链", Value: func(r *checker.Result) string { return strings.Join(r.RedirectChain, redirectSeparator) }},
+	{Name: "cross_site", Header: "跨站重定向", Value: func(r *checker.Result) string { return yesOrEmpty(r.RedirectCrossSite) }},
+	{Name: "favicon_hash", Header: "图标哈希", Value: func(r *checker.Result) string { return r.FaviconHash }},
+	headerField("server", "Server"),
+	headerField("powered_by", "X-Powered-By"),
+	headerField("content_type", "Content-Type"),
+	headerField("location", "Location"),
+}
+
+// -fields 中指定任意记录的响应头时使用的前缀，如 header:X-Frame-Options
+const headerFieldPrefix = "header:"
+
+// 字段中以 header:名称 指定的响应头，检测时需要记录这些响应头
+func FieldHeaders(fields []Field) []string {
+	var headers []string
+	for _, field := range fields {
+		if header, ok := strings.CutPrefix(field.Name, headerFieldPrefix); ok {
+			headers = append(headers, header)
+		}
+	}
+	return headers
+}
+
+// 响应头字段，取值为结果中记录的该响应头
+func headerField(name, header string) Field {
+	header = textproto.CanonicalMIMEHeaderKey(header)
+	return Field{Name: name, Header: header, Value: func(r *checker.Result) string {
+		return r.CapturedHeaders[header]
+	}}
+}
+
+// CSV默认输出的字段（前面的列与早期版本的顺序一致）
+var DefaultCSVFields = "domain,status_text,status,response_time,page_type,title,message,final_url,screenshot,idn,note,ip,server,powered_by,content_type,location,body_size"
+
+// 所有可选字段名
+func FieldNames() []string {
+	names := make([]string, len(fieldRegistry))
+	for i, field := range fieldRegistry {
+		names[i] = field.Name
+	}
+	return names
+}
+
+// 解析逗号分隔的字段列表，字段名中的"-"等同于"_"（兼容 status-text 等旧写法）；
+// header:名称 输出记录的该响应头（默认记录的或 -headers-capture 指定的）
+func ParseFields(s string) ([]Field, error) {
+	var fields []Field
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if header, ok := strings.CutPrefix(name, headerFieldPrefix); ok && header != "" {
+			fields = append(fields, headerField(name, header))
+			continue
+		}
+		name = strings.ReplaceAll(name, "-", "_")
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, field := range fieldRegistry {
+			if field.Name == name {
+				fields = append(fields, field)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("不支持的输出字段: %s (可选: %s, %s<响应头名称>)", name, strings.Join(FieldNames(), ", "), headerFieldPrefix)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("没有指定输出字段 (可选: %s)", strings.Join(FieldNames(), ", "))
+	}
+	return fields, nil
+}
@@ -0,0 +1,59 @@
+package view
+
+import (
+	"sort"
+
+	"subdomain-checker/checker"
+)
+
+// 按主域名分组的结果
+type ApexGroup struct {
+	Apex      string
+	Alive     int
+	Protected int // 受保护（401/403/407），不计入 Alive 和 Dead
+	Dead      int
+	Results   []checker.Result
+}
+
+// 获取结果所属的主域名（eTLD+1），IP地址单独成组
+func ApexOf(domain string) string {
+	return apexOf(hostOf(domain))
+}
+
+// 按主域名对结果分组，分组顺序与结果中首次出现的顺序一致
+func GroupByApex(results []checker.Result) []ApexGroup {
+	var groups []ApexGroup
+	index := make(map[string]int)
+	for _, result := range results {
+		apex := ApexOf(result.Domain)
+		i, ok := index[apex]
+		if !ok {
+			i = len(groups)
+			index[apex] = i
+			groups = append(groups, ApexGroup{Apex: apex})
+		}
+		switch {
+		case result.Alive:
+			groups[i].Alive++
+		case checker.IsProtected(result):
+			groups[i].Protected++
+		default:
+			groups[i].Dead++
+		}
+		groups[i].Results = append(groups[i].Results, result)
+	}
+	return groups
+}
+
+// 按存活数量从高到低返回前 n 个主域名分组
+func topApexGroups(groups []ApexGroup, n int) []ApexGroup {
+	top := make([]ApexGroup, len(groups))
+	copy(top, groups)
+	sort.SliceStable(top, func(i, j int) bool {
+		return top[i].Alive > top[j].Alive
+	})
+	if len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
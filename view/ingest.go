@@ -0,0 +1,70 @@
+package view
+
+import (
+	"fmt"
+	"strings"
+
+	"subdomain-checker/checker"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// 输入文件格式
+const (
+	InputAuto  = "auto"
+	InputText  = "txt"
+	InputCSV   = "csv"
+	InputJSON  = "json"
+	InputExcel = "xlsx"
+)
+
+// 解析Excel格式的结果，读取"子域名检测结果"工作表（不存在时使用第一个工作表）
+func parseExcelResults(filename string) ([]checker.Result, error) {
+	f, err := excelize.OpenFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("打开Excel文件失败: %v", err)
+	}
+	defer f.Close()
+
+	sheet := "子域名检测结果"
+	if index, err := f.GetSheetIndex(sheet); err != nil || index < 0 {
+		sheet = f.GetSheetName(0)
+	}
+	rows, err := f.GetRows(sheet)
+	if err != nil {
+		return nil, fmt.Errorf("读取工作表 %s 失败: %v", sheet, err)
+	}
+	results, err := parseResultRows(rows)
+	if err != nil {
+		return nil, err
+	}
+	// 国际化域名的单元格显示为 "Unicode形式 (ASCII形式)"，取括号中的ASCII形式
+	for i := range results {
+		domain := results[i].Domain
+		if start := strings.LastIndex(domain, " ("); start >= 0 && strings.HasSuffix(domain, ")") {
+			results[i].Domain = domain[start+2 : len(domain)-1]
+		}
+	}
+	return results, nil
+}
+
+// 从文本列表或上一次运行输出的结果文件中读取目标，onlyAlive 为 true 时只保留当时存活的目标。
+// notes 为目标的备注（文本列表的行尾注释或结果文件中的备注列）
+func LoadTargets(filename, format string, onlyAlive bool) ([]string, map[string]string, error) {
+	results, err := LoadResults(filename, format)
+	if err != nil {
+		return nil, nil, err
+	}
+	targets := make([]string, 0, len(results))
+	notes := make(map[string]string)
+	for _, result := range results {
+		if onlyAlive && !result.Alive {
+			continue
+		}
+		targets = append(targets, result.Domain)
+		if _, ok := notes[result.Domain]; result.Note != "" && !ok {
+			notes[result.Domain] = result.Note
+		}
+	}
+	return targets, notes, nil
+}
@@ -0,0 +1,114 @@
+package view
+
+import (
+	"encoding/json"
+
+	"subdomain-checker/checker"
+)
+
+// JSON输出中的单条结果
+type JSONResult struct {
+	Domain         string            `json:"domain"`
+	URL            string            `json:"url"`
+	FinalURL       string            `json:"final_url,omitempty"`
+	Alive          bool              `json:"alive"`
+	Status         int               `json:"status"`
+	StatusText     string            `json:"status_text"`
+	ResponseTimeMs int64             `json:"response_time_ms"`
+	PageType       string            `json:"page_type,omitempty"`
+	Title          string            `json:"title,omitempty"`
+	Message        string            `json:"message,omitempty"`
+	Screenshot     string            `json:"screenshot,omitempty"`
+	IDN            string            `json:"idn,omitempty"`
+	ErrorClass     string            `json:"error_class,omitempty"`
+	Original       string            `json:"original,omitempty"`
+	Note           string            `json:"note,omitempty"`
+	Override       string            `json:"override,omitempty"`
+	Response       string            `json:"response,omitempty"`
+	IPs            []string          `json:"ips,omitempty"`
+	UserAgent      string            `json:"user_agent,omitempty"`
+	Proxy          string            `json:"proxy,omitempty"`
+	Method         string            `json:"method,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	Fingerprints   []string          `json:"fingerprints,omitempty"`
+	FaviconHash    string            `json:"favicon_hash,omitempty"`
+	BodySize       *int64            `json:"body_size,omitempty"`
+	BodySizeApprox bool              `json:"body_size_approx,omitempty"`
+	RedirectChain  []string          `json:"redirect_chain,omitempty"`
+	CrossSite      bool              `json:"redirect_cross_site,omitempty"`
+}
+
+// 转换为JSON输出结构
+func NewJSONResult(result checker.Result) JSONResult {
+	return JSONResult{
+		Domain:         result.Domain,
+		URL:            domainLink(result.Domain),
+		FinalURL:       result.FinalURL,
+		Alive:          result.Alive,
+		Status:         result.Status,
+		StatusText:     result.StatusText,
+		ResponseTimeMs: result.ResponseTime.Milliseconds(),
+		PageType:       pageTypeOf(&result),
+		Title:          decodeTitle(result.Title),
+		Message:        result.Message,
+		Screenshot:     result.Screenshot,
+		IDN:            result.IDN,
+		ErrorClass:     result.ErrorClass,
+		Original:       result.Original,
+		Note:           result.Note,
+		Override:       result.Override,
+		Response:       result.Response,
+		IPs:            result.IPs,
+		UserAgent:      result.UserAgent,
+		Proxy:          result.Proxy,
+		Method:         result.Method,
+		Headers:        result.CapturedHeaders,
+		Fingerprints:   result.Fingerprints,
+		FaviconHash:    result.FaviconHash,
+		BodySize:       jsonBodySize(&result),
+		BodySizeApprox: result.BodySizeApprox && jsonBodySize(&result) != nil,
+		RedirectChain:  result.RedirectChain,
+		CrossSite:      result.RedirectCrossSite,
+	}
+}
+
+// JSON中的响应大小，没有响应或大小未知时为nil
+func jsonBodySize(r *checker.Result) *int64 {
+	if bodySizeOf(r) == "" {
+		return nil
+	}
+	size := r.BodySize
+	return &size
+}
+
+// JSON输出的顶层结构
+type JSONReport struct {
+	Meta    *RunMeta     `json:"meta"`
+	Results []JSONResult `json:"results"`
+}
+
+// 保存结果到JSON文件（运行元数据和结果数组），文件名以 .gz 结尾时使用gzip压缩
+func SaveResultsToJSON(results []checker.Result, filename string, filter ExportFilter, meta *RunMeta) (err error) {
+	file, err := createOutput(filename)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	items := make([]JSONResult, 0, len(results))
+	for _, result := range results {
+		// 如果只导出存活的域名，则跳过非存活的（-include-protected 时保留受保护的）
+		if filter.Skip(result) {
+			continue
+		}
+		items = append(items, NewJSONResult(result))
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(JSONReport{Meta: meta, Results: items})
+}
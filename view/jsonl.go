@@ -0,0 +1,131 @@
+package view
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"subdomain-checker/checker"
+)
+
+// 流式JSONL输出写入磁盘的最长间隔
+const jsonlFlushInterval = time.Second
+
+// 流式JSONL输出（-jsonl）：检测过程中每得到一批结果就追加写入，每行一条结果，字段与JSON输出相同。
+// 并发安全；关闭后的写入被忽略
+type JSONLWriter struct {
+	mu        sync.Mutex
+	file      io.WriteCloser
+	buf       *bufio.Writer
+	encoder   *json.Encoder
+	lines     int
+	lastFlush time.Time
+	closed    bool
+	err       error // 第一次写入失败的错误，之后的写入被忽略，关闭时返回
+}
+
+// 创建JSONL输出文件，已存在的同名文件会被覆盖，文件名以 .gz 结尾时使用gzip压缩
+func CreateJSONL(filename string) (*JSONLWriter, error) {
+	file, err := createOutput(filename)
+	if err != nil {
+		return nil, err
+	}
+	buf := bufio.NewWriter(file)
+	return &JSONLWriter{file: file, buf: buf, encoder: json.NewEncoder(buf), lastFlush: time.Now()}, nil
+}
+
+// 追加写入结果，距上次写入磁盘超过 jsonlFlushInterval 时写入磁盘。
+// 只返回第一次失败的错误，之后的写入直接忽略
+func (w *JSONLWriter) Write(results []checker.Result) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed || w.err != nil {
+		return nil
+	}
+	w.err = w.write(results)
+	return w.err
+}
+
+func (w *JSONLWriter) write(results []checker.Result) error {
+	for _, result := range results {
+		if err := w.encoder.Encode(NewJSONResult(result)); err != nil {
+			return err
+		}
+		w.lines++
+	}
+	if time.Since(w.lastFlush) < jsonlFlushInterval {
+		return nil
+	}
+	w.lastFlush = time.Now()
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	// gzip压缩时同时输出已压缩的部分，文件中始终是可以解压的完整的行
+	if flusher, ok := w.file.(interface{ Flush() error }); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+// 已写入的行数
+func (w *JSONLWriter) Lines() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lines
+}
+
+// 写入剩余内容并关闭文件，可重复调用
+func (w *JSONLWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	err := w.err
+	if ferr := w.buf.Flush(); err == nil {
+		err = ferr
+	}
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// 是否为JSONL结果（第一行是一条完整的结果）
+func isJSONLines(data []byte) bool {
+	first, _, _ := bytes.Cut(data, []byte("\n"))
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(first, &fields); err != nil {
+		return false
+	}
+	_, ok := fields["domain"]
+	return ok
+}
+
+// 解析JSONL结果，跳过无法解析的行（如运行异常退出时写了一半的最后一行）。
+// 同一域名出现多次（-recheck-dead 追加的复查结果）时以后出现的行为准
+func parseJSONLines(data []byte) []JSONResult {
+	var items []JSONResult
+	index := make(map[string]int)
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var item JSONResult
+		if err := json.Unmarshal(line, &item); err != nil {
+			continue
+		}
+		if i, ok := index[item.Domain]; ok {
+			items[i] = item
+			continue
+		}
+		index[item.Domain] = len(items)
+		items = append(items, item)
+	}
+	return items
+}
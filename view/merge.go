@@ -0,0 +1,115 @@
+package view
+
+import (
+	"fmt"
+	"strings"
+
+	"subdomain-checker/checker"
+)
+
+// 合并多个结果文件（如 -shard 各分片的输出）的结果：按主机去重，同一主机保留存活的结果，
+// 都存活或都未存活时保留先出现的，顺序为各主机首次出现的顺序
+func MergeResults(sets ...[]checker.Result) []checker.Result {
+	var merged []checker.Result
+	index := make(map[string]int)
+	for _, results := range sets {
+		for _, result := range results {
+			key := BaselineKey(result.Domain)
+			i, ok := index[key]
+			if !ok {
+				index[key] = len(merged)
+				merged = append(merged, result)
+				continue
+			}
+			if result.Alive && !merged[i].Alive {
+				merged[i] = result
+			}
+		}
+	}
+	return merged
+}
+
+// 合并各结果文件的运行元数据：开始时间取最早、结束时间取最晚，目标数量相加（重复的分片只计一次），
+// 分片号依次列出。没有任何元数据时返回nil
+func MergeMeta(metas []*RunMeta) *RunMeta {
+	var merged *RunMeta
+	var shards []string
+	seen := make(map[string]bool)
+	for _, meta := range metas {
+		if meta == nil || (meta.Shard != "" && seen[meta.Shard]) {
+			continue
+		}
+		if meta.Shard != "" {
+			seen[meta.Shard] = true
+			shards = append(shards, meta.Shard)
+		}
+		if merged == nil {
+			copied := *meta
+			merged = &copied
+			continue
+		}
+		if meta.StartTime.Before(merged.StartTime) {
+			merged.StartTime = meta.StartTime
+		}
+		if meta.EndTime.After(merged.EndTime) {
+			merged.EndTime = meta.EndTime
+		}
+		merged.Targets += meta.Targets
+		merged.Concurrency = max(merged.Concurrency, meta.Concurrency)
+		merged.Partial = merged.Partial || meta.Partial
+	}
+	if merged != nil {
+		merged.Shard = strings.Join(shards, ",")
+	}
+	return merged
+}
+
+// 检查各结果文件的分片号是否齐全，返回发现的问题（分片数不一致、缺少或重复的分片），
+// 没有分片信息时返回nil
+func CheckShards(metas []*RunMeta) []string {
+	seen := make(map[int]int)
+	count := 0
+	var problems []string
+	for _, meta := range metas {
+		if meta == nil || meta.Shard == "" {
+			continue
+		}
+		var index, n int
+		if _, err := fmt.Sscanf(meta.Shard, "%d/%d", &index, &n); err != nil {
+			continue
+		}
+		if count == 0 {
+			count = n
+		} else if n != count {
+			problems = append(problems, fmt.Sprintf("分片数不一致: %d 和 %d", count, n))
+		}
+		seen[index]++
+	}
+	if count == 0 {
+		return nil
+	}
+	var missing, repeated []int
+	for i := 0; i < count; i++ {
+		switch {
+		case seen[i] == 0:
+			missing = append(missing, i)
+		case seen[i] > 1:
+			repeated = append(repeated, i)
+		}
+	}
+	if len(missing) > 0 {
+		problems = append(problems, fmt.Sprintf("缺少分片 %s（共 %d 片）", joinInts(missing), count))
+	}
+	if len(repeated) > 0 {
+		problems = append(problems, fmt.Sprintf("重复的分片 %s", joinInts(repeated)))
+	}
+	return problems
+}
+
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprint(v)
+	}
+	return strings.Join(parts, ", ")
+}
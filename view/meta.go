@@ -0,0 +1,93 @@
+package view
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 运行元数据，在main中生成一次后传给所有输出，保证各格式中的值一致
+type RunMeta struct {
+	Version     string    `json:"version"`
+	Command     string    `json:"command"` // 已隐藏密钥等敏感参数的命令行
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+	Targets     int       `json:"targets"`
+	Concurrency int       `json:"concurrency"`
+	Timeout     int       `json:"timeout"`
+	Partial     bool      `json:"partial,omitempty"` // 运行被中断，只包含中断前已得到的结果
+	Shard       string    `json:"shard,omitempty"`   // -shard 的分片号（如 0/4），合并后为各分片号以逗号连接
+}
+
+// 运行耗时
+func (m *RunMeta) Duration() time.Duration {
+	return m.EndTime.Sub(m.StartTime)
+}
+
+// 以"名称, 值"形式列出元数据，用于CSV注释、Excel和HTML
+func (m *RunMeta) Fields() [][2]string {
+	fields := [][2]string{
+		{"版本", m.Version},
+		{"命令行", m.Command},
+		{"开始时间", m.StartTime.Format("2006-01-02 15:04:05")},
+		{"结束时间", m.EndTime.Format("2006-01-02 15:04:05")},
+		{"检测耗时(秒)", fmt.Sprintf("%.2f", m.Duration().Seconds())},
+		{"目标数量", fmt.Sprint(m.Targets)},
+		{"并发数", fmt.Sprint(m.Concurrency)},
+		{"超时(秒)", fmt.Sprint(m.Timeout)},
+	}
+	if m.Shard != "" {
+		fields = append(fields, [2]string{"分片", m.Shard})
+	}
+	if m.Partial {
+		fields = append(fields, [2]string{"部分结果", "是（运行被中断，只包含中断前已检测的目标）"})
+	}
+	return fields
+}
+
+// 包含敏感信息的参数，输出时隐藏参数值
+var sensitiveFlags = map[string]bool{
+	"webhook":         true,
+	"webhook-header":  true,
+	"notify":          true,
+	"dingtalk-secret": true,
+}
+
+// URL中的用户名和密码
+var userinfoPattern = regexp.MustCompile(`://[^/@\s]+@`)
+
+// 隐藏命令行中的敏感参数值（通知地址、请求头、密钥）和URL中的用户名密码，-notify 保留机器人类型
+func SanitizeArgs(args []string) string {
+	out := make([]string, 0, len(args))
+	maskNext := ""
+	for _, arg := range args {
+		if maskNext != "" {
+			out = append(out, maskValue(maskNext, arg))
+			maskNext = ""
+			continue
+		}
+		name := strings.TrimLeft(arg, "-")
+		if !strings.HasPrefix(arg, "-") || !sensitiveFlags[strings.SplitN(name, "=", 2)[0]] {
+			out = append(out, arg)
+			continue
+		}
+		if parts := strings.SplitN(name, "=", 2); len(parts) == 2 {
+			out = append(out, arg[:len(arg)-len(parts[1])]+maskValue(parts[0], parts[1]))
+		} else {
+			out = append(out, arg)
+			maskNext = name
+		}
+	}
+	return userinfoPattern.ReplaceAllString(strings.Join(out, " "), "://***@")
+}
+
+// 隐藏单个参数值
+func maskValue(flagName, value string) string {
+	if flagName == "notify" {
+		if i := strings.Index(value, ":"); i > 0 {
+			return value[:i+1] + "***"
+		}
+	}
+	return "***"
+}
@@ -0,0 +1,17 @@
+package view
+
+import (
+	"strings"
+
+	"subdomain-checker/checker"
+)
+
+// 将单个结果格式化为一行纯文本，字段之间以制表符分隔
+func FormatPlain(result checker.Result, fields []Field) string {
+	values := make([]string, len(fields))
+	for i, field := range fields {
+		// 制表符和换行会破坏行格式
+		values[i] = strings.NewReplacer("\t", " ", "\r", " ", "\n", " ").Replace(field.Value(&result))
+	}
+	return strings.Join(values, "\t")
+}
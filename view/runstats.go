@@ -0,0 +1,283 @@
+package view
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"subdomain-checker/checker"
+	"subdomain-checker/screenshot"
+)
+
+// 一次运行的汇总统计，终端总结、Excel统计表和 -stats-file 都使用这份数据
+type RunStats struct {
+	Partial       bool // 运行被中断，只包含已处理部分
+	Total         int  // 目标数量
+	Checked       int  // 已检测数量
+	Alive         int
+	Protected     int // 受保护（401/403/407），不计入 Alive 和 Dead
+	Dead          int
+	StatusCounts  StatusCounts
+	PageTypes     map[string]int
+	Fingerprints  map[string]int    // 存活主机中各技术指纹（-extract）的数量
+	Clusters      []ContentCluster  // 标题和响应内容相同的主机分组（至少两个主机），按主机数从多到少
+	FaviconGroups []FaviconGroup    // 图标哈希（-favicon）相同的主机分组（至少两个主机），按主机数从多到少
+	Screenshots   int               // 结果中带截图的数量（-screenshot-alive 时只统计存活主机）
+	ScreenshotRun *screenshot.Stats // 截图工作池统计，未启用截图时为nil
+	ResponseTimes ResponseTimeStats
+	BodySizes     BodySizeStats
+	Duration      time.Duration
+	Rechecked     int           // -recheck-dead 复查的目标数量
+	Recovered     int           // 复查后恢复存活的数量
+	Excluded      int           // 被排除规则去除的目标数量（不计入 Total）
+	Limited       int           // 因 -sample/-max-hosts 未检测的目标数量（不计入 Total）
+	LimitNote     string        // 应用的抽样和上限说明，如 "随机抽样 5% (种子 42)"
+	Throttled     time.Duration // 内存占用接近 -max-memory 时暂缓截图和检测的累计时间
+	SlowDowns     int           // -adaptive 因失败突发降低并发的次数
+	MinConc       int           // -adaptive 运行中的最低并发数（SlowDowns 为0时不使用）
+	AutoMin       int           // -auto-concurrency 运行中的最低并发数，未启用时为0
+	AutoMax       int           // -auto-concurrency 运行中的最高并发数
+	AutoTypical   int           // -auto-concurrency 通常的并发数（各调整间隔的中位数）
+	RateGroups    int           // -rate-per-host 按主域名限速的分组数量，未启用时为0
+	CrossSite     int           // 重定向到其他主域名（跨站）的主机数量
+
+	// 同一主机检测了多个路径（-paths）时按主机（含端口）汇总，任一路径存活即为存活；每个主机只有一个目标时为0
+	Hosts      int
+	AliveHosts int
+}
+
+// 从结果列表汇总统计，shots 为截图工作池的统计（未启用截图时传nil）
+func ComputeRunStats(results []checker.Result, total int, screenshotAlive bool, shots *screenshot.Stats, duration time.Duration) *RunStats {
+	stats := &RunStats{
+		Total:         total,
+		Checked:       len(results),
+		StatusCounts:  make(StatusCounts),
+		PageTypes:     make(map[string]int),
+		Fingerprints:  make(map[string]int),
+		ScreenshotRun: shots,
+		ResponseTimes: ComputeResponseTimeStats(results),
+		BodySizes:     ComputeBodySizeStats(results),
+		Clusters:      ClusterResults(results),
+		FaviconGroups: GroupByFavicon(results),
+		Duration:      duration,
+	}
+	hosts := make(map[string]bool) // 主机 -> 是否有存活的路径
+	for _, result := range results {
+		stats.StatusCounts.Add(result)
+		switch {
+		case result.Alive:
+			stats.Alive++
+			if result.PageInfo != nil {
+				stats.PageTypes[result.PageInfo.Type]++
+			}
+			for _, fingerprint := range result.Fingerprints {
+				stats.Fingerprints[fingerprint]++
+			}
+		case checker.IsProtected(result):
+			stats.Protected++
+		default:
+			stats.Dead++
+		}
+		if result.Screenshot != "" && (result.Alive || checker.IsProtected(result) || !screenshotAlive) {
+			stats.Screenshots++
+		}
+		if result.RedirectCrossSite {
+			stats.CrossSite++
+		}
+		host := targetWithoutPath(result)
+		hosts[host] = hosts[host] || result.Alive
+	}
+	if len(hosts) < len(results) {
+		stats.Hosts = len(hosts)
+		for _, alive := range hosts {
+			if alive {
+				stats.AliveHosts++
+			}
+		}
+	}
+	return stats
+}
+
+// 结果对应的主机（含端口），去掉协议和路径
+func targetWithoutPath(result checker.Result) string {
+	target := result.Input
+	if target == "" {
+		target = result.Domain
+		if _, rest, ok := strings.Cut(target, "://"); ok {
+			target = rest
+		}
+	}
+	host, _, _ := strings.Cut(target, "/")
+	return strings.ToLower(host)
+}
+
+// 按数量从多到少排列的技术指纹，数量相同时按名称排列
+func (stats *RunStats) SortedFingerprints() []string {
+	names := make([]string, 0, len(stats.Fingerprints))
+	for name := range stats.Fingerprints {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if a, b := stats.Fingerprints[names[i]], stats.Fingerprints[names[j]]; a != b {
+			return a > b
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// 统计文件中的截图统计
+type statsFileScreenshots struct {
+	Saved       int `json:"saved"`
+	Attempted   int `json:"attempted"`
+	Success     int `json:"success"`
+	Failed      int `json:"failed"`
+	ErrorImages int `json:"error_images"`
+	// 实测的每个Chrome实例的平均内存占用，未测量时省略
+	ChromeMemoryMB float64 `json:"chrome_memory_mb,omitempty"`
+}
+
+// 统计文件中的响应时间（毫秒）
+type statsFileResponseTimes struct {
+	Count  int   `json:"count"`
+	Min    int64 `json:"min"`
+	Median int64 `json:"median"`
+	P90    int64 `json:"p90"`
+	P95    int64 `json:"p95"`
+	Max    int64 `json:"max"`
+	Avg    int64 `json:"avg"`
+}
+
+// 统计文件中的响应大小（字节）
+type statsFileBodySizes struct {
+	Count  int   `json:"count"`
+	Min    int64 `json:"min"`
+	Median int64 `json:"median"`
+	Max    int64 `json:"max"`
+}
+
+// -stats-file 的JSON结构
+type statsFile struct {
+	Partial         bool                   `json:"partial"`
+	Meta            *RunMeta               `json:"meta,omitempty"`
+	Total           int                    `json:"total"`
+	Checked         int                    `json:"checked"`
+	Alive           int                    `json:"alive"`
+	Protected       int                    `json:"protected"`
+	Dead            int                    `json:"dead"`
+	StatusCounts    StatusCounts           `json:"status_counts"`
+	PageTypes       map[string]int         `json:"page_types"`
+	Fingerprints    map[string]int         `json:"fingerprints,omitempty"`
+	FaviconGroups   map[string]int         `json:"favicon_groups,omitempty"` // 图标哈希 -> 主机数，只包含至少两个主机的哈希
+	Screenshots     *statsFileScreenshots  `json:"screenshots,omitempty"`
+	ResponseTimeMs  statsFileResponseTimes `json:"response_time_ms"`
+	BodySizeBytes   *statsFileBodySizes    `json:"body_size_bytes,omitempty"`
+	DurationSeconds float64                `json:"duration_seconds"`
+	Rechecked       int                    `json:"rechecked,omitempty"`
+	Recovered       int                    `json:"recovered,omitempty"`
+	Excluded        int                    `json:"excluded,omitempty"`
+	Limited         int                    `json:"limited,omitempty"`
+	LimitNote       string                 `json:"limit_note,omitempty"`
+	ThrottledSecs   float64                `json:"throttled_seconds,omitempty"`
+	SlowDowns       int                    `json:"adaptive_slowdowns,omitempty"`
+	MinConcurrency  int                    `json:"min_concurrency,omitempty"`
+	AutoConcurrency *statsFileConcurrency  `json:"auto_concurrency,omitempty"`
+	RateGroups      int                    `json:"rate_limit_groups,omitempty"`
+	CrossSite       int                    `json:"cross_site_redirects,omitempty"`
+	Hosts           *statsFileHosts        `json:"hosts,omitempty"`
+}
+
+// 统计文件中按主机的汇总（-paths 时同一主机有多个目标），任一路径存活即为存活
+type statsFileHosts struct {
+	Total int `json:"total"`
+	Alive int `json:"alive"`
+}
+
+// 统计文件中 -auto-concurrency 的并发数
+type statsFileConcurrency struct {
+	Min     int `json:"min"`
+	Max     int `json:"max"`
+	Typical int `json:"typical"`
+}
+
+// 保存机器可读的统计文件(JSON)，文件名以 .gz 结尾时使用gzip压缩
+func SaveStatsFile(stats *RunStats, filename string, meta *RunMeta) (err error) {
+	file, err := createOutput(filename)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	rt := stats.ResponseTimes
+	out := statsFile{
+		Partial:      stats.Partial,
+		Meta:         meta,
+		Total:        stats.Total,
+		Checked:      stats.Checked,
+		Alive:        stats.Alive,
+		Protected:    stats.Protected,
+		Dead:         stats.Dead,
+		StatusCounts: stats.StatusCounts,
+		PageTypes:    stats.PageTypes,
+		Fingerprints: stats.Fingerprints,
+		ResponseTimeMs: statsFileResponseTimes{
+			Count:  rt.Count,
+			Min:    rt.Min.Milliseconds(),
+			Median: rt.Median.Milliseconds(),
+			P90:    rt.P90.Milliseconds(),
+			P95:    rt.P95.Milliseconds(),
+			Max:    rt.Max.Milliseconds(),
+			Avg:    rt.Avg.Milliseconds(),
+		},
+		DurationSeconds: stats.Duration.Seconds(),
+		Rechecked:       stats.Rechecked,
+		Recovered:       stats.Recovered,
+		Excluded:        stats.Excluded,
+		Limited:         stats.Limited,
+		LimitNote:       stats.LimitNote,
+		ThrottledSecs:   stats.Throttled.Seconds(),
+		SlowDowns:       stats.SlowDowns,
+		RateGroups:      stats.RateGroups,
+		CrossSite:       stats.CrossSite,
+	}
+	for _, group := range stats.FaviconGroups {
+		if out.FaviconGroups == nil {
+			out.FaviconGroups = make(map[string]int)
+		}
+		out.FaviconGroups[group.Hash] = len(group.Domains)
+	}
+	if bs := stats.BodySizes; bs.Count > 0 {
+		out.BodySizeBytes = &statsFileBodySizes{Count: bs.Count, Min: bs.Min, Median: bs.Median, Max: bs.Max}
+	}
+	if stats.Hosts > 0 {
+		out.Hosts = &statsFileHosts{Total: stats.Hosts, Alive: stats.AliveHosts}
+	}
+	if stats.SlowDowns > 0 {
+		out.MinConcurrency = stats.MinConc
+	}
+	if stats.AutoMax > 0 {
+		out.AutoConcurrency = &statsFileConcurrency{Min: stats.AutoMin, Max: stats.AutoMax, Typical: stats.AutoTypical}
+	}
+	if shots := stats.ScreenshotRun; shots != nil {
+		out.Screenshots = &statsFileScreenshots{
+			Saved:       stats.Screenshots,
+			Attempted:   shots.Total,
+			Success:     shots.Success,
+			Failed:      shots.Failed,
+			ErrorImages: shots.ErrorImages,
+		}
+		if shots.ChromeMemory > 0 {
+			out.Screenshots.ChromeMemoryMB = math.Round(float64(shots.ChromeMemory)/(1<<20)*10) / 10
+		}
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
@@ -0,0 +1,101 @@
+package view
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"subdomain-checker/checker"
+	"subdomain-checker/utils"
+)
+
+// 保存输出的重试参数
+const (
+	saveAttempts   = 3               // 每个输出最多尝试的次数
+	saveRetryDelay = 2 * time.Second // 两次尝试之间的等待
+)
+
+// 本次运行的备用输出目录（系统临时目录下），首次使用时创建
+var fallback struct {
+	once sync.Once
+	dir  string
+	err  error
+}
+
+func fallbackDir() (string, error) {
+	fallback.once.Do(func() {
+		fallback.dir = filepath.Join(os.TempDir(), "squirrel-"+time.Now().Format("20060102-150405"))
+		fallback.err = os.MkdirAll(fallback.dir, 0755)
+	})
+	return fallback.dir, fallback.err
+}
+
+// 保存一个输出文件：write 先写入同目录下的临时文件（文件名保留原扩展名），成功后重命名为 filename，
+// 中途失败不会留下不完整的文件或破坏已有的文件。失败时重试，目标文件被占用且在交互式终端中运行时
+// 提示关闭占用的程序后按回车重试。仍然失败时改为保存到系统临时目录。
+// 返回实际保存的路径；保存到备用位置时同时返回原位置的错误，两处都失败时路径为空
+func SaveOutput(filename string, write func(path string) error) (string, error) {
+	var err error
+	for attempt := 1; attempt <= saveAttempts; attempt++ {
+		if err = saveAtomic(filename, write); err == nil {
+			return filename, nil
+		}
+		if attempt == saveAttempts {
+			break
+		}
+		if utils.IsFileLocked(err) && utils.IsTerminal(os.Stdin) && utils.IsTerminal(os.Stdout) {
+			utils.Log().Warnf("⚠️  %s 被其他程序占用（如已在Excel中打开），关闭后按回车重试...\n", filename)
+			bufio.NewReader(os.Stdin).ReadString('\n')
+			continue
+		}
+		utils.Log().Warnf("保存 %s 失败: %s，%s后重试（第 %d/%d 次）\n", filename, err, saveRetryDelay, attempt+1, saveAttempts)
+		time.Sleep(saveRetryDelay)
+	}
+
+	dir, derr := fallbackDir()
+	if derr != nil {
+		return "", fmt.Errorf("%v（创建备用目录失败: %v）", err, derr)
+	}
+	path := filepath.Join(dir, filepath.Base(filename))
+	if ferr := saveAtomic(path, write); ferr != nil {
+		return "", fmt.Errorf("%v（保存到备用位置 %s 也失败: %v）", err, path, ferr)
+	}
+	return path, err
+}
+
+// 写入临时文件后重命名为 filename
+func saveAtomic(filename string, write func(path string) error) error {
+	if err := ensureOutputDir(filename); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(filename), ".squirrel-tmp-*-"+filepath.Base(filename))
+	if err != nil {
+		return err
+	}
+	path := tmp.Name()
+	tmp.Close()
+	// 临时文件默认只有所有者可读写，改为与直接创建的输出文件相同
+	os.Chmod(path, 0644)
+	if err := write(path); err != nil {
+		os.Remove(path)
+		return err
+	}
+	if err := os.Rename(path, filename); err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+// 把全部结果（含运行元数据）以JSON保存到备用目录，在输出文件保存失败时作为最后的保障，返回保存的路径
+func DumpResults(results []checker.Result, meta *RunMeta) (string, error) {
+	dir, err := fallbackDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "results.json")
+	return path, SaveResultsToJSON(results, path, ExportFilter{}, meta)
+}
@@ -0,0 +1,123 @@
+package view
+
+import (
+	"cmp"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	"subdomain-checker/checker"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// 支持的排序字段
+var SortKeys = []string{"domain", "status", "response-time", "page-type", "body-size"}
+
+// 按指定字段对结果进行稳定排序，相同值保持原有顺序
+func SortResults(results []checker.Result, key string, reverse bool) error {
+	var less func(a, b *checker.Result) int
+	switch key {
+	case "":
+		return nil
+	case "domain":
+		less = func(a, b *checker.Result) int {
+			return compareDomains(a.Domain, b.Domain)
+		}
+	case "status":
+		less = func(a, b *checker.Result) int {
+			return a.Status - b.Status
+		}
+	case "response-time":
+		less = func(a, b *checker.Result) int {
+			switch {
+			case a.ResponseTime < b.ResponseTime:
+				return -1
+			case a.ResponseTime > b.ResponseTime:
+				return 1
+			}
+			return 0
+		}
+	case "page-type":
+		less = func(a, b *checker.Result) int {
+			return strings.Compare(pageTypeOf(a), pageTypeOf(b))
+		}
+	case "body-size":
+		// 没有响应和大小未知的结果排在最前（倒序时在最后）
+		less = func(a, b *checker.Result) int {
+			return cmp.Compare(bodySizeKey(a), bodySizeKey(b))
+		}
+	default:
+		return fmt.Errorf("不支持的排序字段: %s (可选: %s)", key, strings.Join(SortKeys, ", "))
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		c := less(&results[i], &results[j])
+		if reverse {
+			return c > 0
+		}
+		return c < 0
+	})
+	return nil
+}
+
+// 按响应大小排序时使用的值，没有响应或大小未知时为-1
+func bodySizeKey(result *checker.Result) int64 {
+	if bodySizeOf(result) == "" {
+		return -1
+	}
+	return result.BodySize
+}
+
+// 获取页面类型，未识别时返回空字符串
+func pageTypeOf(result *checker.Result) string {
+	if result.PageInfo == nil {
+		return ""
+	}
+	return result.PageInfo.Type
+}
+
+// 从域名或URL中提取主机名（去掉协议、端口和路径）
+func hostOf(domain string) string {
+	host := domain
+	if strings.Contains(host, "://") {
+		if u, err := url.Parse(host); err == nil && u.Host != "" {
+			host = u.Host
+		}
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(host, "/")
+	return strings.ToLower(strings.Trim(host, "[]"))
+}
+
+// 计算主域名（eTLD+1），IP地址和无法识别的主机直接返回主机本身
+func apexOf(host string) string {
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	apex, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return apex
+}
+
+// 按主域名分组比较域名，同一主域名下按子域名标签从右到左比较
+func compareDomains(a, b string) int {
+	hostA, hostB := hostOf(a), hostOf(b)
+	if c := strings.Compare(apexOf(hostA), apexOf(hostB)); c != 0 {
+		return c
+	}
+	labelsA := strings.Split(hostA, ".")
+	labelsB := strings.Split(hostB, ".")
+	for i, j := len(labelsA)-1, len(labelsB)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if c := strings.Compare(labelsA[i], labelsB[j]); c != 0 {
+			return c
+		}
+	}
+	return len(labelsA) - len(labelsB)
+}
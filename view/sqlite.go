@@ -0,0 +1,119 @@
+package view
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"subdomain-checker/checker"
+
+	_ "modernc.org/sqlite"
+)
+
+// 数据库结构：每次运行在 scans 中增加一行，结果通过 scan_id 关联，已有的数据库只追加不覆盖
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS scans (
+  scan_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  start_time TEXT,
+  end_time TEXT,
+  version TEXT,
+  command TEXT,
+  total INTEGER,
+  alive INTEGER,
+  protected INTEGER,
+  dead INTEGER,
+  partial INTEGER
+);
+CREATE TABLE IF NOT EXISTS results (
+  scan_id INTEGER NOT NULL REFERENCES scans(scan_id),
+  domain TEXT,
+  status INTEGER,
+  status_text TEXT,
+  alive INTEGER,
+  response_time_ms INTEGER,
+  page_type TEXT,
+  title TEXT,
+  message TEXT,
+  screenshot_path TEXT,
+  final_url TEXT,
+  error_class TEXT
+);
+CREATE INDEX IF NOT EXISTS results_scan_id ON results(scan_id);
+CREATE INDEX IF NOT EXISTS results_domain ON results(domain);
+`
+
+// 保存结果到SQLite数据库（-sqlite），数据库已存在时追加为新的一次运行（新的 scan_id）。
+// 在一个事务中写入，失败时数据库保持不变
+func SaveResultsToSQLite(results []checker.Result, dbPath string, filter ExportFilter, meta *RunMeta) error {
+	if err := ensureOutputDir(dbPath); err != nil {
+		return err
+	}
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := writeSQLiteScan(tx, results, filter, meta); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// 在事务中建表并写入一次运行和它的结果
+func writeSQLiteScan(tx *sql.Tx, results []checker.Result, filter ExportFilter, meta *RunMeta) error {
+	var alive, protected, dead int
+	for _, result := range results {
+		switch {
+		case result.Alive:
+			alive++
+		case checker.IsProtected(result):
+			protected++
+		default:
+			dead++
+		}
+	}
+	var startTime, endTime, version, command string
+	total := len(results)
+	partial := false
+	if meta != nil {
+		startTime, endTime = meta.StartTime.Format(time.RFC3339), meta.EndTime.Format(time.RFC3339)
+		version, command = meta.Version, meta.Command
+		total = max(total, meta.Targets)
+		partial = meta.Partial
+	}
+
+	if _, err := tx.Exec(sqliteSchema); err != nil {
+		return fmt.Errorf("创建数据表失败: %v", err)
+	}
+	scan, err := tx.Exec("INSERT INTO scans (start_time, end_time, version, command, total, alive, protected, dead, partial) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		startTime, endTime, version, command, total, alive, protected, dead, partial)
+	if err != nil {
+		return fmt.Errorf("写入运行记录失败: %v", err)
+	}
+	scanID, err := scan.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	insert, err := tx.Prepare("INSERT INTO results (scan_id, domain, status, status_text, alive, response_time_ms, page_type, title, message, screenshot_path, final_url, error_class) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+	for _, result := range results {
+		if filter.Skip(result) {
+			continue
+		}
+		if _, err := insert.Exec(scanID, result.Domain, result.Status, result.StatusText, result.Alive,
+			result.ResponseTime.Milliseconds(), pageTypeOf(&result), decodeTitle(result.Title),
+			result.Message, result.Screenshot, result.FinalURL, result.ErrorClass); err != nil {
+			return fmt.Errorf("写入 %s 的结果失败: %v", result.Domain, err)
+		}
+	}
+	return nil
+}
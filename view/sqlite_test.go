@@ -0,0 +1,76 @@
+package view
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"subdomain-checker/checker"
+)
+
+// 写入SQLite数据库后读取：每次运行追加一个 scan_id，结果的各列与写入的相同，导出过滤同样生效
+func TestSaveResultsToSQLite(t *testing.T) {
+	results := sampleResults()
+	results[0].ResponseTime = 120 * time.Millisecond
+	results[0].PageInfo = &checker.PageType{Type: "登录页面"}
+	dbPath := filepath.Join(t.TempDir(), "sub", "scans.db")
+	meta := &RunMeta{Version: "test", Command: "squirrel -sqlite scans.db", Targets: 10}
+
+	if err := SaveResultsToSQLite(results, dbPath, ExportFilter{}, meta); err != nil {
+		t.Fatalf("第一次写入失败: %v", err)
+	}
+	if err := SaveResultsToSQLite(results, dbPath, ExportFilter{OnlyAlive: true}, nil); err != nil {
+		t.Fatalf("第二次写入失败: %v", err)
+	}
+
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var scans, total, alive, protected, dead int
+	var command string
+	if err := db.QueryRow("SELECT count(*) FROM scans").Scan(&scans); err != nil {
+		t.Fatal(err)
+	}
+	if scans != 2 {
+		t.Fatalf("运行记录有 %d 行，应为2行", scans)
+	}
+	if err := db.QueryRow("SELECT command, total, alive, protected, dead FROM scans WHERE scan_id = 1").Scan(&command, &total, &alive, &protected, &dead); err != nil {
+		t.Fatal(err)
+	}
+	if command != meta.Command || total != 10 || alive != 3 || protected != 1 || dead != 1 {
+		t.Errorf("运行记录为 %q %d/%d/%d/%d", command, total, alive, protected, dead)
+	}
+
+	counts := map[int]int{}
+	rows, err := db.Query("SELECT scan_id, count(*) FROM results GROUP BY scan_id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var scanID, count int
+		if err := rows.Scan(&scanID, &count); err != nil {
+			t.Fatal(err)
+		}
+		counts[scanID] = count
+	}
+	rows.Close()
+	if counts[1] != len(results) || counts[2] != 3 {
+		t.Errorf("每次运行的结果数为 %v，应为 %d 和 3", counts, len(results))
+	}
+
+	var domain, statusText, pageType, title string
+	var status, responseTime int
+	var isAlive bool
+	err = db.QueryRow("SELECT domain, status, status_text, alive, response_time_ms, page_type, title FROM results WHERE scan_id = 1 AND domain = ?", results[0].Domain).
+		Scan(&domain, &status, &statusText, &isAlive, &responseTime, &pageType, &title)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != 200 || statusText != "存活" || !isAlive || responseTime != 120 || pageType != "登录页面" || title != results[0].Title {
+		t.Errorf("读取到 %d %q %v %d %q %q", status, statusText, isAlive, responseTime, pageType, title)
+	}
+}
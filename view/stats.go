@@ -0,0 +1,103 @@
+package view
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"subdomain-checker/checker"
+)
+
+// 存活主机的响应时间统计
+type ResponseTimeStats struct {
+	Count  int
+	Min    time.Duration
+	Median time.Duration
+	P90    time.Duration
+	P95    time.Duration
+	Max    time.Duration
+	Avg    time.Duration
+}
+
+// 统计存活主机的响应时间（不包含请求失败和超时的结果），没有存活主机时 Count 为0
+func ComputeResponseTimeStats(results []checker.Result) ResponseTimeStats {
+	var times []time.Duration
+	var sum time.Duration
+	for _, result := range results {
+		if !result.Alive {
+			continue
+		}
+		times = append(times, result.ResponseTime)
+		sum += result.ResponseTime
+	}
+
+	stats := ResponseTimeStats{Count: len(times)}
+	if stats.Count == 0 {
+		return stats
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	stats.Min = times[0]
+	stats.Median = percentile(times, 50)
+	stats.P90 = percentile(times, 90)
+	stats.P95 = percentile(times, 95)
+	stats.Max = times[len(times)-1]
+	stats.Avg = sum / time.Duration(stats.Count)
+	return stats
+}
+
+// 按最近秩法计算已排序数据的百分位数
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// 存活主机的响应大小统计（字节），包括取自 Content-Length 的近似值
+type BodySizeStats struct {
+	Count  int
+	Min    int64
+	Median int64
+	Max    int64
+}
+
+// 统计存活主机的响应大小，大小未知的结果不计入，没有时 Count 为0
+func ComputeBodySizeStats(results []checker.Result) BodySizeStats {
+	var sizes []int64
+	for _, result := range results {
+		if result.Alive && bodySizeOf(&result) != "" {
+			sizes = append(sizes, result.BodySize)
+		}
+	}
+
+	stats := BodySizeStats{Count: len(sizes)}
+	if stats.Count == 0 {
+		return stats
+	}
+	slices.Sort(sizes)
+	stats.Min = sizes[0]
+	stats.Median = sizes[(len(sizes)+1)/2-1]
+	stats.Max = sizes[len(sizes)-1]
+	return stats
+}
+
+// 获取响应最慢的前 n 个存活主机，响应时间相同时保持原有顺序
+func TopSlowest(results []checker.Result, n int) []checker.Result {
+	if n <= 0 {
+		return nil
+	}
+	var alive []checker.Result
+	for _, result := range results {
+		if result.Alive {
+			alive = append(alive, result)
+		}
+	}
+	sort.SliceStable(alive, func(i, j int) bool {
+		return alive[i].ResponseTime > alive[j].ResponseTime
+	})
+	if len(alive) > n {
+		alive = alive[:n]
+	}
+	return alive
+}
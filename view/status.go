@@ -0,0 +1,63 @@
+package view
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"subdomain-checker/checker"
+)
+
+// 状态分布：键为状态码，请求失败时为错误类别
+type StatusCounts map[string]int
+
+// 单个状态的数量
+type StatusCount struct {
+	Key   string
+	Label string // 状态码的说明，错误类别为"请求失败"
+	Count int
+}
+
+// 获取结果在状态分布中的键
+func StatusKey(result checker.Result) string {
+	if result.Status != 0 {
+		return strconv.Itoa(result.Status)
+	}
+	if result.ErrorClass != "" {
+		return result.ErrorClass
+	}
+	return checker.ErrorOther
+}
+
+// 记录一条结果
+func (c StatusCounts) Add(result checker.Result) {
+	c[StatusKey(result)]++
+}
+
+// 按数量从多到少排序，数量相同时按键排序
+func (c StatusCounts) Sorted() []StatusCount {
+	counts := make([]StatusCount, 0, len(c))
+	for key, count := range c {
+		label := "请求失败"
+		if code, err := strconv.Atoi(key); err == nil {
+			label = http.StatusText(code)
+		}
+		counts = append(counts, StatusCount{Key: key, Label: label, Count: count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Key < counts[j].Key
+	})
+	return counts
+}
+
+// 格式化数量，每三位加逗号分隔
+func formatCount(n int) string {
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
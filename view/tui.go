@@ -0,0 +1,387 @@
+package view
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"subdomain-checker/checker"
+	"subdomain-checker/utils"
+
+	"golang.org/x/term"
+	"golang.org/x/text/width"
+)
+
+// 交互式界面的刷新间隔
+const tuiRefreshInterval = 250 * time.Millisecond
+
+// 跳到第一行或最后一行时的移动距离
+const maxMove = 1 << 30
+
+// 提示信息（如已复制）的显示时间
+const tuiNoticeDuration = 3 * time.Second
+
+// 交互式界面的筛选条件，按 a 依次切换
+const (
+	tuiShowAll       = iota // 全部
+	tuiShowAlive            // 只看存活
+	tuiShowProtected        // 只看受保护
+	tuiFilterCount
+)
+
+// 交互式界面的操作回调
+type TUIControls struct {
+	Pause    func(paused bool) // 按 p 或空格时暂停/恢复检测
+	QueueLen func() int        // 截图队列中等待的任务数，未启用截图时为nil
+	Quit     func()            // 按 q 或 Ctrl+C 时调用，与收到中断信号的处理相同
+}
+
+// 表格中的一行，只保留显示需要的字段
+type tuiRow struct {
+	domain    string
+	alive     bool
+	protected bool // 受保护（401/403/407）
+	status    string
+	ms        int64
+	pageType  string
+	title     string
+}
+
+// 交互式终端界面（-tui）：实时显示检测结果表格、计数和截图队列，支持暂停、筛选和复制域名
+type TUI struct {
+	controls  TUIControls
+	total     int
+	startTime time.Time
+	oldState  *term.State
+	release   func()
+	done      chan struct{}
+	stopOnce  sync.Once
+
+	mu        sync.Mutex
+	rows      []tuiRow
+	alive     int
+	protected int
+	paused    bool
+	filter    int  // 筛选条件，tuiShowAll 等
+	selected  int  // 选中行在筛选后列表中的下标
+	follow    bool // 选中行跟随最新的结果
+	lastLog   string
+	notice    string
+	noticeAt  time.Time
+}
+
+// 终端是否支持交互式界面：标准输入和输出都是终端，且不是哑终端
+func TUISupported() bool {
+	return utils.IsTerminal(os.Stdin) && utils.IsTerminal(os.Stdout) && os.Getenv("TERM") != "dumb"
+}
+
+// 启动交互式界面，切换到终端的备用屏幕；终端无法进入原始模式时返回错误，调用方应改用进度条
+func StartTUI(total int, startTime time.Time, controls TUIControls) (*TUI, error) {
+	oldState, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		return nil, fmt.Errorf("无法进入交互式界面: %v", err)
+	}
+	t := &TUI{
+		controls:  controls,
+		total:     total,
+		startTime: startTime,
+		oldState:  oldState,
+		done:      make(chan struct{}),
+		follow:    true,
+	}
+	// 界面运行期间的日志只在底部显示最后一条，退出后再完整输出
+	t.release = utils.CaptureConsole(func(text string) {
+		if line := strings.TrimSpace(text); line != "" {
+			t.mu.Lock()
+			t.lastLog = line
+			t.mu.Unlock()
+		}
+	})
+	fmt.Fprint(os.Stdout, "\033[?1049h\033[?25l")
+
+	go t.readKeys()
+	go func() {
+		ticker := time.NewTicker(tuiRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				t.render()
+			case <-t.done:
+				return
+			}
+		}
+	}()
+	t.render()
+	return t, nil
+}
+
+// 追加检测结果
+func (t *TUI) Add(result checker.Result) {
+	status := result.StatusText
+	if result.Status != 0 {
+		status = strconv.Itoa(result.Status)
+	}
+	row := tuiRow{
+		domain:    displayDomain(result),
+		alive:     result.Alive,
+		protected: checker.IsProtected(result),
+		status:    status,
+		ms:        result.ResponseTime.Milliseconds(),
+		pageType:  pageTypeOf(&result),
+		title:     strings.TrimSpace(decodeTitle(result.Title)),
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rows = append(t.rows, row)
+	if row.alive {
+		t.alive++
+	} else if row.protected {
+		t.protected++
+	}
+}
+
+// 设置暂停状态，用于显示通过信号等界面以外的方式暂停或恢复
+func (t *TUI) SetPaused(paused bool) {
+	t.mu.Lock()
+	t.paused = paused
+	t.mu.Unlock()
+}
+
+// 退出界面并恢复终端，输出界面运行期间的日志；可以多次调用
+func (t *TUI) Stop() {
+	t.stopOnce.Do(func() {
+		close(t.done)
+		t.mu.Lock()
+		fmt.Fprint(os.Stdout, "\033[?25h\033[?1049l")
+		term.Restore(int(os.Stdin.Fd()), t.oldState)
+		t.mu.Unlock()
+		t.release()
+	})
+}
+
+func (t *TUI) stopped() bool {
+	select {
+	case <-t.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// 读取按键。标准输入的读取无法取消，界面退出后读到的按键直接丢弃
+func (t *TUI) readKeys() {
+	buf := make([]byte, 16)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil || t.stopped() {
+			return
+		}
+		switch key := string(buf[:n]); key {
+		case "q", "\x03": // Ctrl+C 在原始模式下不会产生信号
+			if t.controls.Quit != nil {
+				t.controls.Quit()
+			}
+			return
+		case "p", " ":
+			t.mu.Lock()
+			t.paused = !t.paused
+			paused := t.paused
+			t.mu.Unlock()
+			if t.controls.Pause != nil {
+				t.controls.Pause(paused)
+			}
+		case "a":
+			t.mu.Lock()
+			t.filter = (t.filter + 1) % tuiFilterCount
+			t.selected, t.follow = 0, true
+			t.mu.Unlock()
+		case "k", "\033[A":
+			t.move(-1)
+		case "j", "\033[B":
+			t.move(1)
+		case "\033[5~": // PageUp
+			t.move(-t.pageSize())
+		case "\033[6~": // PageDown
+			t.move(t.pageSize())
+		case "g", "\033[H":
+			t.move(-maxMove)
+		case "G", "\033[F":
+			t.move(maxMove)
+		case "c":
+			t.copySelected()
+		}
+		t.render()
+	}
+}
+
+// 当前筛选条件下显示的行，调用方需持有 t.mu
+func (t *TUI) visibleRows() []tuiRow {
+	if t.filter == tuiShowAll {
+		return t.rows
+	}
+	var rows []tuiRow
+	for _, row := range t.rows {
+		if (t.filter == tuiShowAlive && row.alive) || (t.filter == tuiShowProtected && row.protected) {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// 移动选中行，移到最后一行时恢复跟随最新结果
+func (t *TUI) move(delta int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := len(t.visibleRows())
+	if t.follow {
+		t.selected = n - 1
+	}
+	t.selected = max(0, min(n-1, t.selected+delta))
+	t.follow = t.selected == n-1
+}
+
+// 通过 OSC 52 转义序列把选中的域名复制到终端的剪贴板
+func (t *TUI) copySelected() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	rows := t.visibleRows()
+	if t.selected < 0 || t.selected >= len(rows) {
+		return
+	}
+	domain := rows[t.selected].domain
+	fmt.Fprintf(os.Stdout, "\033]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(domain)))
+	t.notice, t.noticeAt = "已复制: "+domain, time.Now()
+}
+
+// 终端尺寸，无法获取时使用 80x24
+func terminalSize() (int, int) {
+	w, h, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || w <= 0 || h <= 0 {
+		return 80, 24
+	}
+	return w, h
+}
+
+// 表格可显示的行数（去掉标题、进度、筛选、表头、日志和按键说明各一行）
+func (t *TUI) pageSize() int {
+	_, h := terminalSize()
+	return max(1, h-6)
+}
+
+// 重绘整个界面
+func (t *TUI) render() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopped() {
+		return
+	}
+	w, _ := terminalSize()
+	pageSize := t.pageSize()
+	rows := t.visibleRows()
+	if t.follow {
+		t.selected = len(rows) - 1
+	}
+
+	var sb strings.Builder
+	line := func(s string) {
+		sb.WriteString(s)
+		sb.WriteString("\033[K\r\n")
+	}
+
+	state := "检测中"
+	if t.paused {
+		state = "\033[33m已暂停\033[0m"
+	}
+	line(fmt.Sprintf("\033[1m松鼠子域名检测\033[0m  %s", state))
+
+	processed := len(t.rows)
+	percent := 0.0
+	if t.total > 0 {
+		percent = float64(processed) / float64(t.total) * 100
+	}
+	filled := min(progressBarWidth, int(percent/100*progressBarWidth))
+	progress := fmt.Sprintf("[%s%s] %.1f%% %d/%d | \033[32m存活 %d\033[0m \033[33m受保护 %d\033[0m \033[31m无法访问 %d\033[0m",
+		strings.Repeat("█", filled), strings.Repeat("░", progressBarWidth-filled),
+		percent, processed, t.total, t.alive, t.protected, processed-t.alive-t.protected)
+	if t.controls.QueueLen != nil {
+		progress += fmt.Sprintf(" | 截图队列 %d", t.controls.QueueLen())
+	}
+	progress += " | 耗时 " + time.Since(t.startTime).Round(time.Second).String()
+	line(progress)
+
+	filter := [tuiFilterCount]string{"全部", "只看存活", "只看受保护"}[t.filter]
+	line(fmt.Sprintf("显示: %s (%d)", filter, len(rows)))
+
+	// 列宽：状态、响应时间和页面类型固定，域名和标题平分剩余宽度
+	const statusWidth, msWidth, typeWidth = 10, 8, 10
+	rest := max(20, w-statusWidth-msWidth-typeWidth-4)
+	domainWidth := rest / 2
+	titleWidth := rest - domainWidth
+	line("\033[1m" + fitWidth("状态", statusWidth) + " " + fitWidth("域名", domainWidth) + " " +
+		fitWidth("响应", msWidth) + " " + fitWidth("页面类型", typeWidth) + " " + fitWidth("标题", titleWidth) + "\033[0m")
+
+	// 保持选中行可见，跟随时显示最新的一页
+	start := max(0, len(rows)-pageSize)
+	if t.selected >= 0 && t.selected < start {
+		start = t.selected
+	}
+	for i := start; i < start+pageSize; i++ {
+		if i >= len(rows) {
+			line("")
+			continue
+		}
+		row := rows[i]
+		color := "\033[31m"
+		if row.alive {
+			color = "\033[32m"
+		} else if row.protected {
+			color = "\033[33m"
+		}
+		ms := "-"
+		if row.alive {
+			ms = fmt.Sprintf("%dms", row.ms)
+		}
+		text := color + fitWidth(row.status, statusWidth) + "\033[39m " + fitWidth(row.domain, domainWidth) + " " +
+			fitWidth(ms, msWidth) + " " + fitWidth(row.pageType, typeWidth) + " " + fitWidth(row.title, titleWidth)
+		if i == t.selected {
+			text = "\033[7m" + text + "\033[27m"
+		}
+		line(text)
+	}
+
+	footer := t.lastLog
+	if t.notice != "" && time.Since(t.noticeAt) < tuiNoticeDuration {
+		footer = t.notice
+	}
+	line("\033[2m" + fitWidth(footer, w) + "\033[0m")
+	sb.WriteString("\033[7m" + fitWidth(" p 暂停/继续  a 切换筛选  ↑↓ 选择  c 复制域名  q 退出", w) + "\033[0m\033[J")
+
+	fmt.Fprint(os.Stdout, "\033[H"+sb.String())
+}
+
+// 按终端显示宽度截断或补齐字符串，中文等全角字符占两列
+func fitWidth(s string, w int) string {
+	var sb strings.Builder
+	used := 0
+	for _, r := range s {
+		rw := 1
+		switch width.LookupRune(r).Kind() {
+		case width.EastAsianWide, width.EastAsianFullwidth:
+			rw = 2
+		}
+		if r < ' ' {
+			continue
+		}
+		if used+rw > w {
+			break
+		}
+		sb.WriteRune(r)
+		used += rw
+	}
+	return sb.String() + strings.Repeat(" ", w-used)
+}
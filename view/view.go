@@ -1,43 +1,148 @@
 package view
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
 	"fmt"
 	"html/template"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
-	"sync"
 	"sync/atomic"
 	"time"
 	"unicode/utf8"
 
 	"subdomain-checker/checker"
 	"subdomain-checker/config"
+	"subdomain-checker/utils"
 
+	"github.com/fogleman/gg"
 	"github.com/xuri/excelize/v2"
 	"golang.org/x/text/encoding/simplifiedchinese"
 	"golang.org/x/text/transform"
 )
 
-// 显示进度
-func ShowProgress(processed *int32, totalDomains int, startTime time.Time, doneChan, progressDone chan struct{}) {
+// 进度速率的平滑窗口
+const progressRateWindow = 10 * time.Second
+
+// 进度条宽度（字符数）
+const progressBarWidth = 30
+
+// 进度采样点
+type progressSample struct {
+	at    time.Time
+	count int32
+}
+
+// 显示进度：终端中显示带速率和预计剩余时间的进度条，非终端时定期输出简单的进度行。
+// label 不为空时作为前缀显示，用于区分复查等阶段；shots 返回已完成和需要的截图数（未启用截图时为nil），
+// 截图在检测之后继续进行，检测全部完成后进度条切换为截图阶段。受保护的数量不为0时单独显示
+func ShowProgress(label string, processed, alive, protected, dead *int32, totalDomains int, startTime time.Time, paused func() bool, shots func() (done, total int), doneChan, progressDone chan struct{}) {
+	prefix := ""
+	if label != "" {
+		prefix = label + " "
+	}
+	counts := func() string {
+		if n := atomic.LoadInt32(protected); n > 0 {
+			return fmt.Sprintf("存活 %d 受保护 %d 无法访问 %d", atomic.LoadInt32(alive), n, atomic.LoadInt32(dead))
+		}
+		return fmt.Sprintf("存活 %d 无法访问 %d", atomic.LoadInt32(alive), atomic.LoadInt32(dead))
+	}
+
 	// 启动进度显示goroutine
 	go func() {
 		defer close(progressDone)
-		ticker := time.NewTicker(500 * time.Millisecond) // 更新频率提高到0.5秒一次
+		defer utils.ClearStatus()
+
+		isTerminal := utils.IsTerminal(os.Stdout)
+		interval := 500 * time.Millisecond // 更新频率0.5秒一次
+		if !isTerminal {
+			interval = 5 * time.Second // 非终端（如重定向到文件）时降低输出频率
+		}
+		ticker := time.NewTicker(interval)
 		defer ticker.Stop()
 
+		samples := []progressSample{{at: startTime, count: 0}}
+		screenshotPhase := false
+
 		for {
 			select {
 			case <-ticker.C:
-				current := atomic.LoadInt32(processed)
-				if current >= int32(totalDomains) {
+				// 当前阶段的进度：先是HTTP检测，检测全部完成后是剩余的截图
+				phase, current, total := "", atomic.LoadInt32(processed), int32(totalDomains)
+				shotInfo := ""
+				if shots != nil {
+					shotsDone, shotsTotal := shots()
+					phase = "检测 "
+					if current >= total {
+						if shotsDone >= shotsTotal {
+							return
+						}
+						if !screenshotPhase {
+							screenshotPhase = true
+							samples = []progressSample{{at: time.Now(), count: int32(shotsDone)}}
+						}
+						phase, current, total = "截图 ", int32(shotsDone), int32(shotsTotal)
+					} else {
+						shotInfo = fmt.Sprintf(" | 截图 %d/%d", shotsDone, shotsTotal)
+					}
+				} else if current >= total {
 					return
 				}
-				percent := float64(current) / float64(totalDomains) * 100
-				fmt.Printf("\r进度: %.2f%% (%d/%d) - 耗时: %.1fs",
-					percent, current, totalDomains, time.Since(startTime).Seconds())
+				percent := float64(current) / float64(total) * 100
+				elapsed := time.Since(startTime)
+
+				// 暂停期间不计算速率，恢复后重新采样，剩余时间不受暂停时长影响
+				if paused != nil && paused() {
+					samples = []progressSample{{at: time.Now(), count: current}}
+					if !isTerminal {
+						fmt.Printf("%s%s进度: 已暂停 %.2f%% (%d/%d) - 耗时: %.1fs\n",
+							prefix, phase, percent, current, total, elapsed.Seconds())
+						continue
+					}
+					filled := int(percent / 100 * progressBarWidth)
+					bar := strings.Repeat("█", filled) + strings.Repeat("░", progressBarWidth-filled)
+					utils.SetStatus(fmt.Sprintf("%s%s[%s] %.1f%% %d/%d | %s | %s%s | 耗时 %s",
+						prefix, phase, bar, percent, current, total, utils.Color("33", "已暂停"),
+						counts(), shotInfo, elapsed.Round(time.Second)))
+					continue
+				}
+
+				if !isTerminal {
+					fmt.Printf("%s%s进度: %.2f%% (%d/%d)%s - 耗时: %.1fs\n",
+						prefix, phase, percent, current, total, shotInfo, elapsed.Seconds())
+					continue
+				}
+
+				// 只保留窗口内的采样点，计算平滑后的速率
+				now := time.Now()
+				samples = append(samples, progressSample{at: now, count: current})
+				for len(samples) > 2 && now.Sub(samples[1].at) > progressRateWindow {
+					samples = samples[1:]
+				}
+				oldest := samples[0]
+				rate := 0.0
+				if span := now.Sub(oldest.at).Seconds(); span > 0 {
+					rate = float64(current-oldest.count) / span
+				}
+
+				eta := "--"
+				if rate > 0 {
+					remaining := time.Duration(float64(total-current)/rate) * time.Second
+					eta = remaining.Round(time.Second).String()
+				}
+
+				filled := int(percent / 100 * progressBarWidth)
+				bar := strings.Repeat("█", filled) + strings.Repeat("░", progressBarWidth-filled)
+				utils.SetStatus(fmt.Sprintf("%s%s[%s] %.1f%% %d/%d | %.1f/s | %s%s | 剩余 %s | 耗时 %s",
+					prefix, phase, bar, percent, current, total, rate,
+					counts(), shotInfo, eta, elapsed.Round(time.Second)))
 			case <-doneChan:
 				return
 			}
@@ -45,76 +150,285 @@ func ShowProgress(processed *int32, totalDomains int, startTime time.Time, doneC
 	}()
 }
 
+// 单条结果中标题的最大显示长度
+const resultTitleMaxLen = 50
+
+// 打印单条检测结果（-verbose），终端中按存活状态着色，与进度条交替输出；showTime 时包含响应时间
+func PrintResult(result checker.Result, showTime bool) {
+	mark, color := "✗", "31"
+	if result.Alive {
+		mark, color = "✓", "32"
+	}
+
+	status := result.StatusText
+	if result.Status != 0 {
+		status = strconv.Itoa(result.Status)
+	}
+
+	line := fmt.Sprintf("%s  %s", utils.Color(color, mark+" "+displayDomain(result)), status)
+	if showTime {
+		line += fmt.Sprintf("  %dms", result.ResponseTime.Milliseconds())
+	}
+	if title := strings.TrimSpace(decodeTitle(result.Title)); title != "" {
+		line += "  " + utils.Truncate(title, resultTitleMaxLen)
+	}
+	utils.Printf("%s\n", line)
+}
+
 // 打印总结
-func PrintSummary(total, alive, dead int, cfg *config.Config, pageTypeCount map[string]int, pageTypeCountMutex *sync.Mutex, screenshotCount int32, totalTime time.Duration) {
+func PrintSummary(stats *RunStats, cfg *config.Config, results []checker.Result) {
 	// 打印表头
 	fmt.Println("\n检测结果 (总结):")
 	fmt.Println("----------------------------------------")
 
 	// 输出总结
-	fmt.Printf("总计: %d 个域名, %d 个存活, %d 个无法访问\n", total, alive, dead)
+	fmt.Printf("总计: %d 个域名, %d 个存活, %d 个无法访问\n", stats.Total, stats.Alive, stats.Dead)
+	if stats.Hosts > 0 {
+		fmt.Printf("按主机: %d 个主机, %d 个存活（任一路径存活）\n", stats.Hosts, stats.AliveHosts)
+	}
+	if stats.Protected > 0 {
+		fmt.Printf("受保护: %d 个（401/403/407，有响应但需要认证或禁止访问）\n", stats.Protected)
+	}
+	if stats.Excluded > 0 {
+		fmt.Printf("已排除: %d 个目标\n", stats.Excluded)
+	}
+	if stats.LimitNote != "" {
+		fmt.Printf("抽样/上限: %s，%d 个目标未检测\n", stats.LimitNote, stats.Limited)
+	}
+
+	// 状态分布，按数量从多到少排列
+	if len(stats.StatusCounts) > 0 {
+		var parts []string
+		for _, sc := range stats.StatusCounts.Sorted() {
+			parts = append(parts, fmt.Sprintf("%s: %s", sc.Key, formatCount(sc.Count)))
+		}
+		fmt.Printf("状态分布: %s\n", strings.Join(parts, ", "))
+	}
+
+	if stats.Rechecked > 0 {
+		fmt.Printf("复查: %d 个无法访问的目标, %d 个恢复存活\n", stats.Rechecked, stats.Recovered)
+	}
+
+	// 存活主机的响应时间分布
+	if rt := stats.ResponseTimes; rt.Count > 0 {
+		fmt.Printf("响应时间: 最短 %dms, 中位数 %dms, P90 %dms, P95 %dms, 最长 %dms, 平均 %dms\n",
+			rt.Min.Milliseconds(), rt.Median.Milliseconds(), rt.P90.Milliseconds(),
+			rt.P95.Milliseconds(), rt.Max.Milliseconds(), rt.Avg.Milliseconds())
+	}
+	if bs := stats.BodySizes; bs.Count > 0 {
+		fmt.Printf("响应大小: 最小 %s, 中位数 %s, 最大 %s\n", formatBytes(bs.Min), formatBytes(bs.Median), formatBytes(bs.Max))
+	}
 
 	// 如果启用了页面信息提取，显示页面类型统计
-	if cfg.ExtractInfo && len(pageTypeCount) > 0 {
+	if cfg.ExtractInfo && len(stats.PageTypes) > 0 {
 		fmt.Println("页面类型统计:")
-		pageTypeCountMutex.Lock()
-		for pageType, count := range pageTypeCount {
+		for pageType, count := range stats.PageTypes {
 			fmt.Printf("  %s: %d 个\n", pageType, count)
 		}
-		pageTypeCountMutex.Unlock()
+	}
+
+	// 启用页面信息提取时，显示存活主机使用的技术
+	if cfg.ExtractInfo && len(stats.Fingerprints) > 0 {
+		fmt.Println("技术指纹统计 (存活主机):")
+		for _, name := range stats.SortedFingerprints() {
+			fmt.Printf("  %s: %d 个\n", name, stats.Fingerprints[name])
+		}
+	}
+
+	// 显示响应最慢的存活主机，便于进一步排查
+	if slowest := TopSlowest(results, cfg.Top); len(slowest) > 0 {
+		fmt.Printf("响应最慢的存活主机 (前%d个):\n", len(slowest))
+		for _, result := range slowest {
+			fmt.Printf("  %s: %dms\n", result.Domain, result.ResponseTime.Milliseconds())
+		}
+	}
+
+	// 标题和内容相同的主机分组：默认页面等噪音，或同一个共享的系统
+	if len(stats.Clusters) > 0 {
+		fmt.Printf("重复页面 (共%d组，标题和内容相同的主机):\n", len(stats.Clusters))
+		for i, cluster := range stats.Clusters {
+			if i == clusterPrintMax {
+				break
+			}
+			fmt.Printf("  %d 个主机: %s，如 %s\n", len(cluster.Domains), cluster.Describe(), cluster.Domains[0])
+		}
+	}
+
+	// 图标哈希相同的主机分组，便于发现同一种应用
+	if len(stats.FaviconGroups) > 0 {
+		fmt.Printf("相同图标 (共%d组，图标哈希相同的主机):\n", len(stats.FaviconGroups))
+		for i, group := range stats.FaviconGroups {
+			if i == clusterPrintMax {
+				break
+			}
+			fmt.Printf("  %d 个主机: 哈希 %s，如 %s\n", len(group.Domains), group.Hash, group.Domains[0])
+		}
+	}
+
+	if stats.CrossSite > 0 {
+		fmt.Printf("跨站重定向: %d 个主机重定向到其他主域名（可能指向第三方服务）\n", stats.CrossSite)
+	}
+
+	// 涉及多个主域名时，显示存活数量最多的主域名
+	if groups := GroupByApex(results); len(groups) > 1 {
+		fmt.Println("存活数量最多的主域名:")
+		for _, group := range topApexGroups(groups, 5) {
+			if group.Protected > 0 {
+				fmt.Printf("  %s: %d 个存活, %d 个受保护, %d 个无法访问\n", group.Apex, group.Alive, group.Protected, group.Dead)
+			} else {
+				fmt.Printf("  %s: %d 个存活, %d 个无法访问\n", group.Apex, group.Alive, group.Dead)
+			}
+		}
 	}
 
 	// 显示截图统计
 	if cfg.Screenshot || cfg.ScreenshotAlive {
-		if cfg.ScreenshotAlive {
-			fmt.Printf("成功截图存活网站: %d 个\n", screenshotCount)
+		if cfg.ScreenshotProtect {
+			fmt.Printf("成功截图存活和受保护的网站: %d 个\n", stats.Screenshots)
+		} else if cfg.ScreenshotAlive {
+			fmt.Printf("成功截图存活网站: %d 个\n", stats.Screenshots)
 		} else {
-			fmt.Printf("成功截图: %d 个\n", screenshotCount)
+			fmt.Printf("成功截图: %d 个\n", stats.Screenshots)
 		}
 	}
 
-	fmt.Printf("检测耗时: %.2f 秒\n", totalTime.Seconds())
+	fmt.Printf("检测耗时: %.2f 秒\n", stats.Duration.Seconds())
+	if stats.Throttled > 0 {
+		fmt.Printf("内存限流: %s（内存占用接近 -max-memory 时暂缓了截图和检测）\n", stats.Throttled.Round(time.Second))
+	}
+	if stats.SlowDowns > 0 {
+		fmt.Printf("自适应并发: 因超时和连接被拒绝突增降低并发 %d 次，最低 %d\n", stats.SlowDowns, stats.MinConc)
+	}
+	if stats.AutoMax > 0 {
+		fmt.Printf("自动并发: 最低 %d, 最高 %d, 通常 %d（可作为以后运行的 -concurrency）\n", stats.AutoMin, stats.AutoMax, stats.AutoTypical)
+	}
+	if stats.RateGroups > 0 {
+		fmt.Printf("按主域名限速: %d 个主域名分组，同组请求间隔 %s\n", stats.RateGroups, cfg.RatePerHost)
+	}
 }
 
-// 保存结果到文件
-func SaveResultsToFile(results []checker.Result, filename string) error {
+// 创建输出文件所在的目录（如果不存在）
+func ensureOutputDir(filename string) error {
+	outputDir := filepath.Dir(filename)
+	if outputDir != "" && outputDir != "." {
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return fmt.Errorf("创建输出目录失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// gzip压缩的输出文件，关闭时先写完gzip尾部再关闭文件
+type gzipFile struct {
+	*gzip.Writer
+	file *os.File
+}
+
+func (g *gzipFile) Close() error {
+	err := g.Writer.Close()
+	if cerr := g.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// 创建输出文件，文件名以 .gz 结尾时自动使用gzip压缩
+func createOutput(filename string) (io.WriteCloser, error) {
+	if err := ensureOutputDir(filename); err != nil {
+		return nil, err
+	}
 	file, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(filename, ".gz") {
+		return &gzipFile{Writer: gzip.NewWriter(file), file: file}, nil
+	}
+	return file, nil
+}
+
+// 导出的结果范围
+type ExportFilter struct {
+	OnlyAlive        bool // 只导出存活的结果（-only-alive）
+	IncludeProtected bool // 只导出存活的结果时保留受保护的结果（-include-protected）
+}
+
+// 是否跳过该结果
+func (f ExportFilter) Skip(result checker.Result) bool {
+	if !f.OnlyAlive || result.Alive {
+		return false
+	}
+	return !f.IncludeProtected || !checker.IsProtected(result)
+}
+
+// 显示用的域名：国际化域名同时显示Unicode和punycode形式
+func displayDomain(result checker.Result) string {
+	if result.IDN == "" {
+		return result.Domain
+	}
+	return fmt.Sprintf("%s (%s)", result.IDN, result.Domain)
+}
+
+// 保存结果到CSV文件，文件名以 .gz 结尾时使用gzip压缩
+func SaveResultsToFile(results []checker.Result, filename string, filter ExportFilter, meta *RunMeta, fields []Field) (err error) {
+	if fields == nil {
+		fields, _ = ParseFields(DefaultCSVFields)
+	}
+
+	file, err := createOutput(filename)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	defer func() {
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+	}()
 
-	// 写入标题行
-	fmt.Fprintf(file, "域名,状态,状态码,响应时间(毫秒),页面类型,页面标题,消息\n")
+	// 写入运行元数据（# 开头的注释行）
+	if meta != nil {
+		for _, field := range meta.Fields() {
+			fmt.Fprintf(file, "# %s: %s\n", field[0], field[1])
+		}
+	}
+
+	// 写入标题行（默认字段中新增的列只追加在末尾，保持原有列的位置）。
+	// 按CSV规则转义，含逗号、引号或换行的值（如带逗号的Location、含引号的标题）加引号输出
+	headers := make([]string, len(fields))
+	for i, field := range fields {
+		headers[i] = field.Header
+	}
+	writer := csv.NewWriter(file)
+	if err := writer.Write(headers); err != nil {
+		return err
+	}
 
 	// 写入数据行
+	values := make([]string, len(fields))
 	for _, result := range results {
-		pageType := ""
-		if result.PageInfo != nil {
-			pageType = result.PageInfo.Type
+		// 如果只导出存活的域名，则跳过非存活的（-include-protected 时保留受保护的）
+		if filter.Skip(result) {
+			continue
+		}
+		for i, field := range fields {
+			values[i] = field.Value(&result)
+		}
+		if err := writer.Write(values); err != nil {
+			return err
 		}
-
-		fmt.Fprintf(file, "%s,%s,%d,%.2f,%s,%s,%s\n",
-			result.Domain,
-			result.StatusText,
-			result.Status,
-			float64(result.ResponseTime.Milliseconds()),
-			pageType,
-			strings.ReplaceAll(result.Title, ",", " "),   // 避免标题中的逗号影响CSV格式
-			strings.ReplaceAll(result.Message, ",", " ")) // 避免消息中的逗号影响CSV格式
 	}
-
-	return nil
+	writer.Flush()
+	return writer.Error()
 }
 
 // 保存结果到 Excel 文件
-func SaveResultsToExcel(results []checker.Result, filename string, onlyAlive bool) error {
+// 主表使用 StreamWriter 流式写入，样式只创建一次，以支持数万行的大规模导出
+func SaveResultsToExcel(results []checker.Result, filename string, cfg *config.Config, meta *RunMeta, stats *RunStats, diff *Diff) error {
+	filter := ExportFilter{OnlyAlive: cfg.OnlyAlive, IncludeProtected: cfg.IncludeProtected}
+
 	// 创建输出目录（如果不存在）
-	outputDir := filepath.Dir(filename)
-	if outputDir != "" && outputDir != "." {
-		if err := os.MkdirAll(outputDir, 0755); err != nil {
-			return fmt.Errorf("创建输出目录失败: %v", err)
-		}
+	if err := ensureOutputDir(filename); err != nil {
+		return err
 	}
 
 	// 创建一个新的 Excel 文件
@@ -125,23 +439,27 @@ func SaveResultsToExcel(results []checker.Result, filename string, onlyAlive boo
 		}
 	}()
 
-	// 设置表头
 	sheetName := "子域名检测结果"
 	f.SetSheetName("Sheet1", sheetName)
-	headers := []string{"域名", "状态", "状态码", "响应时间(毫秒)", "页面类型", "页面标题", "消息", "截图"}
-	for i, header := range headers {
-		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
-		f.SetCellValue(sheetName, cell, header)
+	headers := []string{"域名", "状态", "状态码", "响应时间(毫秒)", "页面类型", "页面标题", "消息", "截图", "主域名", "备注", "IP地址", "技术指纹", "图标哈希", "响应大小(字节)", "重定向链"}
+	const screenshotCol = 8 // 截图所在列（H）
+	// 记录的响应头各占一列，列名为响应头名称
+	capturedHeaders := cfg.CapturedHeaders()
+	headers = append(headers, capturedHeaders...)
+	// 保存了原始响应（-store-response）时在最后添加链接列
+	responses := hasResponses(results)
+	if responses {
+		headers = append(headers, "原始响应")
 	}
 
-	// 创建截图工作表
-	screenshotSheet := "页面截图"
-	f.NewSheet(screenshotSheet)
-	f.SetCellValue(screenshotSheet, "A1", "域名")
-	f.SetCellValue(screenshotSheet, "B1", "截图")
-
-	// 设置表头样式
-	headerStyle, _ := f.NewStyle(&excelize.Style{
+	// 预先创建所有样式，避免每行重复创建
+	border := []excelize.Border{
+		{Type: "left", Color: "#000000", Style: 1},
+		{Type: "right", Color: "#000000", Style: 1},
+		{Type: "top", Color: "#000000", Style: 1},
+		{Type: "bottom", Color: "#000000", Style: 1},
+	}
+	headerStyle, err := f.NewStyle(&excelize.Style{
 		Font: &excelize.Font{Bold: true},
 		Fill: excelize.Fill{
 			Type:    "pattern",
@@ -152,148 +470,210 @@ func SaveResultsToExcel(results []checker.Result, filename string, onlyAlive boo
 			Horizontal: "center",
 			Vertical:   "center",
 		},
-		Border: []excelize.Border{
-			{Type: "left", Color: "#000000", Style: 1},
-			{Type: "right", Color: "#000000", Style: 1},
-			{Type: "top", Color: "#000000", Style: 1},
-			{Type: "bottom", Color: "#000000", Style: 1},
+		Border: border,
+	})
+	if err != nil {
+		return err
+	}
+	contentStyle, err := f.NewStyle(&excelize.Style{Border: border})
+	if err != nil {
+		return err
+	}
+	// 跨站的重定向链使用橙色文字
+	crossSiteStyle, err := f.NewStyle(&excelize.Style{Border: border, Font: &excelize.Font{Color: "#C65911"}})
+	if err != nil {
+		return err
+	}
+	// 取自 Content-Length 的近似响应大小显示为 "~1234"，单元格仍为数值，可以排序
+	approxSizeStyle, err := f.NewStyle(&excelize.Style{Border: border, CustomNumFmt: &approxSizeFormat})
+	if err != nil {
+		return err
+	}
+	linkStyle, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{
+			Color:     "#0563C1",
+			Underline: "single",
+		},
+		Border: border,
+		Alignment: &excelize.Alignment{
+			Horizontal: "center",
+		},
+	})
+	if err != nil {
+		return err
+	}
+	domainLinkStyle, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{
+			Color:     "#0563C1",
+			Underline: "single",
+		},
+		Border: border,
+	})
+	if err != nil {
+		return err
+	}
+	// 受保护（401/403/407）的域名使用橙色链接
+	protectedLinkStyle, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{
+			Color:     "#C65911",
+			Underline: "single",
 		},
+		Border: border,
 	})
-	f.SetCellStyle(sheetName, "A1", fmt.Sprintf("%c1", 'A'+len(headers)-1), headerStyle)
-	f.SetCellStyle(screenshotSheet, "A1", "B1", headerStyle)
+	if err != nil {
+		return err
+	}
+
+	// 流式写入主表
+	sw, err := f.NewStreamWriter(sheetName)
+	if err != nil {
+		return err
+	}
+	// 列宽和冻结表头必须在写入任何行之前设置
+	if err := sw.SetColWidth(1, len(headers), 20); err != nil {
+		return err
+	}
+	if cfg.ExcelInlineThumbs {
+		// 截图列加宽以容纳缩略图
+		if err := sw.SetColWidth(screenshotCol, screenshotCol, 25); err != nil {
+			return err
+		}
+	}
+	if err := sw.SetPanes(&excelize.Panes{
+		Freeze:      true,
+		Split:       false,
+		XSplit:      0,
+		YSplit:      1,
+		TopLeftCell: "A2",
+		ActivePane:  "bottomLeft",
+	}); err != nil {
+		return err
+	}
+
+	headerRow := make([]interface{}, len(headers))
+	for i, header := range headers {
+		headerRow[i] = excelize.Cell{StyleID: headerStyle, Value: header}
+	}
+	if err := sw.SetRow("A1", headerRow); err != nil {
+		return err
+	}
+
+	// 截图工作表，-excel-no-images 时不嵌入图片，只保留主表中的文件链接
+	screenshotSheet := "页面截图"
+	embedImages := !cfg.ExcelNoImages
+	if embedImages {
+		f.NewSheet(screenshotSheet)
+		f.SetCellValue(screenshotSheet, "A1", "域名")
+		f.SetCellValue(screenshotSheet, "B1", "截图")
+		f.SetCellStyle(screenshotSheet, "A1", "B1", headerStyle)
+	}
 
 	// 写入数据行
 	row := 2           // 从第二行开始
 	screenshotRow := 2 // 截图表从第二行开始
 
-	// 创建模板数据
-	data := TemplateData{
-		TotalDomains: len(results),
-		AliveDomains: 0,
-		DeadDomains:  0,
-		ReportTime:   time.Now().Format("2006-01-02 15:04:05"),
-		Results:      make([]TemplateResult, 0, len(results)),
-	}
-
 	for _, result := range results {
-		// 如果只导出存活的域名，则跳过非存活的
-		if onlyAlive && !result.Alive {
+		// 如果只导出存活的域名，则跳过非存活的（-include-protected 时保留受保护的）
+		if filter.Skip(result) {
 			continue
 		}
 
-		// 更新统计数据
-		if result.Alive {
-			data.AliveDomains++
-		} else {
-			data.DeadDomains++
-		}
-
 		pageType := ""
 		if result.PageInfo != nil {
 			pageType = result.PageInfo.Type
 		}
 
-		// 设置单元格样式
-		contentStyle, _ := f.NewStyle(&excelize.Style{
-			Border: []excelize.Border{
-				{Type: "left", Color: "#000000", Style: 1},
-				{Type: "right", Color: "#000000", Style: 1},
-				{Type: "top", Color: "#000000", Style: 1},
-				{Type: "bottom", Color: "#000000", Style: 1},
-			},
-		})
-
-		// 写入一行数据到主表
-		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), result.Domain)
-		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), result.StatusText)
-		f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), result.Status)
-		f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), float64(result.ResponseTime.Milliseconds()))
-		f.SetCellValue(sheetName, fmt.Sprintf("E%d", row), pageType)
-		f.SetCellValue(sheetName, fmt.Sprintf("F%d", row), result.Title)
-		f.SetCellValue(sheetName, fmt.Sprintf("G%d", row), result.Message)
-
-		// 应用内容样式
-		f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("G%d", row), contentStyle)
+		// 截图列：有截图时使用 HYPERLINK 公式（流式写入不支持 SetCellHyperLink）
+		screenshotCell := excelize.Cell{StyleID: contentStyle, Value: "无截图"}
+		if result.Screenshot != "" {
+			screenshot := screenshotRelPath(result.Screenshot)
+			screenshotCell = excelize.Cell{
+				StyleID: linkStyle,
+				Formula: fmt.Sprintf(`HYPERLINK("%s","查看截图")`, escapeFormulaString(screenshot)),
+				Value:   "查看截图",
+			}
+		}
 
-		// 处理域名链接
-		domainLink := result.Domain
-		if !strings.HasPrefix(domainLink, "http://") && !strings.HasPrefix(domainLink, "https://") {
-			domainLink = "http://" + domainLink
+		// 域名列：链接到实际探测的URL，受保护的域名为橙色，无法访问的域名保持默认文字颜色
+		domainCell := excelize.Cell{
+			StyleID: contentStyle,
+			Formula: fmt.Sprintf(`HYPERLINK("%s","%s")`, escapeFormulaString(domainLink(result.Domain)), escapeFormulaString(displayDomain(result))),
+			Value:   displayDomain(result),
+		}
+		if result.Alive {
+			domainCell.StyleID = domainLinkStyle
+		} else if checker.IsProtected(result) {
+			domainCell.StyleID = protectedLinkStyle
 		}
 
-		// 处理截图路径
-		screenshot := ""
-		if result.Screenshot != "" {
-			// 使用相对路径
-			screenshot = filepath.Join("screenshots", filepath.Base(result.Screenshot))
-			// 将路径分隔符转换为正斜杠，确保在HTML中正确显示
-			screenshot = strings.ReplaceAll(screenshot, "\\", "/")
-			// 确保路径以screenshots/开头
-			if !strings.HasPrefix(screenshot, "screenshots/") {
-				screenshot = "screenshots/" + filepath.Base(screenshot)
+		// 在截图列中嵌入缩略图，截图文件不存在时保留普通链接
+		var rowOpts []excelize.RowOpts
+		if cfg.ExcelInlineThumbs && result.Screenshot != "" {
+			if thumb, err := makeThumbnail(result.Screenshot); err == nil {
+				thumbCell, _ := excelize.CoordinatesToCellName(screenshotCol, row)
+				if err := f.AddPictureFromBytes(sheetName, thumbCell, &excelize.Picture{
+					Extension: ".png",
+					File:      thumb,
+					Format: &excelize.GraphicOptions{
+						OffsetX:       4,
+						OffsetY:       4,
+						Hyperlink:     screenshotRelPath(result.Screenshot),
+						HyperlinkType: "External",
+						Positioning:   "oneCell",
+					},
+				}); err == nil {
+					rowOpts = append(rowOpts, excelize.RowOpts{Height: thumbnailRowHeight})
+				} else {
+					fmt.Printf("添加缩略图到Excel时出错: %s\n", err)
+				}
 			}
 		}
 
-		// 处理标题编码
-		title := result.Title
-		if title != "" {
-			// 尝试检测并转换编码
-			if !utf8.ValidString(title) {
-				// 尝试从GBK转换到UTF-8
-				reader := transform.NewReader(strings.NewReader(title), simplifiedchinese.GBK.NewDecoder())
-				if d, err := io.ReadAll(reader); err == nil {
-					title = string(d)
+		redirectStyle := contentStyle
+		if result.RedirectCrossSite {
+			redirectStyle = crossSiteStyle
+		}
+		cells := []interface{}{
+			domainCell,
+			excelize.Cell{StyleID: contentStyle, Value: result.StatusText},
+			excelize.Cell{StyleID: contentStyle, Value: result.Status},
+			excelize.Cell{StyleID: contentStyle, Value: float64(result.ResponseTime.Milliseconds())},
+			excelize.Cell{StyleID: contentStyle, Value: pageType},
+			excelize.Cell{StyleID: contentStyle, Value: decodeTitle(result.Title)},
+			excelize.Cell{StyleID: contentStyle, Value: result.Message},
+			screenshotCell,
+			excelize.Cell{StyleID: contentStyle, Value: ApexOf(result.Domain)},
+			excelize.Cell{StyleID: contentStyle, Value: result.Note},
+			excelize.Cell{StyleID: contentStyle, Value: ipsOf(&result)},
+			excelize.Cell{StyleID: contentStyle, Value: strings.Join(result.Fingerprints, ", ")},
+			excelize.Cell{StyleID: contentStyle, Value: result.FaviconHash},
+			sizeCell(&result, contentStyle, approxSizeStyle),
+			excelize.Cell{StyleID: redirectStyle, Value: strings.Join(result.RedirectChain, redirectSeparator)},
+		}
+		for _, name := range capturedHeaders {
+			cells = append(cells, excelize.Cell{StyleID: contentStyle, Value: result.CapturedHeaders[name]})
+		}
+		if responses {
+			// 显示文件路径而不是"查看响应"，重新读取Excel结果时可以还原
+			responseCell := excelize.Cell{StyleID: contentStyle}
+			if result.Response != "" {
+				path := escapeFormulaString(result.Response)
+				responseCell = excelize.Cell{
+					StyleID: domainLinkStyle,
+					Formula: fmt.Sprintf(`HYPERLINK("%s","%s")`, path, path),
+					Value:   result.Response,
 				}
 			}
+			cells = append(cells, responseCell)
 		}
+		cell, _ := excelize.CoordinatesToCellName(1, row)
+		if err := sw.SetRow(cell, cells, rowOpts...); err != nil {
+			return err
+		}
+		row++
 
-		// 设置状态样式
-		statusClass := "status-dead"
-		domainStatus := "无法访问"
-		if result.Alive {
-			statusClass = "status-alive"
-			domainStatus = "可访问"
-		}
-
-		// 添加到结果列表
-		data.Results = append(data.Results, TemplateResult{
-			Domain:       result.Domain,
-			DomainLink:   domainLink,
-			StatusClass:  statusClass,
-			DomainStatus: domainStatus,
-			StatusText:   result.StatusText,
-			Status:       result.Status,
-			ResponseTime: result.ResponseTime.Seconds() * 1000,
-			PageType:     pageType,
-			Title:        title,
-			Message:      result.Message,
-			Screenshot:   screenshot,
-			Alive:        result.Alive,
-		})
-
-		// 在主表中添加"查看截图"超链接
-		if result.Screenshot != "" {
-			f.SetCellValue(sheetName, fmt.Sprintf("H%d", row), "查看截图")
-			linkStyle, _ := f.NewStyle(&excelize.Style{
-				Font: &excelize.Font{
-					Color:     "#0563C1",
-					Underline: "single",
-				},
-				Border: []excelize.Border{
-					{Type: "left", Color: "#000000", Style: 1},
-					{Type: "right", Color: "#000000", Style: 1},
-					{Type: "top", Color: "#000000", Style: 1},
-					{Type: "bottom", Color: "#000000", Style: 1},
-				},
-				Alignment: &excelize.Alignment{
-					Horizontal: "center",
-				},
-			})
-			f.SetCellStyle(sheetName, fmt.Sprintf("H%d", row), fmt.Sprintf("H%d", row), linkStyle)
-			f.SetCellHyperLink(sheetName, fmt.Sprintf("H%d", row), screenshot, "External")
-		} else {
-			f.SetCellValue(sheetName, fmt.Sprintf("H%d", row), "无截图")
-			f.SetCellStyle(sheetName, fmt.Sprintf("H%d", row), fmt.Sprintf("H%d", row), contentStyle)
+		if !embedImages {
+			continue
 		}
 
 		// 在截图表中添加域名和截图
@@ -317,39 +697,65 @@ func SaveResultsToExcel(results []checker.Result, filename string, onlyAlive boo
 		}
 
 		// 设置单元格样式
-		f.SetCellStyle(screenshotSheet, fmt.Sprintf("A%d", screenshotRow), fmt.Sprintf("A%d", screenshotRow), contentStyle)
-		f.SetCellStyle(screenshotSheet, fmt.Sprintf("B%d", screenshotRow), fmt.Sprintf("B%d", screenshotRow), contentStyle)
+		f.SetCellStyle(screenshotSheet, fmt.Sprintf("A%d", screenshotRow), fmt.Sprintf("B%d", screenshotRow), contentStyle)
 
 		screenshotRow++
-		row++
 	}
 
-	// 自动调整列宽
-	for i := range headers {
-		col, _ := excelize.ColumnNumberToName(i + 1)
-		f.SetColWidth(sheetName, col, col, 20)
+	if err := sw.Flush(); err != nil {
+		return err
 	}
-	f.SetColWidth(screenshotSheet, "A", "A", 40)
-	f.SetColWidth(screenshotSheet, "B", "B", 200) // 加宽截图列以便更好地显示截图（原来是150）
 
-	// 冻结表头
-	f.SetPanes(sheetName, &excelize.Panes{
-		Freeze:      true,
-		Split:       false,
-		XSplit:      0,
-		YSplit:      1,
-		TopLeftCell: "A2",
-		ActivePane:  "bottomLeft",
-	})
-	f.SetPanes(screenshotSheet, &excelize.Panes{
-		Freeze:      true,
-		Split:       false,
-		XSplit:      0,
-		YSplit:      1,
-		TopLeftCell: "A2",
-		ActivePane:  "bottomLeft",
+	if embedImages {
+		f.SetColWidth(screenshotSheet, "A", "A", 40)
+		f.SetColWidth(screenshotSheet, "B", "B", 200) // 加宽截图列以便更好地显示截图（原来是150）
+		f.SetPanes(screenshotSheet, &excelize.Panes{
+			Freeze:      true,
+			Split:       false,
+			XSplit:      0,
+			YSplit:      1,
+			TopLeftCell: "A2",
+			ActivePane:  "bottomLeft",
+		})
+	}
+
+	// 文档属性中同样记录运行元数据
+	f.SetDocProps(&excelize.DocProperties{
+		Creator:     "Squirrel " + meta.Version,
+		Title:       "子域名检测结果",
+		Description: meta.Command,
+		Created:     meta.StartTime.Format(time.RFC3339),
+		Modified:    meta.EndTime.Format(time.RFC3339),
 	})
 
+	// 创建统计工作表，并放在第一个位置作为默认打开的工作表
+	if err := writeSummarySheet(f, summarySheet, results, meta, stats, headerStyle); err != nil {
+		return fmt.Errorf("写入统计工作表失败: %v", err)
+	}
+	if err := f.MoveSheet(summarySheet, sheetName); err != nil {
+		return fmt.Errorf("调整统计工作表位置失败: %v", err)
+	}
+	if index, err := f.GetSheetIndex(summarySheet); err == nil {
+		f.SetActiveSheet(index)
+	}
+
+	// 有内容相同的主机时，在最后添加重复分组工作表
+	if len(stats.Clusters) > 0 {
+		if err := writeClusterSheet(f, stats.Clusters, headerStyle); err != nil {
+			return fmt.Errorf("写入重复分组工作表失败: %v", err)
+		}
+	}
+
+	// 指定了基线时，在统计工作表之后添加变化工作表
+	if diff != nil {
+		if err := writeDiffSheet(f, diff, headerStyle); err != nil {
+			return fmt.Errorf("写入变化工作表失败: %v", err)
+		}
+		if err := f.MoveSheet(diffSheet, sheetName); err != nil {
+			return fmt.Errorf("调整变化工作表位置失败: %v", err)
+		}
+	}
+
 	// 保存文件
 	if err := f.SaveAs(filename); err != nil {
 		return err
@@ -358,18 +764,339 @@ func SaveResultsToExcel(results []checker.Result, filename string, onlyAlive boo
 	return nil
 }
 
+// 缩略图尺寸（像素）及对应的行高（磅）
+const (
+	thumbnailWidth     = 160
+	thumbnailHeight    = 100
+	thumbnailRowHeight = 82
+)
+
+// 生成截图缩略图：截取页面顶部区域并缩小，返回PNG数据
+func makeThumbnail(path string) ([]byte, error) {
+	img, err := gg.LoadImage(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按缩略图比例截取页面顶部，避免长页面被压缩成细条
+	bounds := img.Bounds()
+	srcWidth := float64(bounds.Dx())
+	srcHeight := srcWidth * thumbnailHeight / thumbnailWidth
+	if srcHeight > float64(bounds.Dy()) {
+		srcHeight = float64(bounds.Dy())
+	}
+	scale := thumbnailWidth / srcWidth
+
+	dc := gg.NewContext(thumbnailWidth, int(srcHeight*scale))
+	dc.Scale(scale, scale)
+	dc.DrawImage(img, -bounds.Min.X, -bounds.Min.Y)
+
+	var buf bytes.Buffer
+	if err := dc.EncodePNG(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// 为域名补全协议前缀，未指定协议时使用 http://
+func domainLink(domain string) string {
+	if !strings.HasPrefix(domain, "http://") && !strings.HasPrefix(domain, "https://") {
+		return "http://" + domain
+	}
+	return domain
+}
+
+// 将截图路径转换为以 screenshots/ 开头的相对路径
+func screenshotRelPath(path string) string {
+	// 使用相对路径
+	screenshot := filepath.Join("screenshots", filepath.Base(path))
+	// 将路径分隔符转换为正斜杠，确保在HTML和Excel中正确显示
+	return strings.ReplaceAll(screenshot, "\\", "/")
+}
+
+// 结果中的技术指纹，以", "分隔（与Excel相同）
+func fingerprintsOf(r *checker.Result) string {
+	return strings.Join(r.Fingerprints, ", ")
+}
+
+// 结果中的响应大小（字节），取自 Content-Length 的近似值前加"~"，没有响应或大小未知时为空
+func bodySizeOf(r *checker.Result) string {
+	if r.Status == 0 || r.BodySize < 0 {
+		return ""
+	}
+	size := strconv.FormatInt(r.BodySize, 10)
+	if r.BodySizeApprox {
+		return "~" + size
+	}
+	return size
+}
+
+// CSV和Excel中重定向链各项之间的分隔符
+const redirectSeparator = " -> "
+
+// 布尔值在CSV和静默模式中的形式，为true时为"是"，否则为空
+func yesOrEmpty(b bool) string {
+	if b {
+		return "是"
+	}
+	return ""
+}
+
+// 字节数的可读形式，如 512 B、1.5 KB、2.3 MB
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+// 结果中的IP地址，多个时以空格分隔
+func ipsOf(r *checker.Result) string {
+	return strings.Join(r.IPs, " ")
+}
+
+// 近似响应大小的数字格式
+var approxSizeFormat = `"~"0`
+
+// 响应大小单元格，大小未知时为空，近似值使用 approxStyle
+func sizeCell(r *checker.Result, style, approxStyle int) excelize.Cell {
+	if bodySizeOf(r) == "" {
+		return excelize.Cell{StyleID: style}
+	}
+	if r.BodySizeApprox {
+		style = approxStyle
+	}
+	return excelize.Cell{StyleID: style, Value: r.BodySize}
+}
+
+// 是否有结果保存了原始响应
+func hasResponses(results []checker.Result) bool {
+	for _, result := range results {
+		if result.Response != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// 转义 Excel 公式中的字符串字面量
+func escapeFormulaString(s string) string {
+	return strings.ReplaceAll(s, `"`, `""`)
+}
+
+// 处理标题编码，非UTF-8标题尝试按GBK解码
+func decodeTitle(title string) string {
+	if title == "" || utf8.ValidString(title) {
+		return title
+	}
+	reader := transform.NewReader(strings.NewReader(title), simplifiedchinese.GBK.NewDecoder())
+	if d, err := io.ReadAll(reader); err == nil {
+		return string(d)
+	}
+	return title
+}
+
+// 统计工作表名称
+const summarySheet = "统计"
+
+// 写入统计工作表：运行信息、总计、状态码分布和页面类型统计
+func writeSummarySheet(f *excelize.File, sheet string, results []checker.Result, meta *RunMeta, stats *RunStats, headerStyle int) error {
+	if _, err := f.NewSheet(sheet); err != nil {
+		return err
+	}
+
+	// 运行信息
+	row := 1
+	f.SetCellValue(sheet, "A1", "运行信息")
+	f.SetCellStyle(sheet, "A1", "B1", headerStyle)
+	row++
+	for _, field := range meta.Fields() {
+		f.SetCellValue(sheet, fmt.Sprintf("A%d", row), field[0])
+		f.SetCellValue(sheet, fmt.Sprintf("B%d", row), field[1])
+		row++
+	}
+
+	// 总计
+	row++
+	f.SetCellValue(sheet, fmt.Sprintf("A%d", row), "总计")
+	f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("B%d", row), headerStyle)
+	row++
+	totals := [][]interface{}{
+		{"检测域名", stats.Checked},
+		{"存活", stats.Alive},
+		{"受保护", stats.Protected},
+		{"无法访问", stats.Dead},
+		{"成功截图", stats.Screenshots},
+	}
+	if stats.Hosts > 0 {
+		totals = append(totals, []interface{}{"主机数", stats.Hosts}, []interface{}{"存活主机（任一路径存活）", stats.AliveHosts})
+	}
+	for _, item := range totals {
+		f.SetCellValue(sheet, fmt.Sprintf("A%d", row), item[0])
+		f.SetCellValue(sheet, fmt.Sprintf("B%d", row), item[1])
+		row++
+	}
+
+	// 存活主机的响应时间分布
+	if rt := stats.ResponseTimes; rt.Count > 0 {
+		row++
+		f.SetCellValue(sheet, fmt.Sprintf("A%d", row), "响应时间(毫秒)")
+		f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("B%d", row), headerStyle)
+		row++
+		times := [][]interface{}{
+			{"最短", rt.Min.Milliseconds()},
+			{"中位数", rt.Median.Milliseconds()},
+			{"P90", rt.P90.Milliseconds()},
+			{"P95", rt.P95.Milliseconds()},
+			{"最长", rt.Max.Milliseconds()},
+			{"平均", rt.Avg.Milliseconds()},
+		}
+		for _, item := range times {
+			f.SetCellValue(sheet, fmt.Sprintf("A%d", row), item[0])
+			f.SetCellValue(sheet, fmt.Sprintf("B%d", row), item[1])
+			row++
+		}
+	}
+
+	// 存活主机的响应大小分布
+	if bs := stats.BodySizes; bs.Count > 0 {
+		row++
+		f.SetCellValue(sheet, fmt.Sprintf("A%d", row), "响应大小(字节)")
+		f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("B%d", row), headerStyle)
+		row++
+		for _, item := range [][]interface{}{{"最小", bs.Min}, {"中位数", bs.Median}, {"最大", bs.Max}} {
+			f.SetCellValue(sheet, fmt.Sprintf("A%d", row), item[0])
+			f.SetCellValue(sheet, fmt.Sprintf("B%d", row), item[1])
+			row++
+		}
+	}
+
+	// 状态分布，与终端总结使用同一份统计
+	row++
+	f.SetCellValue(sheet, fmt.Sprintf("A%d", row), "状态码")
+	f.SetCellValue(sheet, fmt.Sprintf("B%d", row), "说明")
+	f.SetCellValue(sheet, fmt.Sprintf("C%d", row), "数量")
+	f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("C%d", row), headerStyle)
+	row++
+	statuses := stats.StatusCounts.Sorted()
+	statusFirstRow := row
+	for _, sc := range statuses {
+		f.SetCellValue(sheet, fmt.Sprintf("A%d", row), sc.Key)
+		f.SetCellValue(sheet, fmt.Sprintf("B%d", row), sc.Label)
+		f.SetCellValue(sheet, fmt.Sprintf("C%d", row), sc.Count)
+		row++
+	}
+	statusLastRow := row - 1
+
+	// 页面类型统计
+	if len(stats.PageTypes) > 0 {
+		row++
+		f.SetCellValue(sheet, fmt.Sprintf("A%d", row), "页面类型")
+		f.SetCellValue(sheet, fmt.Sprintf("B%d", row), "数量")
+		f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("B%d", row), headerStyle)
+		row++
+		pageTypes := make([]string, 0, len(stats.PageTypes))
+		for pageType := range stats.PageTypes {
+			pageTypes = append(pageTypes, pageType)
+		}
+		sort.Strings(pageTypes)
+		for _, pageType := range pageTypes {
+			f.SetCellValue(sheet, fmt.Sprintf("A%d", row), pageType)
+			f.SetCellValue(sheet, fmt.Sprintf("B%d", row), stats.PageTypes[pageType])
+			row++
+		}
+	}
+
+	// 技术指纹统计
+	if len(stats.Fingerprints) > 0 {
+		row++
+		f.SetCellValue(sheet, fmt.Sprintf("A%d", row), "技术指纹")
+		f.SetCellValue(sheet, fmt.Sprintf("B%d", row), "数量")
+		f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("B%d", row), headerStyle)
+		row++
+		for _, name := range stats.SortedFingerprints() {
+			f.SetCellValue(sheet, fmt.Sprintf("A%d", row), name)
+			f.SetCellValue(sheet, fmt.Sprintf("B%d", row), stats.Fingerprints[name])
+			row++
+		}
+	}
+
+	// 主域名小计
+	row++
+	f.SetCellValue(sheet, fmt.Sprintf("A%d", row), "主域名")
+	f.SetCellValue(sheet, fmt.Sprintf("B%d", row), "存活")
+	f.SetCellValue(sheet, fmt.Sprintf("C%d", row), "无法访问")
+	f.SetCellValue(sheet, fmt.Sprintf("D%d", row), "受保护")
+	f.SetCellStyle(sheet, fmt.Sprintf("A%d", row), fmt.Sprintf("D%d", row), headerStyle)
+	row++
+	for _, group := range GroupByApex(results) {
+		f.SetCellValue(sheet, fmt.Sprintf("A%d", row), group.Apex)
+		f.SetCellValue(sheet, fmt.Sprintf("B%d", row), group.Alive)
+		f.SetCellValue(sheet, fmt.Sprintf("C%d", row), group.Dead)
+		f.SetCellValue(sheet, fmt.Sprintf("D%d", row), group.Protected)
+		row++
+	}
+
+	f.SetColWidth(sheet, "A", "A", 20)
+	f.SetColWidth(sheet, "B", "B", 60)
+	f.SetColWidth(sheet, "C", "C", 12)
+
+	// 状态码分布柱状图
+	if len(statuses) > 0 {
+		return f.AddChart(sheet, "E2", &excelize.Chart{
+			Type: excelize.Col,
+			Series: []excelize.ChartSeries{{
+				Name:       fmt.Sprintf("'%s'!$C$%d", sheet, statusFirstRow-1),
+				Categories: fmt.Sprintf("'%s'!$A$%d:$A$%d", sheet, statusFirstRow, statusLastRow),
+				Values:     fmt.Sprintf("'%s'!$C$%d:$C$%d", sheet, statusFirstRow, statusLastRow),
+			}},
+			Title:  []excelize.RichTextRun{{Text: "状态码分布"}},
+			Legend: excelize.ChartLegend{Position: "none"},
+		})
+	}
+
+	return nil
+}
+
 // 定义模板数据结构
 type TemplateData struct {
-	TotalDomains int
-	AliveDomains int
-	DeadDomains  int
-	ReportTime   string
-	Results      []TemplateResult
+	TotalDomains     int
+	AliveDomains     int
+	ProtectedDomains int // 受保护（401/403/407），不计入 AliveDomains 和 DeadDomains
+	DeadDomains      int
+	ReportTime       string
+	ResponseTimes    ResponseTimeStats
+	Results          []TemplateResult
+	Groups           []TemplateGroup
+	Slowest          []TemplateResult // 响应最慢的存活主机，只在详细版报告中显示
+	Statuses         []TemplateStatus // 状态分布
+	Diff             *Diff            // 与基线相比的变化，未指定基线时为nil
+	Meta             *RunMeta         // 运行元数据，显示在页脚
+	DuplicateHosts   int              // 内容相同的主机中每组只保留一个时隐藏的主机数，为0时不显示"隐藏重复页面"
+}
+
+// 状态分布中的一项，Percent 为相对最大数量的百分比，用于绘制横向柱状图
+type TemplateStatus struct {
+	StatusCount
+	Percent float64
+}
+
+// 按主域名分组的结果，用于侧边栏的折叠分组
+type TemplateGroup struct {
+	Apex      string
+	Alive     int
+	Protected int
+	Dead      int
+	Results   []TemplateResult
 }
 
 // 定义单个域名结果的数据结构
 type TemplateResult struct {
 	Domain       string
+	IDN          string // 国际化域名的Unicode形式
 	DomainLink   string
 	StatusClass  string
 	DomainStatus string
@@ -379,12 +1106,161 @@ type TemplateResult struct {
 	PageType     string
 	Title        string
 	Message      string
+	Note         string // 输入文件中的行尾备注
 	Screenshot   string
+	Response     string // -store-response 保存的原始响应文件
+	IPs          string // 连接的IP地址，多个时以空格分隔
+	UserAgent    string // 请求使用的User-Agent，使用默认值时为空
+	Proxy        string // 请求使用的代理（已隐藏密码），未使用代理时为空
+	Method       string // -head 时得到状态码的请求方法
+	BodySize     string // 响应大小的可读形式，近似值前加"约"，大小未知时为空
+	Redirects    string // 重定向链，各项以箭头连接，没有重定向时为空
+	CrossSite    bool   // 重定向离开了起始主机的主域名
+	FaviconHash  string // -favicon 的图标哈希
 	Alive        bool
+	Protected    bool
+	Headers      []TemplateHeader // 响应头，只在详细版报告中填充
+	HeaderText   string           // 响应头的文本形式（每行"名称: 值"），用于搜索
+	Captured     []TemplateHeader // 记录的响应头，默认记录的几个在前
+	Fingerprints []string         // -extract 识别的技术，显示为标签
+	SameContent  int              // 内容相同的其他主机数量
+	DupGroup     int              // 内容相同的主机分组编号，没有相同内容时为0
+	Duplicates   []TemplateResult // 侧边栏中折叠在该结果下的内容相同的主机
+	contentKey   string           // 内容相同判断依据（响应内容哈希）
+}
+
+// 单个响应头
+type TemplateHeader struct {
+	Name  string
+	Value string
+}
+
+// 转换单个检测结果为模板数据
+func newTemplateResult(result checker.Result) TemplateResult {
+	statusClass := "status-dead"
+	domainStatus := "dead"
+	protected := checker.IsProtected(result)
+	if result.Alive {
+		statusClass = "status-alive"
+		domainStatus = "alive"
+	} else if protected {
+		statusClass = "status-protected"
+		domainStatus = "protected"
+	}
+
+	pageType := "-"
+	if result.PageInfo != nil {
+		pageType = result.PageInfo.Type
+	}
+
+	// 处理截图路径
+	screenshot := ""
+	if result.Screenshot != "" {
+		screenshot = screenshotRelPath(result.Screenshot)
+	}
+
+	return TemplateResult{
+		Domain:       result.Domain,
+		IDN:          result.IDN,
+		DomainLink:   domainLink(result.Domain),
+		StatusClass:  statusClass,
+		DomainStatus: domainStatus,
+		StatusText:   result.StatusText,
+		Status:       result.Status,
+		ResponseTime: result.ResponseTime.Seconds() * 1000,
+		PageType:     pageType,
+		Title:        decodeTitle(result.Title), // 处理标题编码
+		Message:      result.Message,
+		Note:         result.Note,
+		Screenshot:   screenshot,
+		Response:     result.Response,
+		IPs:          ipsOf(&result),
+		UserAgent:    result.UserAgent,
+		Proxy:        result.Proxy,
+		Method:       result.Method,
+		BodySize:     templateBodySize(&result),
+		Redirects:    strings.Join(result.RedirectChain, " → "),
+		CrossSite:    result.RedirectCrossSite,
+		FaviconHash:  result.FaviconHash,
+		Fingerprints: result.Fingerprints,
+		Captured:     capturedHeaderList(result.CapturedHeaders),
+		Alive:        result.Alive,
+		Protected:    protected,
+		contentKey:   result.BodyHash,
+	}
+}
+
+// 记录的响应头列表，默认记录的几个按固定顺序在前，其余按名称排序
+func capturedHeaderList(captured map[string]string) []TemplateHeader {
+	var headers []TemplateHeader
+	for _, name := range config.DefaultCapturedHeaders {
+		if value, ok := captured[name]; ok {
+			headers = append(headers, TemplateHeader{Name: name, Value: value})
+		}
+	}
+	var extra []string
+	for name := range captured {
+		if !slices.Contains(config.DefaultCapturedHeaders, name) {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		headers = append(headers, TemplateHeader{Name: name, Value: captured[name]})
+	}
+	return headers
+}
+
+// HTML报告中的响应大小，如 "1234 字节 (1.2 KB)"，取自 Content-Length 时注明为约数
+func templateBodySize(r *checker.Result) string {
+	if bodySizeOf(r) == "" {
+		return ""
+	}
+	size := fmt.Sprintf("%d 字节", r.BodySize)
+	if r.BodySize >= 1<<10 {
+		size += fmt.Sprintf(" (%s)", formatBytes(r.BodySize))
+	}
+	if r.BodySizeApprox {
+		return "约 " + size + "（Content-Length）"
+	}
+	return size
+}
+
+// 按名称排序响应头，同名的多个值分别显示
+func templateHeaders(header http.Header) ([]TemplateHeader, string) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var headers []TemplateHeader
+	var lines []string
+	for _, name := range names {
+		for _, value := range header[name] {
+			headers = append(headers, TemplateHeader{Name: name, Value: value})
+			lines = append(lines, name+": "+value)
+		}
+	}
+	return headers, strings.Join(lines, "\n")
 }
 
 // 保存结果到HTML文件（简化版）
-func SaveResultsToSimpleHTML(results []checker.Result, filename string, onlyAlive bool) error {
+func SaveResultsToSimpleHTML(results []checker.Result, filename string, filter ExportFilter, meta *RunMeta, statusCounts StatusCounts, diff *Diff) error {
+	return saveHTML(results, filename, filter, meta, statusCounts, diff, false, 0)
+}
+
+// 保存结果到HTML文件（带详细信息：响应头），topN 大于0时附带响应最慢的存活主机列表
+func SaveResultsToHTML(results []checker.Result, filename string, filter ExportFilter, meta *RunMeta, statusCounts StatusCounts, diff *Diff, topN int) error {
+	return saveHTML(results, filename, filter, meta, statusCounts, diff, true, topN)
+}
+
+// 使用模板生成HTML报告
+func saveHTML(results []checker.Result, filename string, filter ExportFilter, meta *RunMeta, statusCounts StatusCounts, diff *Diff, detailed bool, topN int) error {
+	if err := ensureOutputDir(filename); err != nil {
+		return err
+	}
+
 	// 创建HTML文件
 	file, err := os.Create(filename)
 	if err != nil {
@@ -397,82 +1273,74 @@ func SaveResultsToSimpleHTML(results []checker.Result, filename string, onlyAliv
 
 	// 计算统计信息并准备模板数据
 	data := TemplateData{
-		ReportTime: time.Now().Format("2006-01-02 15:04:05"),
+		ReportTime:    time.Now().Format("2006-01-02 15:04:05"),
+		ResponseTimes: ComputeResponseTimeStats(results),
+		Diff:          diff,
+		Meta:          meta,
 	}
 
 	// 处理结果数据
 	for _, result := range results {
-		// 如果只显示存活域名，跳过非存活的
-		if onlyAlive && !result.Alive {
+		// 如果只显示存活域名，跳过非存活的（-include-protected 时保留受保护的）
+		if filter.Skip(result) {
 			continue
 		}
 
 		data.TotalDomains++
 		if result.Alive {
 			data.AliveDomains++
+		} else if checker.IsProtected(result) {
+			data.ProtectedDomains++
 		}
 
-		// 准备单个结果数据
-		statusClass := "status-dead"
-		domainStatus := "dead"
-		if result.Alive {
-			statusClass = "status-alive"
-			domainStatus = "alive"
+		item := newTemplateResult(result)
+		if detailed {
+			item.Headers, item.HeaderText = templateHeaders(result.Headers)
 		}
+		data.Results = append(data.Results, item)
+	}
+	data.DeadDomains = data.TotalDomains - data.AliveDomains - data.ProtectedDomains
 
-		pageType := "-"
-		if result.PageInfo != nil {
-			pageType = result.PageInfo.Type
-		}
+	for _, result := range TopSlowest(results, topN) {
+		data.Slowest = append(data.Slowest, newTemplateResult(result))
+	}
 
-		// 处理域名链接
-		domainLink := result.Domain
-		if !strings.HasPrefix(domainLink, "http://") && !strings.HasPrefix(domainLink, "https://") {
-			domainLink = "http://" + domainLink
-		}
+	// 状态分布，柱长按最大数量归一化
+	statuses := statusCounts.Sorted()
+	for _, sc := range statuses {
+		data.Statuses = append(data.Statuses, TemplateStatus{
+			StatusCount: sc,
+			Percent:     float64(sc.Count) / float64(statuses[0].Count) * 100,
+		})
+	}
 
-		// 处理截图路径
-		screenshot := ""
-		if result.Screenshot != "" {
-			// 使用相对路径
-			screenshot = filepath.Join("screenshots", filepath.Base(result.Screenshot))
-			// 将路径分隔符转换为正斜杠，确保在HTML中正确显示
-			screenshot = strings.ReplaceAll(screenshot, "\\", "/")
-			// 确保路径以screenshots/开头
-			if !strings.HasPrefix(screenshot, "screenshots/") {
-				screenshot = "screenshots/" + filepath.Base(screenshot)
-			}
-		}
+	data.DuplicateHosts = countSameContent(data.Results)
 
-		// 处理标题编码
-		title := result.Title
-		if title != "" {
-			// 尝试检测并转换编码
-			if !utf8.ValidString(title) {
-				// 尝试从GBK转换到UTF-8
-				reader := transform.NewReader(strings.NewReader(title), simplifiedchinese.GBK.NewDecoder())
-				if d, err := io.ReadAll(reader); err == nil {
-					title = string(d)
-				}
-			}
+	// 按主域名分组，分组顺序与结果顺序一致
+	groupIndex := make(map[string]int)
+	for _, result := range data.Results {
+		apex := ApexOf(result.Domain)
+		i, ok := groupIndex[apex]
+		if !ok {
+			i = len(data.Groups)
+			groupIndex[apex] = i
+			data.Groups = append(data.Groups, TemplateGroup{Apex: apex})
+		}
+		switch {
+		case result.Alive:
+			data.Groups[i].Alive++
+		case result.Protected:
+			data.Groups[i].Protected++
+		default:
+			data.Groups[i].Dead++
 		}
+		data.Groups[i].Results = append(data.Groups[i].Results, result)
+	}
 
-		data.Results = append(data.Results, TemplateResult{
-			Domain:       result.Domain,
-			DomainLink:   domainLink,
-			StatusClass:  statusClass,
-			DomainStatus: domainStatus,
-			StatusText:   result.StatusText,
-			Status:       result.Status,
-			ResponseTime: result.ResponseTime.Seconds() * 1000,
-			PageType:     pageType,
-			Title:        title,
-			Message:      result.Message,
-			Screenshot:   screenshot,
-			Alive:        result.Alive,
-		})
+	// 侧边栏中折叠同一主域名下内容相同的主机
+	for i := range data.Groups {
+		data.Groups[i].Results = clusterDuplicates(data.Groups[i].Results)
 	}
-	data.DeadDomains = data.TotalDomains - data.AliveDomains
 
 	// 解析模板文件
 	tmpl, err := template.ParseFiles("view/template.html")
@@ -487,8 +1355,3 @@ func SaveResultsToSimpleHTML(results []checker.Result, filename string, onlyAliv
 
 	return nil
 }
-
-// 保存结果到HTML文件（带详细信息）
-func SaveResultsToHTML(results []checker.Result, filename string, onlyAlive bool) error {
-	return SaveResultsToSimpleHTML(results, filename, onlyAlive)
-}
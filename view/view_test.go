@@ -0,0 +1,183 @@
+package view
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+
+	"subdomain-checker/checker"
+	"subdomain-checker/config"
+)
+
+// 含逗号、引号和换行的值按CSV规则转义，用CSV库和基线读取都能得到原值
+func TestSaveResultsToFileEscaping(t *testing.T) {
+	results := []checker.Result{{
+		Domain:          "https://a.example.com",
+		Status:          302,
+		Alive:           true,
+		StatusText:      "重定向",
+		Title:           `Say "hi", then leave`,
+		Message:         "line one\nline two",
+		FinalURL:        "https://a.example.com/x?ids=1,2,3",
+		CapturedHeaders: map[string]string{"Location": "https://sso.example.com/?next=a,b"},
+		Fingerprints:    []string{"Nginx", "Spring Boot"},
+		BodySize:        -1,
+	}}
+	fields, err := ParseFields("domain,title,message,final_url,location,fingerprint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "out.csv")
+	if err := SaveResultsToFile(results, filename, ExportFilter{}, nil, fields); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("输出不是有效的CSV: %v", err)
+	}
+	want := []string{"https://a.example.com", `Say "hi", then leave`, "line one\nline two", "https://a.example.com/x?ids=1,2,3", "https://sso.example.com/?next=a,b", "Nginx, Spring Boot"}
+	if len(rows) != 2 || len(rows[1]) != len(want) {
+		t.Fatalf("读取到 %d 行: %q", len(rows), rows)
+	}
+	for i, value := range want {
+		if rows[1][i] != value {
+			t.Errorf("第 %d 列为 %q，应为 %q", i+1, rows[1][i], value)
+		}
+	}
+
+	loaded, err := LoadResults(filename, InputAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 1 || loaded[0].Title != results[0].Title || loaded[0].FinalURL != results[0].FinalURL {
+		t.Errorf("基线读取结果为 %+v", loaded)
+	}
+	if got := loaded[0].Fingerprints; len(got) != 2 || got[1] != "Spring Boot" {
+		t.Errorf("技术指纹为 %q", got)
+	}
+}
+
+// 5万行结果写入Excel（流式写入的主表）和CSV时的耗时和内存分配
+func BenchmarkSaveResults50k(b *testing.B) {
+	results := make([]checker.Result, 50000)
+	for i := range results {
+		results[i] = checker.Result{
+			Domain:       fmt.Sprintf("https://host%d.example.com", i),
+			Status:       200,
+			Alive:        true,
+			StatusText:   "存活",
+			Message:      "OK",
+			Title:        "Example Domain",
+			ResponseTime: 120 * time.Millisecond,
+			FinalURL:     fmt.Sprintf("https://host%d.example.com/", i),
+			BodySize:     1256,
+		}
+	}
+	dir := b.TempDir()
+	cfg := &config.Config{ExcelNoImages: true}
+	stats := ComputeRunStats(results, len(results), false, nil, 0)
+
+	b.Run("excel", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			if err := SaveResultsToExcel(results, filepath.Join(dir, "out.xlsx"), cfg, &RunMeta{}, stats, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	// 只写主表时流式写入与改为流式写入之前逐个单元格写入的对比，两者写入相同的列和样式
+	sheet := func(write func(f *excelize.File, results []checker.Result) error) func(b *testing.B) {
+		return func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				f := excelize.NewFile()
+				if err := write(f, results); err != nil {
+					b.Fatal(err)
+				}
+				if err := f.SaveAs(filepath.Join(dir, "sheet.xlsx")); err != nil {
+					b.Fatal(err)
+				}
+				f.Close()
+			}
+		}
+	}
+	b.Run("sheet-setcellvalue", sheet(writeSheetByCell))
+	b.Run("sheet-stream", sheet(writeSheetStreamed))
+	b.Run("csv", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			if err := SaveResultsToFile(results, filepath.Join(dir, "out.csv"), ExportFilter{}, nil, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// 基准测试用的边框样式
+var benchBorder = []excelize.Border{
+	{Type: "left", Color: "#000000", Style: 1},
+	{Type: "right", Color: "#000000", Style: 1},
+	{Type: "top", Color: "#000000", Style: 1},
+	{Type: "bottom", Color: "#000000", Style: 1},
+}
+
+// 主表每行的前8列
+func benchRow(result checker.Result) []interface{} {
+	return []interface{}{result.Domain, result.StatusText, result.Status, float64(result.ResponseTime.Milliseconds()),
+		"", result.Title, result.Message, "无截图"}
+}
+
+// 改为流式写入之前的写法：每行创建一次样式，逐个单元格 SetCellValue 后再设置样式
+func writeSheetByCell(f *excelize.File, results []checker.Result) error {
+	for i, result := range results {
+		row := i + 2
+		style, err := f.NewStyle(&excelize.Style{Border: benchBorder})
+		if err != nil {
+			return err
+		}
+		for col, value := range benchRow(result) {
+			cell, _ := excelize.CoordinatesToCellName(col+1, row)
+			if err := f.SetCellValue("Sheet1", cell, value); err != nil {
+				return err
+			}
+		}
+		if err := f.SetCellStyle("Sheet1", fmt.Sprintf("A%d", row), fmt.Sprintf("H%d", row), style); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveResultsToExcel 的写法：样式只创建一次，用 StreamWriter 逐行写入
+func writeSheetStreamed(f *excelize.File, results []checker.Result) error {
+	style, err := f.NewStyle(&excelize.Style{Border: benchBorder})
+	if err != nil {
+		return err
+	}
+	sw, err := f.NewStreamWriter("Sheet1")
+	if err != nil {
+		return err
+	}
+	for i, result := range results {
+		cells := benchRow(result)
+		for col, value := range cells {
+			cells[col] = excelize.Cell{StyleID: style, Value: value}
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		if err := sw.SetRow(cell, cells); err != nil {
+			return err
+		}
+	}
+	return sw.Flush()
+}
@@ -0,0 +1,195 @@
+package web
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"subdomain-checker/checker"
+	"subdomain-checker/view"
+)
+
+// 页面本身不依赖运行目录，编译进程序中
+//
+//go:embed index.html
+var indexHTML []byte
+
+// 关闭服务器时等待进行中请求的最长时间
+const shutdownTimeout = 5 * time.Second
+
+// 检测过程中实时展示结果的Web服务器，并发安全
+type Server struct {
+	addr          string
+	screenshotDir string
+	listener      net.Listener
+	srv           *http.Server
+
+	mu        sync.Mutex
+	start     time.Time
+	total     int
+	alive     int
+	protected int
+	results   []webResult
+	finished  bool
+	files     []string // 检测结束后可下载的输出文件
+}
+
+// 页面使用的结果，在JSON输出的字段之外标出受保护（401/403/407）的主机
+type webResult struct {
+	view.JSONResult
+	Protected bool `json:"protected"`
+}
+
+func newWebResult(result checker.Result) webResult {
+	return webResult{JSONResult: view.NewJSONResult(result), Protected: checker.IsProtected(result)}
+}
+
+// 创建服务器，total 为目标总数，screenshotDir 为截图目录（以 /screenshots/ 提供访问）
+func NewServer(addr, screenshotDir string, total int) *Server {
+	s := &Server{addr: addr, screenshotDir: screenshotDir, total: total, start: time.Now()}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleIndex)
+	mux.HandleFunc("/api/results", s.handleResults)
+	mux.HandleFunc("/files/", s.handleFile)
+	mux.Handle("/screenshots/", http.StripPrefix("/screenshots/", http.FileServer(http.Dir(screenshotDir))))
+	s.srv = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	return s
+}
+
+// 开始监听，端口被占用等错误立即返回
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("启动Web界面失败: %v", err)
+	}
+	s.listener = listener
+	go s.srv.Serve(listener)
+	return nil
+}
+
+// 访问地址，监听所有地址时显示为 localhost
+func (s *Server) URL() string {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+		host = "localhost"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
+
+// 追加一批检测结果
+func (s *Server) Add(results []checker.Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, result := range results {
+		item := newWebResult(result)
+		if item.Alive {
+			s.alive++
+		} else if item.Protected {
+			s.protected++
+		}
+		s.results = append(s.results, item)
+	}
+}
+
+// 检测结束，用最终（排序、复查后）的结果替换实时结果，并提供输出文件下载
+func (s *Server) Finish(results []checker.Result, files []string) {
+	items := make([]webResult, len(results))
+	alive, protected := 0, 0
+	for i, result := range results {
+		items[i] = newWebResult(result)
+		if items[i].Alive {
+			alive++
+		} else if items[i].Protected {
+			protected++
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results, s.alive, s.protected = items, alive, protected
+	s.files = files
+	s.finished = true
+}
+
+// 关闭服务器，等待进行中的请求完成
+func (s *Server) Shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	s.srv.Shutdown(ctx)
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(indexHTML)
+}
+
+// 结果接口的响应，页面轮询时通过 since 只获取新增的结果；finished 为 true 后结果已被最终结果替换，页面应以 since=0 整体重新获取
+type resultsResponse struct {
+	Total          int         `json:"total"`
+	Processed      int         `json:"processed"`
+	Alive          int         `json:"alive"`
+	Protected      int         `json:"protected"` // 受保护（401/403/407）的数量，不计入 Alive 和 Dead
+	Dead           int         `json:"dead"`
+	ElapsedSeconds float64     `json:"elapsed_seconds"`
+	Finished       bool        `json:"finished"`
+	Files          []string    `json:"files"`
+	Results        []webResult `json:"results"`
+}
+
+func (s *Server) handleResults(w http.ResponseWriter, r *http.Request) {
+	since, _ := strconv.Atoi(r.URL.Query().Get("since"))
+
+	s.mu.Lock()
+	if since < 0 || since > len(s.results) {
+		since = 0
+	}
+	resp := resultsResponse{
+		Total:          max(s.total, len(s.results)),
+		Processed:      len(s.results),
+		Alive:          s.alive,
+		Protected:      s.protected,
+		Dead:           len(s.results) - s.alive - s.protected,
+		ElapsedSeconds: time.Since(s.start).Seconds(),
+		Finished:       s.finished,
+		Results:        append([]webResult(nil), s.results[since:]...),
+	}
+	for _, file := range s.files {
+		resp.Files = append(resp.Files, filepath.Base(file))
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// 下载输出文件，只允许访问本次生成的文件
+func (s *Server) handleFile(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/files/")
+	s.mu.Lock()
+	var path string
+	for _, file := range s.files {
+		if filepath.Base(file) == name {
+			path = file
+			break
+		}
+	}
+	s.mu.Unlock()
+	if path == "" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	http.ServeFile(w, r, path)
+}
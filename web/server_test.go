@@ -0,0 +1,56 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"subdomain-checker/checker"
+)
+
+// 受保护的主机单独计数，不计入存活和不存活，每条结果带有 protected 标记
+func TestResultsCountsProtected(t *testing.T) {
+	s := NewServer("127.0.0.1:0", t.TempDir(), 5)
+	s.Add([]checker.Result{
+		{Domain: "https://a.example.com", Status: 200, Alive: true},
+		{Domain: "https://b.example.com", Status: 401},
+		{Domain: "https://c.example.com", Status: 403},
+		{Domain: "https://d.example.com", Status: 404},
+		{Domain: "e.example.com"},
+	})
+
+	check := func(t *testing.T) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		s.handleResults(rec, httptest.NewRequest("GET", "/api/results", nil))
+		var resp struct {
+			resultsResponse
+			Results []struct {
+				Domain    string `json:"domain"`
+				Protected bool   `json:"protected"`
+			} `json:"results"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Alive != 1 || resp.Protected != 2 || resp.Dead != 2 {
+			t.Errorf("存活 %d、受保护 %d、不存活 %d，应为 1、2、2", resp.Alive, resp.Protected, resp.Dead)
+		}
+		for i, result := range resp.Results {
+			if want := i == 1 || i == 2; result.Protected != want {
+				t.Errorf("%s 的 protected 为 %v", result.Domain, result.Protected)
+			}
+		}
+	}
+	t.Run("检测中", check)
+
+	// 检测结束后最终结果替换实时结果，计数保持一致
+	s.Finish([]checker.Result{
+		{Domain: "https://a.example.com", Status: 200, Alive: true},
+		{Domain: "https://b.example.com", Status: 401},
+		{Domain: "https://c.example.com", Status: 407},
+		{Domain: "https://d.example.com", Status: 500},
+		{Domain: "e.example.com"},
+	}, nil)
+	t.Run("检测结束", check)
+}